@@ -0,0 +1,365 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+
+	configutil "github.com/NYCU-SDC/summer/pkg/config"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configFile = "config.yaml"
+
+	// legacySettingsFile is where telemetry was stored under the user config directory before it
+	// moved into the config file, it is migrated once and removed
+	legacySettingsFile = "settings.json"
+)
+
+// cliConfig is the per-user configuration of the CLI stored in ~/.summer/config.yaml,
+// the persistent flags of the same name override it for a single run
+type cliConfig struct {
+	Repo     string `yaml:"repo"`
+	Branch   string `yaml:"branch"`
+	Output   string `yaml:"output"`
+	CacheDir string `yaml:"cache_dir,omitempty"`
+
+	// Telemetry is managed by the telemetry command, which also sets the endpoint and the install id
+	Telemetry         bool   `yaml:"telemetry"`
+	TelemetryEndpoint string `yaml:"telemetry_endpoint,omitempty"`
+	InstallID         string `yaml:"install_id,omitempty"`
+}
+
+func defaultCLIConfig() cliConfig {
+	return cliConfig{
+		Repo:   "https://github.com/NYCU-SDC/summer/",
+		Branch: "main",
+		Output: "table",
+	}
+}
+
+// configKey is a setting managed by the config command
+type configKey struct {
+	description string
+	get         func(config cliConfig) (string, error)
+	set         func(config *cliConfig, value string) error
+}
+
+// configKeys lists the settings of the config command. The telemetry endpoint and the install id
+// are set by telemetryCommand.
+var configKeys = map[string]configKey{
+	"repo": {
+		description: "URL of the script repository",
+		get:         func(c cliConfig) (string, error) { return c.Repo, nil },
+		set: func(c *cliConfig, value string) error {
+			c.Repo = value
+			return nil
+		},
+	},
+	"branch": {
+		description: "Branch of the script repository",
+		get:         func(c cliConfig) (string, error) { return c.Branch, nil },
+		set: func(c *cliConfig, value string) error {
+			c.Branch = value
+			return nil
+		},
+	},
+	"output": {
+		description: "Output format of list commands, table or json",
+		get:         func(c cliConfig) (string, error) { return c.Output, nil },
+		set: func(c *cliConfig, value string) error {
+			if err := validateOutput(value); err != nil {
+				return err
+			}
+			c.Output = value
+			return nil
+		},
+	},
+	"cache_dir": {
+		description: "Directory for temporary repository checkouts, the system temp directory when empty",
+		get:         func(c cliConfig) (string, error) { return c.CacheDir, nil },
+		set: func(c *cliConfig, value string) error {
+			c.CacheDir = value
+			return nil
+		},
+	},
+	"telemetry": {
+		description: "Whether anonymous usage telemetry is sent",
+		get:         func(c cliConfig) (string, error) { return strconv.FormatBool(c.Telemetry), nil },
+		set: func(c *cliConfig, value string) error {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid telemetry value %q, expected true or false", value)
+			}
+			if enabled && c.TelemetryEndpoint == "" {
+				return fmt.Errorf("no telemetry endpoint configured, use summer telemetry on --endpoint")
+			}
+			c.Telemetry = enabled
+			return nil
+		},
+	},
+}
+
+func validateOutput(output string) error {
+	if output != "table" && output != "json" {
+		return fmt.Errorf("invalid output format %q, expected table or json", output)
+	}
+	return nil
+}
+
+func configPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate home directory: %w", err)
+	}
+	return filepath.Join(home, "."+appName, configFile), nil
+}
+
+// loadCLIConfig reads the config file over the defaults, a missing file returns the defaults.
+// Telemetry settings left in the legacy settings file are moved into the config file first.
+func loadCLIConfig() (cliConfig, error) {
+	config, err := readCLIConfig()
+	if err != nil {
+		return config, err
+	}
+
+	err = migrateLegacySettings(&config)
+	return config, err
+}
+
+func readCLIConfig() (cliConfig, error) {
+	config := defaultCLIConfig()
+
+	path, err := configPath()
+	if err != nil {
+		return config, err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return config, nil
+		}
+		return config, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	stored, err := configutil.LoadYAML[cliConfig](content)
+	if err != nil {
+		return config, fmt.Errorf("failed to parse config (%s): %w", path, err)
+	}
+
+	merged, err := configutil.Merge(&config, stored)
+	if err != nil {
+		return config, err
+	}
+	return *merged, nil
+}
+
+// migrateLegacySettings moves the telemetry settings of the legacy settings file into config,
+// saves the config file and removes the legacy file. Settings already in the config file win.
+func migrateLegacySettings(config *cliConfig) error {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		// Without a user config directory there is no legacy file either
+		return nil
+	}
+	path := filepath.Join(configDir, appName, legacySettingsFile)
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read legacy settings: %w", err)
+	}
+
+	var legacy struct {
+		Telemetry         bool   `json:"telemetry"`
+		TelemetryEndpoint string `json:"telemetryEndpoint"`
+		InstallID         string `json:"installId"`
+	}
+	err = json.Unmarshal(content, &legacy)
+	if err != nil {
+		return fmt.Errorf("failed to parse legacy settings (%s): %w", path, err)
+	}
+
+	if config.TelemetryEndpoint == "" {
+		config.Telemetry = legacy.Telemetry
+		config.TelemetryEndpoint = legacy.TelemetryEndpoint
+	}
+	if config.InstallID == "" {
+		config.InstallID = legacy.InstallID
+	}
+
+	if err := saveCLIConfig(*config); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove legacy settings: %w", err)
+	}
+	return nil
+}
+
+func saveCLIConfig(config cliConfig) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+func lookupConfigKey(name string) (configKey, error) {
+	key, ok := configKeys[name]
+	if !ok {
+		return configKey{}, fmt.Errorf("unknown config key %q, see summer config list", name)
+	}
+	return key, nil
+}
+
+func configCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "View and change the CLI settings",
+		Long: `The settings are stored in ~/.summer/config.yaml and used as the defaults
+of the matching flags, e.g. --repo and --branch.`,
+	}
+
+	get := &cobra.Command{
+		Use:   "get [key]",
+		Short: "Print the value of a setting",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := lookupConfigKey(args[0])
+			if err != nil {
+				return err
+			}
+
+			config, err := loadCLIConfig()
+			if err != nil {
+				return err
+			}
+
+			value, err := key.get(config)
+			if err != nil {
+				return err
+			}
+			fmt.Println(value)
+			return nil
+		},
+	}
+
+	set := &cobra.Command{
+		Use:   "set [key] [value]",
+		Short: "Change the value of a setting",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := lookupConfigKey(args[0])
+			if err != nil {
+				return err
+			}
+
+			config, err := loadCLIConfig()
+			if err != nil {
+				return err
+			}
+
+			if err := key.set(&config, args[1]); err != nil {
+				return err
+			}
+			if err := saveCLIConfig(config); err != nil {
+				return err
+			}
+			fmt.Printf("Set %s to %s\n", args[0], args[1])
+			return nil
+		},
+	}
+
+	list := &cobra.Command{
+		Use:   "list",
+		Short: "Print all settings",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := loadCLIConfig()
+			if err != nil {
+				return err
+			}
+
+			names := make([]string, 0, len(configKeys))
+			for name := range configKeys {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			values := make(map[string]string, len(names))
+			for _, name := range names {
+				value, err := configKeys[name].get(config)
+				if err != nil {
+					return err
+				}
+				values[name] = value
+			}
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(values)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KEY\tVALUE\tDESCRIPTION")
+			for _, name := range names {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", name, values[name], configKeys[name].description)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.AddCommand(get, set, list)
+	return cmd
+}
+
+// applyCLIConfig fills the persistent flags that were not given on the command line from the config
+func applyCLIConfig(cmd *cobra.Command) error {
+	config, err := loadCLIConfig()
+	if err != nil {
+		return err
+	}
+
+	if !cmd.Flags().Changed("repo") {
+		repoURL = config.Repo
+	}
+	if !cmd.Flags().Changed("branch") {
+		repoBranch = config.Branch
+	}
+	if !cmd.Flags().Changed("output") {
+		outputFormat = config.Output
+	}
+	cacheDir = config.CacheDir
+	if cacheDir != "" {
+		if err := os.MkdirAll(cacheDir, 0755); err != nil {
+			return fmt.Errorf("failed to create cache directory: %w", err)
+		}
+	}
+
+	return validateOutput(outputFormat)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const historyFile = "history.jsonl"
+
+// historyEntry is one line of the script history, appended for every script invocation
+type historyEntry struct {
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	Script     string    `json:"script"`
+	Repo       string    `json:"repo"`
+	Branch     string    `json:"branch"`
+	SHA        string    `json:"sha,omitempty"`
+	ExitCode   int       `json:"exitCode"`
+	DurationMs int64     `json:"durationMs"`
+	Error      string    `json:"error,omitempty"`
+}
+
+func historyPath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate home directory: %w", err)
+	}
+	return filepath.Join(home, "."+appName, historyFile), nil
+}
+
+// recordHistory appends an entry to the history, failures are only reported on stderr
+// since a broken history must not fail the script command itself
+func recordHistory(entry historyEntry) {
+	if err := appendHistory(entry); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to record history: %v\n", err)
+	}
+}
+
+func appendHistory(entry historyEntry) error {
+	path, err := historyPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+func readHistory() ([]historyEntry, error) {
+	path, err := historyPath()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history: %w", err)
+	}
+	defer file.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry historyEntry
+		// Skip lines that were cut short, e.g. by a full disk
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return entries, nil
+}
+
+func historyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Show the history of script invocations",
+		Long:  "Show the most recent script invocations, with the script version and the outcome, stored in ~/.summer/history.jsonl",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			script, _ := cmd.Flags().GetString("script")
+			failedOnly, _ := cmd.Flags().GetBool("failed")
+			limit, _ := cmd.Flags().GetInt("limit")
+
+			entries, err := readHistory()
+			if err != nil {
+				return err
+			}
+
+			matched := []historyEntry{}
+			for _, entry := range entries {
+				if script != "" && entry.Script != script {
+					continue
+				}
+				if failedOnly && entry.ExitCode == 0 {
+					continue
+				}
+				matched = append(matched, entry)
+			}
+			if limit > 0 && len(matched) > limit {
+				matched = matched[len(matched)-limit:]
+			}
+
+			if outputFormat == "json" {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(matched)
+			}
+
+			if len(matched) == 0 {
+				fmt.Println("No history")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "TIME\tCOMMAND\tSCRIPT\tSHA\tEXIT\tDURATION\tERROR")
+			for _, entry := range matched {
+				sha := entry.SHA
+				if len(sha) > 12 {
+					sha = sha[:12]
+				}
+				// git errors span several lines, the first one is enough for the table
+				errLine, _, _ := strings.Cut(entry.Error, "\n")
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+					entry.Time.Local().Format(time.DateTime),
+					entry.Command,
+					entry.Script,
+					sha,
+					entry.ExitCode,
+					time.Duration(entry.DurationMs)*time.Millisecond,
+					errLine,
+				)
+			}
+			return w.Flush()
+		},
+	}
+
+	cmd.Flags().String("script", "", "Only show invocations of this script")
+	cmd.Flags().Bool("failed", false, "Only show failed invocations")
+	cmd.Flags().Int("limit", 20, "Number of entries to show, 0 shows all")
+	return cmd
+}
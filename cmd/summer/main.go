@@ -9,38 +9,48 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 const (
 	appName        = "summer"
 	appVersion     = "0.1.1"
 	scriptsDir     = "./scripts/" // the directory where scripts are stored on the local machine
-	defaultRepo    = "https://github.com/NYCU-SDC/summer/"
-	defaultBranch  = "main"
 	scriptRegistry = "registry.json"
 )
 
 var (
-	repoURL    string
-	repoBranch string
-	rootCmd    = &cobra.Command{
+	repoURL      string
+	repoBranch   string
+	outputFormat string
+	cacheDir     string
+	rootCmd      = &cobra.Command{
 		Use:   appName,
 		Short: "A tool to download and manage useful scripts",
 		Long: `ScriptGet allows you to download, manage, and use helpful scripts
 in various languages. It makes non-Go scripts available as commands
 on your system.`,
 		Version: appVersion,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return applyCLIConfig(cmd)
+		},
 	}
 )
 
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&repoURL, "repo", "r", defaultRepo, "URL of the script repository")
-	rootCmd.PersistentFlags().StringVarP(&repoBranch, "branch", "b", defaultBranch, "Branch of the script repository")
+	rootCmd.PersistentFlags().StringVarP(&repoURL, "repo", "r", "", "URL of the script repository (default from summer config)")
+	rootCmd.PersistentFlags().StringVarP(&repoBranch, "branch", "b", "", "Branch of the script repository (default from summer config)")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", "Output format of list commands, table or json (default from summer config)")
 	rootCmd.PersistentFlags().StringP("name", "n", "", "Name of the project")
 
 	// Initialize commands
 	rootCmd.AddCommand(initCommand())
 	rootCmd.AddCommand(getScriptCommand())
+	rootCmd.AddCommand(migrateCommand())
+	rootCmd.AddCommand(telemetryCommand())
+	rootCmd.AddCommand(historyCommand())
+	rootCmd.AddCommand(configCommand())
+	rootCmd.AddCommand(smokeCommand())
 }
 
 func initCommand() *cobra.Command {
@@ -49,13 +59,18 @@ func initCommand() *cobra.Command {
 		Short: "Initialize the repository",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			projectName, _ := cmd.Flags().GetString("name")
+			template, _ := cmd.Flags().GetString("template")
+			if template != templateHTTP && template != templateGRPC {
+				return fmt.Errorf("unknown template %q, expected %s or %s", template, templateHTTP, templateGRPC)
+			}
 
 			if projectName == "" {
 				projectName = getInput("What is the projects name: ")
 			}
-			return initFileStructure(projectName)
+			return initFileStructure(projectName, template)
 		},
 	}
+	cmd.Flags().StringP("template", "t", templateHTTP, "Project template, http or grpc")
 	return cmd
 }
 
@@ -74,17 +89,37 @@ func getScriptCommand() *cobra.Command {
 		Args:  cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			scriptName := args[0]
-			return downloadScriptFromGit(repoURL, repoBranch, "/resource/scripts/"+scriptName, scriptsDir+scriptName)
+
+			start := time.Now()
+			sha, err := downloadScriptFromGit(repoURL, repoBranch, "/resource/scripts/"+scriptName, scriptsDir+scriptName)
+
+			entry := historyEntry{
+				Time:       start.UTC(),
+				Command:    "getscript",
+				Script:     scriptName,
+				Repo:       repoURL,
+				Branch:     repoBranch,
+				SHA:        sha,
+				DurationMs: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				entry.ExitCode = 1
+				entry.Error = err.Error()
+			}
+			recordHistory(entry)
+
+			return err
 		},
 	}
 	return cmd
 }
 
-func downloadScriptFromGit(repoURL, repoBranch, scriptPath, outputPath string) error {
+// downloadScriptFromGit returns the commit SHA the script was taken from
+func downloadScriptFromGit(repoURL, repoBranch, scriptPath, outputPath string) (string, error) {
 	// Create a temporary directory for Git operations
-	tempDir, err := os.MkdirTemp("", "scriptget-")
+	tempDir, err := os.MkdirTemp(cacheDir, "scriptget-")
 	if err != nil {
-		return fmt.Errorf("failed to create temp directory: %w", err)
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
 	}
 	defer func() {
 		err = os.RemoveAll(tempDir)
@@ -97,61 +132,70 @@ func downloadScriptFromGit(repoURL, repoBranch, scriptPath, outputPath string) e
 	cmd := exec.Command("git", "init")
 	cmd.Dir = tempDir
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to initialize git repo: %w", err)
+		return "", fmt.Errorf("failed to initialize git repo: %w", err)
 	}
 
 	// Add remote
 	cmd = exec.Command("git", "remote", "add", "origin", repoURL)
 	cmd.Dir = tempDir
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to add remote: %w", err)
+		return "", fmt.Errorf("failed to add remote: %w", err)
 	}
 
 	// Enable sparse checkout
 	cmd = exec.Command("git", "config", "core.sparseCheckout", "true")
 	cmd.Dir = tempDir
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to enable sparse checkout: %w", err)
+		return "", fmt.Errorf("failed to enable sparse checkout: %w", err)
 	}
 
 	// Specify which files/folders to checkout
 	sparseConfigPath := filepath.Join(tempDir, ".git", "info", "sparse-checkout")
 	if err := os.WriteFile(sparseConfigPath, []byte(scriptPath), 0755); err != nil {
-		return fmt.Errorf("failed to write sparse-checkout config: %w", err)
+		return "", fmt.Errorf("failed to write sparse-checkout config: %w", err)
 	}
 
 	// Pull the repository (only the specified files/folders)
 	cmd = exec.Command("git", "pull", "--depth=1", "origin", repoBranch) // Assuming main branch
 	cmd.Dir = tempDir
 	if out, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to pull from repository: %w: %s", err, string(out))
+		return "", fmt.Errorf("failed to pull from repository: %w: %s", err, string(out))
 	}
 
+	// Remember which version of the script was downloaded
+	cmd = exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = tempDir
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve script version: %w", err)
+	}
+	sha := strings.TrimSpace(string(out))
+
 	// Get the script from the checked out repo
 	scriptFullPath := filepath.Join(tempDir, scriptPath)
 	scriptContent, err := os.ReadFile(scriptFullPath)
 	if err != nil {
-		return fmt.Errorf("failed to read script: %w", err)
+		return "", fmt.Errorf("failed to read script: %w", err)
 	}
 
 	// Write the script to the output path
 	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
+		return "", fmt.Errorf("failed to create output directory: %w", err)
 	}
 	if err := os.WriteFile(outputPath, scriptContent, 0755); err != nil {
-		return fmt.Errorf("failed to write script to output: %w", err)
+		return "", fmt.Errorf("failed to write script to output: %w", err)
 	}
 	// make sure the script is executable
 	if err := os.Chmod(outputPath, 0755); err != nil {
-		return fmt.Errorf("failed to set script permissions: %w", err)
+		return "", fmt.Errorf("failed to set script permissions: %w", err)
 	}
 
-	return nil
+	return sha, nil
 }
 
 func downloadAllScriptFromGit(repoURL, repoBranch, scriptFolderPath, outputPath string) error {
 	// Create a temporary directory for Git operations
-	tempDir, err := os.MkdirTemp("", "scriptget-")
+	tempDir, err := os.MkdirTemp(cacheDir, "scriptget-")
 	if err != nil {
 		return fmt.Errorf("failed to create temp directory: %w", err)
 	}
@@ -208,7 +252,7 @@ func downloadAllScriptFromGit(repoURL, repoBranch, scriptFolderPath, outputPath
 
 func downloadExampleFromGit(repoURL, repoBranch, examplePath, outputPath string) error {
 	// Create a temporary directory for Git operations
-	tempDir, err := os.MkdirTemp("", "scriptget-")
+	tempDir, err := os.MkdirTemp(cacheDir, "scriptget-")
 	if err != nil {
 		return fmt.Errorf("failed to create temp directory: %w", err)
 	}
@@ -274,7 +318,7 @@ func downloadExampleFromGit(repoURL, repoBranch, examplePath, outputPath string)
 	return nil
 }
 
-func initFileStructure(projectName string) error {
+func initFileStructure(projectName, template string) error {
 	// create go.mod and go.sum
 	cmd := exec.Command("go", "mod", "init", projectName)
 	err := cmd.Run()
@@ -297,8 +341,12 @@ func initFileStructure(projectName string) error {
 		return fmt.Errorf("failed to download scripts: %w", err)
 	}
 
-	// get example main.go
-	if err := downloadExampleFromGit(repoURL, repoBranch, "/example/main.txt", "./cmd/main.go"); err != nil {
+	// get example main.go, or the whole gRPC scaffold
+	if template == templateGRPC {
+		if err := initGRPCTemplate(projectName); err != nil {
+			return err
+		}
+	} else if err := downloadExampleFromGit(repoURL, repoBranch, "/example/main.txt", "./cmd/main.go"); err != nil {
 		return fmt.Errorf("failed to download example: %w", err)
 	}
 
@@ -380,7 +428,11 @@ func copyFileContents(srcFile, dstFile string) (err error) {
 }
 
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	start := time.Now()
+	cmd, err := rootCmd.ExecuteC()
+	sendTelemetry(cmd, time.Since(start), err)
+
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
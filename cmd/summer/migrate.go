@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/NYCU-SDC/summer/pkg/database"
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	defaultMigrationsDir = "./internal/database/migrations"
+	defaultConfigFile    = "config.yaml"
+)
+
+var migrationNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// projectConfig holds the keys the CLI reads from the scaffolded project's config file
+type projectConfig struct {
+	DatabaseURL     string `yaml:"database_url"`
+	MigrationSource string `yaml:"migration_source"`
+}
+
+func migrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage database migrations of the project",
+		Long: `Run the project's migrations with the databaseutil migration runner.
+The database URL is read from --database-url, the DATABASE_URL environment variable
+or the database_url key of the project's config.yaml, in that order.`,
+	}
+
+	cmd.PersistentFlags().String("dir", defaultMigrationsDir, "Directory of the migration files")
+	cmd.PersistentFlags().String("database-url", "", "URL of the database to migrate")
+	cmd.PersistentFlags().String("config", defaultConfigFile, "Config file of the project")
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "up",
+		Short: "Apply all pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sourceURL, databaseURL, err := migrationURLs(cmd)
+			if err != nil {
+				return err
+			}
+
+			logger, err := logutil.ZapDevelopmentConfig().Build()
+			if err != nil {
+				return fmt.Errorf("failed to create logger: %w", err)
+			}
+			return databaseutil.MigrationUp(sourceURL, databaseURL, logger)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "down",
+		Short: "Revert all applied migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sourceURL, databaseURL, err := migrationURLs(cmd)
+			if err != nil {
+				return err
+			}
+
+			logger, err := logutil.ZapDevelopmentConfig().Build()
+			if err != nil {
+				return fmt.Errorf("failed to create logger: %w", err)
+			}
+			return databaseutil.MigrationDown(sourceURL, databaseURL, logger)
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show the current migration version",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sourceURL, databaseURL, err := migrationURLs(cmd)
+			if err != nil {
+				return err
+			}
+
+			version, dirty, err := databaseutil.MigrationVersion(sourceURL, databaseURL)
+			if err != nil {
+				return fmt.Errorf("failed to get migration version: %w", err)
+			}
+
+			if version == 0 {
+				fmt.Println("No migration has been applied")
+				return nil
+			}
+			fmt.Printf("Current version: %d (dirty: %t)\n", version, dirty)
+			return nil
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "create [name]",
+		Short: "Create a new pair of up and down migration files",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, _ := cmd.Flags().GetString("dir")
+			return createMigration(dir, args[0], time.Now())
+		},
+	})
+
+	return cmd
+}
+
+// migrationURLs resolves the migration source and database URL from the flags, the environment and the config file
+func migrationURLs(cmd *cobra.Command) (string, string, error) {
+	dir, _ := cmd.Flags().GetString("dir")
+	databaseURL, _ := cmd.Flags().GetString("database-url")
+	configFile, _ := cmd.Flags().GetString("config")
+
+	config, err := readProjectConfig(configFile)
+	if err != nil {
+		return "", "", err
+	}
+
+	if databaseURL == "" {
+		databaseURL = os.Getenv("DATABASE_URL")
+	}
+	if databaseURL == "" {
+		databaseURL = config.DatabaseURL
+	}
+	if databaseURL == "" {
+		return "", "", errors.New("database URL is not set, use --database-url, DATABASE_URL or database_url in " + configFile)
+	}
+
+	sourceURL := "file://" + filepath.ToSlash(dir)
+	if !cmd.Flags().Changed("dir") && config.MigrationSource != "" {
+		sourceURL = config.MigrationSource
+	}
+
+	return sourceURL, databaseURL, nil
+}
+
+// readProjectConfig reads the config file of the project, a missing file is not an error
+func readProjectConfig(path string) (projectConfig, error) {
+	var config projectConfig
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return config, nil
+		}
+		return config, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	err = yaml.Unmarshal(content, &config)
+	if err != nil {
+		return config, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	return config, nil
+}
+
+func createMigration(dir, name string, now time.Time) error {
+	if !migrationNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid migration name %q, use lowercase letters, digits and underscores", name)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create migrations directory: %w", err)
+	}
+
+	version := now.UTC().Format("20060102150405")
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%s_%s.%s.sql", version, name, direction))
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			return fmt.Errorf("failed to create migration file: %w", err)
+		}
+		fmt.Println("Created", path)
+	}
+
+	return nil
+}
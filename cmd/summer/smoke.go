@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// smokeCheck is one step of summer smoke, a failed step is reported without stopping the others
+type smokeCheck struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+func smokeCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "smoke",
+		Short: "Build, start and probe the project to check the scaffold works",
+		Long: `Build the project, start it on a free local port with the test environment,
+then check that /healthz answers, that the sample endpoint writes RFC 9457 problems,
+and that responses carry the trace context header. The server address is passed in
+the ADDR environment variable, like the scaffolded main.go reads it.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pkg, _ := cmd.Flags().GetString("package")
+			endpoint, _ := cmd.Flags().GetString("endpoint")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			env, _ := cmd.Flags().GetStringArray("env")
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			defer cancel()
+			return runSmoke(ctx, cmd.OutOrStdout(), pkg, endpoint, env)
+		},
+	}
+	cmd.Flags().String("package", "./cmd", "Package of the server to build")
+	cmd.Flags().String("endpoint", "/api/hello", "Sample endpoint answering a request without parameters with a problem")
+	cmd.Flags().Duration("timeout", 2*time.Minute, "Time allowed for the whole run, including the build")
+	cmd.Flags().StringArray("env", nil, "Environment variable of the test config as KEY=VALUE, can be repeated")
+	return cmd
+}
+
+func runSmoke(ctx context.Context, out io.Writer, pkg, endpoint string, env []string) error {
+	tempDir, err := os.MkdirTemp(cacheDir, "summer-smoke-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	binary := filepath.Join(tempDir, "server")
+	build := exec.CommandContext(ctx, "go", "build", "-o", binary, pkg)
+	if output, err := build.CombinedOutput(); err != nil {
+		fmt.Fprintf(out, "FAIL  build: %v\n%s", err, output)
+		return errors.New("smoke test failed: the project does not build")
+	}
+	fmt.Fprintln(out, "PASS  build")
+
+	addr, err := freeLocalAddr()
+	if err != nil {
+		return err
+	}
+
+	var serverOutput bytes.Buffer
+	server := exec.Command(binary)
+	server.Env = append(append(os.Environ(), env...), "ADDR="+addr)
+	server.Stdout = &serverOutput
+	server.Stderr = &serverOutput
+	if err := server.Start(); err != nil {
+		return fmt.Errorf("failed to start the server: %w", err)
+	}
+	defer stopServer(server)
+
+	baseURL := "http://" + addr
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	checks := []smokeCheck{
+		{name: "start", run: func(ctx context.Context) error {
+			return waitForServer(ctx, client, baseURL+"/healthz")
+		}},
+		{name: "healthz", run: func(ctx context.Context) error {
+			resp, _, err := smokeGet(ctx, client, baseURL+"/healthz")
+			if err != nil {
+				return err
+			}
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("status %d, want 200", resp.StatusCode)
+			}
+			return nil
+		}},
+		{name: "problem format", run: func(ctx context.Context) error {
+			resp, body, err := smokeGet(ctx, client, baseURL+endpoint)
+			if err != nil {
+				return err
+			}
+			return checkProblem(resp, body)
+		}},
+		{name: "trace header", run: func(ctx context.Context) error {
+			resp, _, err := smokeGet(ctx, client, baseURL+"/healthz")
+			if err != nil {
+				return err
+			}
+			if resp.Header.Get("traceparent") == "" {
+				return errors.New("no traceparent header, wrap the mux with traceutil.TraceMiddleware and traceutil.WithTraceResponse")
+			}
+			return nil
+		}},
+	}
+
+	failed := 0
+	for _, check := range checks {
+		err := check.run(ctx)
+		if err != nil {
+			failed++
+			fmt.Fprintf(out, "FAIL  %s: %v\n", check.name, err)
+			continue
+		}
+		fmt.Fprintf(out, "PASS  %s\n", check.name)
+	}
+
+	fmt.Fprintf(out, "\n%d passed, %d failed\n", len(checks)+1-failed, failed)
+	if failed > 0 {
+		fmt.Fprintf(out, "\nServer output:\n%s", serverOutput.String())
+		return fmt.Errorf("smoke test failed: %d of %d checks failed", failed, len(checks)+1)
+	}
+	return nil
+}
+
+// freeLocalAddr returns a loopback address with a port that is free right now
+func freeLocalAddr() (string, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", fmt.Errorf("failed to find a free port: %w", err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().String(), nil
+}
+
+func waitForServer(ctx context.Context, client *http.Client, url string) error {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		_, _, err := smokeGet(ctx, client, url)
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("server did not answer before the timeout: %w", err)
+		case <-ticker.C:
+		}
+	}
+}
+
+func smokeGet(ctx context.Context, client *http.Client, url string) (*http.Response, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	return resp, body, nil
+}
+
+// checkProblem verifies resp is an error answered with an RFC 9457 problem
+func checkProblem(resp *http.Response, body []byte) error {
+	if resp.StatusCode < 400 {
+		return fmt.Errorf("status %d, want an error status", resp.StatusCode)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/problem+json") {
+		return fmt.Errorf("Content-Type %q, want application/problem+json", contentType)
+	}
+
+	var problem struct {
+		Type   string `json:"type"`
+		Title  string `json:"title"`
+		Status int    `json:"status"`
+	}
+	if err := json.Unmarshal(body, &problem); err != nil {
+		return fmt.Errorf("body is not a JSON problem: %w", err)
+	}
+	switch {
+	case problem.Type == "":
+		return errors.New("problem has no type member")
+	case problem.Title == "":
+		return errors.New("problem has no title member")
+	case problem.Status != resp.StatusCode:
+		return fmt.Errorf("problem status %d differs from the response status %d", problem.Status, resp.StatusCode)
+	}
+	return nil
+}
+
+// stopServer interrupts the server and kills it when it does not exit in time
+func stopServer(server *exec.Cmd) {
+	_ = server.Process.Signal(os.Interrupt)
+
+	done := make(chan struct{})
+	go func() {
+		_ = server.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		_ = server.Process.Kill()
+		<-done
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+const telemetryTimeout = 2 * time.Second
+
+// telemetryEvent is everything sent about a command run, it never includes arguments, paths or project names
+type telemetryEvent struct {
+	InstallID  string `json:"installId"`
+	Command    string `json:"command"`
+	DurationMs int64  `json:"durationMs"`
+	Success    bool   `json:"success"`
+	Version    string `json:"version"`
+	OS         string `json:"os"`
+	Arch       string `json:"arch"`
+}
+
+func telemetryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "telemetry",
+		Short: "Manage anonymous usage telemetry",
+		Long: `Telemetry is off by default. When turned on, summer sends the command name,
+its duration, whether it succeeded, the summer version and the OS to the configured
+endpoint. Arguments, paths and project names are never sent. Setting DO_NOT_TRACK=1
+disables telemetry regardless of this setting.`,
+	}
+
+	on := &cobra.Command{
+		Use:   "on",
+		Short: "Turn on anonymous usage telemetry",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := loadCLIConfig()
+			if err != nil {
+				return err
+			}
+
+			endpoint, _ := cmd.Flags().GetString("endpoint")
+			if endpoint != "" {
+				config.TelemetryEndpoint = endpoint
+			}
+			if config.TelemetryEndpoint == "" {
+				return fmt.Errorf("no telemetry endpoint configured, use --endpoint")
+			}
+			if config.InstallID == "" {
+				config.InstallID = uuid.NewString()
+			}
+			config.Telemetry = true
+
+			if err := saveCLIConfig(config); err != nil {
+				return err
+			}
+			fmt.Println("Telemetry is on, thank you!")
+			return nil
+		},
+	}
+	on.Flags().String("endpoint", "", "URL the usage events are sent to")
+
+	off := &cobra.Command{
+		Use:   "off",
+		Short: "Turn off anonymous usage telemetry",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := loadCLIConfig()
+			if err != nil {
+				return err
+			}
+
+			config.Telemetry = false
+			if err := saveCLIConfig(config); err != nil {
+				return err
+			}
+			fmt.Println("Telemetry is off")
+			return nil
+		},
+	}
+
+	status := &cobra.Command{
+		Use:   "status",
+		Short: "Show whether telemetry is on",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := loadCLIConfig()
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case os.Getenv("DO_NOT_TRACK") != "":
+				fmt.Println("Telemetry is off (DO_NOT_TRACK is set)")
+			case config.Telemetry:
+				fmt.Printf("Telemetry is on, sending to %s\n", config.TelemetryEndpoint)
+			default:
+				fmt.Println("Telemetry is off")
+			}
+			return nil
+		},
+	}
+
+	cmd.AddCommand(on, off, status)
+	return cmd
+}
+
+// sendTelemetry reports a command run when the user opted in, failures are silently ignored
+// so telemetry can never break or noticeably slow down the CLI
+func sendTelemetry(cmd *cobra.Command, duration time.Duration, runErr error) {
+	if cmd == nil || os.Getenv("DO_NOT_TRACK") != "" {
+		return
+	}
+
+	config, err := loadCLIConfig()
+	if err != nil || !config.Telemetry || config.TelemetryEndpoint == "" {
+		return
+	}
+
+	body, err := json.Marshal(telemetryEvent{
+		InstallID:  config.InstallID,
+		Command:    cmd.CommandPath(),
+		DurationMs: duration.Milliseconds(),
+		Success:    runErr == nil,
+		Version:    appVersion,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+	})
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), telemetryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.TelemetryEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return
+	}
+	_ = resp.Body.Close()
+}
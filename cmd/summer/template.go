@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+const (
+	templateHTTP = "http"
+	templateGRPC = "grpc"
+)
+
+// grpcTemplateFiles maps the files of /example/grpc/ in the script repository to their place in the project
+var grpcTemplateFiles = map[string]string{
+	"main.txt":            "cmd/main.go",
+	"grpcutil.txt":        "internal/grpcutil/interceptor.go",
+	"buf.yaml":            "buf.yaml",
+	"buf.gen.yaml":        "buf.gen.yaml",
+	"proto/service.proto": "proto/{{service}}/v1/{{service}}.proto",
+}
+
+// initGRPCTemplate writes the gRPC scaffold: a server with the summer interceptors, health and
+// reflection services, and a buf layout with a first proto to generate the service from
+func initGRPCTemplate(projectName string) error {
+	tempDir, err := os.MkdirTemp(cacheDir, "template-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer func() {
+		err = os.RemoveAll(tempDir)
+		if err != nil {
+			fmt.Printf("Failed to remove temp directory (%s): %v\n", tempDir, err)
+		}
+	}()
+
+	templateDir := filepath.Join(tempDir, "grpc")
+	if err := downloadAllScriptFromGit(repoURL, repoBranch, "/example/grpc/", templateDir); err != nil {
+		return fmt.Errorf("failed to download grpc template: %w", err)
+	}
+
+	replacer := templateReplacer(projectName)
+	for src, dst := range grpcTemplateFiles {
+		content, err := os.ReadFile(filepath.Join(templateDir, src))
+		if err != nil {
+			return fmt.Errorf("failed to read template %s: %w", src, err)
+		}
+
+		outputPath := replacer.Replace(dst)
+		if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", outputPath, err)
+		}
+		if err := os.WriteFile(outputPath, []byte(replacer.Replace(string(content))), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", outputPath, err)
+		}
+	}
+
+	fmt.Println("Run `buf generate` to generate the gRPC code from proto/, then register the service in cmd/main.go")
+	return nil
+}
+
+// templateReplacer fills the placeholders of the template files: the Go module and the service
+// name derived from its last path element, in lower case for packages and title case for types
+func templateReplacer(projectName string) *strings.Replacer {
+	service := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, path.Base(projectName))
+	if service == "" || !unicode.IsLetter(rune(service[0])) {
+		service = "service" + service
+	}
+
+	return strings.NewReplacer(
+		"{{module}}", projectName,
+		"{{service}}", service,
+		"{{Service}}", strings.ToUpper(service[:1])+service[1:],
+	)
+}
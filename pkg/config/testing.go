@@ -0,0 +1,167 @@
+package configutil
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ConfigBuilder builds config permutations for tests, see TestConfig
+type ConfigBuilder[T any] struct {
+	config T
+	err    error
+}
+
+// TestConfig starts a builder from the zero value of T:
+//
+//	config := configutil.TestConfig[Config]().With("Database.MaxConns", 2).MustBuild()
+func TestConfig[T any]() *ConfigBuilder[T] {
+	return &ConfigBuilder[T]{}
+}
+
+// TestConfigFrom starts a builder from a copy of base, usually the service defaults. The copy is
+// shallow, so setting a path through a pointer field also changes base.
+func TestConfigFrom[T any](base T) *ConfigBuilder[T] {
+	return &ConfigBuilder[T]{config: base}
+}
+
+// With sets the field at path, a dot separated list of Go field names or yaml/json keys.
+// Nil pointers along the path are allocated, and value is converted to the field type when
+// possible, e.g. an int for an int32 field or a string such as "5s" for a time.Duration.
+func (b *ConfigBuilder[T]) With(path string, value any) *ConfigBuilder[T] {
+	if b.err != nil {
+		return b
+	}
+
+	field, err := fieldByPath(reflect.ValueOf(&b.config).Elem(), path)
+	if err != nil {
+		b.err = err
+		return b
+	}
+
+	converted, err := convertValue(value, field.Type())
+	if err != nil {
+		b.err = fmt.Errorf("config path %s: %w", path, err)
+		return b
+	}
+	field.Set(converted)
+	return b
+}
+
+// Build returns the config, or the first error of the With calls
+func (b *ConfigBuilder[T]) Build() (*T, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	config := b.config
+	return &config, nil
+}
+
+// MustBuild is like Build but panics on error, a wrong path is a bug in the test itself
+func (b *ConfigBuilder[T]) MustBuild() *T {
+	config, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return config
+}
+
+func fieldByPath(v reflect.Value, path string) (reflect.Value, error) {
+	current := v
+	for _, segment := range strings.Split(path, ".") {
+		for current.Kind() == reflect.Pointer {
+			if current.IsNil() {
+				current.Set(reflect.New(current.Type().Elem()))
+			}
+			current = current.Elem()
+		}
+
+		if current.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("config path %s: %s is not a struct", path, segment)
+		}
+
+		next, ok := structField(current, segment)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("config path %s: unknown field %s in %s", path, segment, current.Type())
+		}
+		current = next
+	}
+	return current, nil
+}
+
+// structField finds the exported field named segment, by Go name or by yaml/json key
+func structField(v reflect.Value, segment string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Name == segment || tagName(field, "yaml") == segment || tagName(field, "json") == segment {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func tagName(field reflect.StructField, tag string) string {
+	name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+	return name
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func convertValue(value any, target reflect.Type) (reflect.Value, error) {
+	if value == nil {
+		return reflect.Zero(target), nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Type().AssignableTo(target):
+		return v, nil
+	case target == durationType && v.Kind() == reflect.String:
+		d, err := time.ParseDuration(v.String())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(d), nil
+	case isNumber(v.Kind()) && isNumber(target.Kind()), v.Kind() == reflect.String && target.Kind() == reflect.String:
+		return v.Convert(target), nil
+	default:
+		return reflect.Value{}, fmt.Errorf("cannot use %T as %s", value, target)
+	}
+}
+
+func isNumber(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// SetEnv sets the environment variables for the duration of the test, restoring them afterwards
+func SetEnv(t testing.TB, vars map[string]string) {
+	t.Helper()
+	for key, value := range vars {
+		t.Setenv(key, value)
+	}
+}
+
+// UnsetEnv removes the environment variables for the duration of the test, so that a value
+// from the developer's shell or the CI runner cannot leak into a config test
+func UnsetEnv(t testing.TB, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		// t.Setenv registers the restore of the original value and refuses parallel tests
+		t.Setenv(key, os.Getenv(key))
+		_ = os.Unsetenv(key)
+	}
+}
@@ -0,0 +1,246 @@
+package configutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrUnknownConfigKey = errors.New("unknown config key")
+
+// UnknownKey is a key found in the config file that does not map to any struct field
+type UnknownKey struct {
+	Path       string
+	Suggestion string
+}
+
+// UnknownKeysError aggregates every unknown key found in a config file
+type UnknownKeysError struct {
+	Keys []UnknownKey
+}
+
+func (e UnknownKeysError) Error() string {
+	parts := make([]string, 0, len(e.Keys))
+	for _, key := range e.Keys {
+		if key.Suggestion != "" {
+			parts = append(parts, fmt.Sprintf("%s (did you mean %s?)", key.Path, key.Suggestion))
+		} else {
+			parts = append(parts, key.Path)
+		}
+	}
+	return fmt.Sprintf("%s: %s", ErrUnknownConfigKey.Error(), strings.Join(parts, ", "))
+}
+
+func (e UnknownKeysError) Is(target error) bool {
+	return errors.Is(target, ErrUnknownConfigKey)
+}
+
+// LoadYAML decodes a YAML config into T, failing with UnknownKeysError when the file
+// contains keys that T does not declare
+func LoadYAML[T any](data []byte) (*T, error) {
+	var raw map[string]any
+	err := yaml.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkUnknownKeys[T](raw, "yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	var config T
+	err = yaml.Unmarshal(data, &config)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// LoadJSON decodes a JSON config into T, failing with UnknownKeysError when the file
+// contains keys that T does not declare
+func LoadJSON[T any](data []byte) (*T, error) {
+	var raw map[string]any
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkUnknownKeys[T](raw, "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var config T
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func checkUnknownKeys[T any](raw map[string]any, tagName string) error {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+
+	var unknown []UnknownKey
+	collectUnknownKeys(raw, t, tagName, "", &unknown)
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	sort.Slice(unknown, func(i, j int) bool {
+		return unknown[i].Path < unknown[j].Path
+	})
+	return UnknownKeysError{Keys: unknown}
+}
+
+// collectUnknownKeys walks the decoded document along the struct type and records the keys without a matching field
+func collectUnknownKeys(value any, t reflect.Type, tagName, path string, unknown *[]UnknownKey) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		doc, ok := value.(map[string]any)
+		if !ok {
+			return
+		}
+
+		fields := structFields(t, tagName)
+		for key, child := range doc {
+			fieldType, found := lookupField(fields, key, tagName)
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+
+			if !found {
+				*unknown = append(*unknown, UnknownKey{Path: childPath, Suggestion: suggestKey(key, fields)})
+				continue
+			}
+			collectUnknownKeys(child, fieldType, tagName, childPath, unknown)
+		}
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]any)
+		if !ok {
+			return
+		}
+		for i, item := range items {
+			collectUnknownKeys(item, t.Elem(), tagName, fmt.Sprintf("%s[%d]", path, i), unknown)
+		}
+	}
+}
+
+// structFields returns the keys accepted by the struct, including the ones promoted from inlined structs
+func structFields(t reflect.Type, tagName string) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		// encoding/json promotes the exported fields of unexported embedded structs
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		tag := field.Tag.Get(tagName)
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		// yaml.v3 only inlines fields marked ",inline", encoding/json every untagged embedded struct
+		inline := strings.Contains(options, "inline") || (tagName == "json" && field.Anonymous && name == "")
+		if inline {
+			embedded := field.Type
+			for embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for k, v := range structFields(embedded, tagName) {
+					fields[k] = v
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			// yaml.v3 lowercases field names without a tag, encoding/json keeps them as is
+			name = field.Name
+			if tagName == "yaml" {
+				name = strings.ToLower(field.Name)
+			}
+		}
+		fields[name] = field.Type
+	}
+
+	return fields
+}
+
+// lookupField finds the field for key, JSON keys match case-insensitively like encoding/json does
+func lookupField(fields map[string]reflect.Type, key, tagName string) (reflect.Type, bool) {
+	if t, ok := fields[key]; ok {
+		return t, true
+	}
+	if tagName == "json" {
+		for name, t := range fields {
+			if strings.EqualFold(name, key) {
+				return t, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// suggestKey returns the closest known key when it is close enough to be a typo
+func suggestKey(key string, fields map[string]reflect.Type) string {
+	best := ""
+	bestDistance := -1
+	for name := range fields {
+		d := levenshtein(strings.ToLower(key), strings.ToLower(name))
+		if bestDistance == -1 || d < bestDistance || (d == bestDistance && name < best) {
+			best = name
+			bestDistance = d
+		}
+	}
+
+	maxDistance := len(key) / 3
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+	if bestDistance == -1 || bestDistance > maxDistance {
+		return ""
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
@@ -0,0 +1,183 @@
+package configutil
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type unknownTestDatabase struct {
+	Host string `yaml:"host" json:"host"`
+	Port int    `yaml:"port" json:"port"`
+}
+
+type unknownTestLogging struct {
+	Level string `yaml:"level" json:"level"`
+}
+
+type unknownTestConfig struct {
+	unknownTestLogging `yaml:",inline"`
+
+	Debug     bool                  `yaml:"debug" json:"debug"`
+	Database  unknownTestDatabase   `yaml:"database" json:"database"`
+	Replicas  []unknownTestDatabase `yaml:"replicas" json:"replicas"`
+	Secret    string                `yaml:"-" json:"-"`
+	ServerURL string
+}
+
+type unknownTestEmbedded struct {
+	unknownTestLogging
+
+	Debug bool `yaml:"debug" json:"debug"`
+}
+
+func TestLoadYAML_UnknownKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantKeys []UnknownKey
+	}{
+		{
+			name: "Should accept known keys",
+			data: "debug: true\nlevel: info\nserverurl: http://localhost\ndatabase:\n  host: db\n  port: 5432\n",
+		},
+		{
+			name:     "Should report an unknown top-level key",
+			data:     "debug: true\ntimeout: 5s\n",
+			wantKeys: []UnknownKey{{Path: "timeout"}},
+		},
+		{
+			name:     "Should report an unknown nested key with its path",
+			data:     "database:\n  host: db\n  prot: 5432\n",
+			wantKeys: []UnknownKey{{Path: "database.prot", Suggestion: "port"}},
+		},
+		{
+			name:     "Should report an unknown key inside a list",
+			data:     "replicas:\n  - host: a\n  - hots: b\n",
+			wantKeys: []UnknownKey{{Path: "replicas[1].hots", Suggestion: "host"}},
+		},
+		{
+			name:     "Should accept keys of an inline struct and suggest them",
+			data:     "level: info\nlevle: debug\n",
+			wantKeys: []UnknownKey{{Path: "levle", Suggestion: "level"}},
+		},
+		{
+			name:     "Should report a key skipped with a dash tag",
+			data:     "secret: s3cr3t\n",
+			wantKeys: []UnknownKey{{Path: "secret"}},
+		},
+		{
+			name:     "Should report every unknown key sorted by path",
+			data:     "zone: a\ndatabase:\n  hots: db\ndebg: true\n",
+			wantKeys: []UnknownKey{{Path: "database.hots", Suggestion: "host"}, {Path: "debg", Suggestion: "debug"}, {Path: "zone"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadYAML[unknownTestConfig]([]byte(tt.data))
+			assertUnknownKeys(t, err, tt.wantKeys)
+		})
+	}
+}
+
+func TestLoadYAML_EmbeddedWithoutInline(t *testing.T) {
+	// yaml.v3 decodes an untagged embedded struct as a key named after its type, not inline
+	_, err := LoadYAML[unknownTestEmbedded]([]byte("debug: true\nlevel: info\n"))
+	assertUnknownKeys(t, err, []UnknownKey{{Path: "level"}})
+}
+
+func TestLoadJSON_UnknownKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantKeys []UnknownKey
+	}{
+		{
+			name: "Should accept known keys",
+			data: `{"debug":true,"ServerURL":"http://localhost","database":{"host":"db","port":5432}}`,
+		},
+		{
+			name: "Should match keys case-insensitively",
+			data: `{"DEBUG":true,"serverurl":"http://localhost","Database":{"HOST":"db"}}`,
+		},
+		{
+			name:     "Should report an unknown nested key with its path",
+			data:     `{"database":{"host":"db","hots":"db"}}`,
+			wantKeys: []UnknownKey{{Path: "database.hots", Suggestion: "host"}},
+		},
+		{
+			name:     "Should report a key skipped with a dash tag",
+			data:     `{"Secret":"s3cr3t"}`,
+			wantKeys: []UnknownKey{{Path: "Secret"}},
+		},
+		{
+			name: "Should accept the keys of an untagged embedded struct",
+			data: `{"level":"info"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadJSON[unknownTestConfig]([]byte(tt.data))
+			assertUnknownKeys(t, err, tt.wantKeys)
+		})
+	}
+}
+
+func TestLoadJSON_Embedded(t *testing.T) {
+	// encoding/json promotes the fields of an untagged embedded struct
+	config, err := LoadJSON[unknownTestEmbedded]([]byte(`{"debug":true,"level":"info"}`))
+	assertUnknownKeys(t, err, nil)
+	if config != nil && config.Level != "info" {
+		t.Errorf("LoadJSON() Level = %q, want %q", config.Level, "info")
+	}
+}
+
+func TestSuggestKey(t *testing.T) {
+	fields := structFields(reflect.TypeFor[unknownTestConfig](), "yaml")
+
+	tests := []struct {
+		name string
+		key  string
+		want string
+	}{
+		{name: "Should suggest a key one edit away", key: "debgu", want: "debug"},
+		{name: "Should suggest a key two edits away", key: "dbug", want: "debug"},
+		{name: "Should ignore case", key: "DATABASE", want: "database"},
+		{name: "Should not suggest a short key three edits away", key: "dg", want: ""},
+		{name: "Should suggest a long key three edits away", key: "serverxyz", want: "serverurl"},
+		{name: "Should not suggest a long key more than a third of its length away", key: "databsae_hots", want: ""},
+		{name: "Should not suggest an unrelated key", key: "timeout", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := suggestKey(tt.key, fields); got != tt.want {
+				t.Errorf("suggestKey(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func assertUnknownKeys(t *testing.T, err error, want []UnknownKey) {
+	t.Helper()
+
+	if len(want) == 0 {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+
+	if !errors.Is(err, ErrUnknownConfigKey) {
+		t.Fatalf("error = %v, want %v", err, ErrUnknownConfigKey)
+	}
+	var unknownErr UnknownKeysError
+	if !errors.As(err, &unknownErr) {
+		t.Fatalf("error = %T, want UnknownKeysError", err)
+	}
+	if !reflect.DeepEqual(unknownErr.Keys, want) {
+		t.Errorf("unknown keys = %+v, want %+v", unknownErr.Keys, want)
+	}
+}
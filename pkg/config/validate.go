@@ -0,0 +1,115 @@
+package configutil
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+// Violation is a config field failing one of its validation rules
+type Violation struct {
+	Path string
+	Rule string
+	Env  string
+}
+
+// ValidationError aggregates every violation found by Validate
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		if violation.Env != "" {
+			parts = append(parts, fmt.Sprintf("%s fails %q in %s", violation.Path, violation.Rule, violation.Env))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s fails %q", violation.Path, violation.Rule))
+		}
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidConfig.Error(), strings.Join(parts, ", "))
+}
+
+func (e ValidationError) Is(target error) bool {
+	return errors.Is(target, ErrInvalidConfig)
+}
+
+// Validate checks cfg against its "validate" tags, then against the tags only enforced in env,
+// named "validate_<env>". Rules that would be a nuisance in development can be made mandatory
+// before a config reaches production:
+//
+//	type Config struct {
+//		DatabaseURL string   `yaml:"database_url" validate_prod:"required"`
+//		CORSOrigins []string `yaml:"cors_origins" validate_prod:"required,dive,excludes=*"`
+//	}
+//
+//	err := configutil.Validate(cfg, "prod")
+//
+// Fields are named after their yaml tag, so the violations match the keys of the config file.
+func Validate(cfg any, env string) error {
+	violations, err := collectViolations(cfg, "validate", "", nil)
+	if err != nil {
+		return err
+	}
+	if env != "" {
+		violations, err = collectViolations(cfg, "validate_"+env, env, violations)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(violations) > 0 {
+		return ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+func collectViolations(cfg any, tagName, env string, violations []Violation) ([]Violation, error) {
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.SetTagName(tagName)
+	validate.RegisterTagNameFunc(yamlFieldName)
+
+	err := validate.Struct(cfg)
+	if err == nil {
+		return violations, nil
+	}
+
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil, err
+	}
+	for _, fieldErr := range validationErrors {
+		rule := fieldErr.Tag()
+		if fieldErr.Param() != "" {
+			rule += "=" + fieldErr.Param()
+		}
+		violations = append(violations, Violation{Path: fieldPath(fieldErr.Namespace()), Rule: rule, Env: env})
+	}
+	return violations, nil
+}
+
+// yamlFieldName names a field like yaml.v3 decodes it
+func yamlFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// fieldPath drops the name of the root struct from the validator namespace
+func fieldPath(namespace string) string {
+	_, path, found := strings.Cut(namespace, ".")
+	if !found {
+		return namespace
+	}
+	return path
+}
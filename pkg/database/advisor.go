@@ -0,0 +1,190 @@
+package databaseutil
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+var (
+	fingerprintLiteralPattern    = regexp.MustCompile(`'(?:[^']|'')*'|\b\d+(?:\.\d+)?\b|\$\d+`)
+	fingerprintWhitespacePattern = regexp.MustCompile(`\s+`)
+	filterColumnPattern          = regexp.MustCompile(`\(*(\w+)\)?(?:::[\w ]+?)?\s*(?:=|<>|!=|<=|>=|<|>|~~\*?|!~~\*?|IS)\s`)
+)
+
+type advisorContextKey struct{}
+
+type explainJob struct {
+	fingerprint string
+	sql         string
+	args        []any
+}
+
+// IndexAdvisor is a pgx.QueryTracer meant for development. It runs EXPLAIN once for every new
+// query fingerprint and logs a suggestion when the plan contains a sequential scan over a table
+// with at least MinTableRows rows. The EXPLAIN runs in a background worker on db, so the traced
+// query is never delayed.
+type IndexAdvisor struct {
+	MinTableRows float64
+
+	db     RowQuerier
+	logger *zap.Logger
+	queue  chan explainJob
+	seen   sync.Map
+}
+
+func NewIndexAdvisor(db RowQuerier, logger *zap.Logger, minTableRows float64) *IndexAdvisor {
+	return &IndexAdvisor{
+		MinTableRows: minTableRows,
+		db:           db,
+		logger:       logger,
+		queue:        make(chan explainJob, 64),
+	}
+}
+
+// Start runs the EXPLAIN worker until ctx is canceled
+func (a *IndexAdvisor) Start(ctx context.Context) {
+	ctx = context.WithValue(ctx, advisorContextKey{}, true)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-a.queue:
+			a.explain(ctx, job)
+		}
+	}
+}
+
+func (a *IndexAdvisor) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	// Skip the queries issued by the advisor itself
+	if ctx.Value(advisorContextKey{}) != nil {
+		return ctx
+	}
+
+	if !strings.HasPrefix(strings.ToLower(strings.TrimSpace(data.SQL)), "select") {
+		return ctx
+	}
+
+	fingerprint := Fingerprint(data.SQL)
+	if _, loaded := a.seen.LoadOrStore(fingerprint, true); loaded {
+		return ctx
+	}
+
+	select {
+	case a.queue <- explainJob{fingerprint: fingerprint, sql: data.SQL, args: data.Args}:
+	default:
+		// The worker is behind, forget the fingerprint so the query is analyzed next time
+		a.seen.Delete(fingerprint)
+	}
+
+	return ctx
+}
+
+func (a *IndexAdvisor) TraceQueryEnd(context.Context, *pgx.Conn, pgx.TraceQueryEndData) {}
+
+// planNode is the subset of the EXPLAIN (FORMAT JSON) output used by the advisor
+type planNode struct {
+	NodeType     string     `json:"Node Type"`
+	RelationName string     `json:"Relation Name"`
+	Filter       string     `json:"Filter"`
+	Plans        []planNode `json:"Plans"`
+}
+
+func (a *IndexAdvisor) explain(ctx context.Context, job explainJob) {
+	var raw []byte
+	rows, err := a.db.Query(ctx, "EXPLAIN (FORMAT JSON) "+job.sql, job.args...)
+	if err != nil {
+		a.logger.Debug("Failed to explain query", zap.String("fingerprint", job.fingerprint), zap.Error(err))
+		return
+	}
+	if rows.Next() {
+		err = rows.Scan(&raw)
+	}
+	rows.Close()
+	if err == nil {
+		err = rows.Err()
+	}
+	if err != nil {
+		a.logger.Debug("Failed to explain query", zap.String("fingerprint", job.fingerprint), zap.Error(err))
+		return
+	}
+
+	var plans []struct {
+		Plan planNode `json:"Plan"`
+	}
+	err = json.Unmarshal(raw, &plans)
+	if err != nil || len(plans) == 0 {
+		return
+	}
+
+	for _, scan := range sequentialScans(plans[0].Plan) {
+		tableRows, err := a.tableRows(ctx, scan.RelationName)
+		if err != nil || tableRows < a.MinTableRows {
+			continue
+		}
+
+		a.logger.Warn("Sequential scan over large table, consider adding an index",
+			zap.String("table", scan.RelationName),
+			zap.Float64("estimated_rows", tableRows),
+			zap.Strings("suggested_columns", filterColumns(scan.Filter)),
+			zap.String("filter", scan.Filter),
+			zap.String("fingerprint", job.fingerprint),
+		)
+	}
+}
+
+func (a *IndexAdvisor) tableRows(ctx context.Context, table string) (float64, error) {
+	rows, err := a.db.Query(ctx, "SELECT reltuples::float8 FROM pg_class WHERE relname = $1 AND relkind = 'r'", table)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var count float64
+	if rows.Next() {
+		err = rows.Scan(&count)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return count, rows.Err()
+}
+
+// sequentialScans returns every Seq Scan node of the plan tree
+func sequentialScans(node planNode) []planNode {
+	var scans []planNode
+	if node.NodeType == "Seq Scan" && node.RelationName != "" {
+		scans = append(scans, node)
+	}
+	for _, child := range node.Plans {
+		scans = append(scans, sequentialScans(child)...)
+	}
+	return scans
+}
+
+// filterColumns extracts the column names compared in a plan filter such as "((email)::text = $1)"
+func filterColumns(filter string) []string {
+	var columns []string
+	seen := map[string]bool{}
+	for _, match := range filterColumnPattern.FindAllStringSubmatch(filter, -1) {
+		column := match[1]
+		if !seen[column] {
+			seen[column] = true
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// Fingerprint normalizes a query by replacing literals and parameters with ? and collapsing whitespace,
+// so that executions of the same statement with different values share a fingerprint
+func Fingerprint(sql string) string {
+	fingerprint := fingerprintLiteralPattern.ReplaceAllString(sql, "?")
+	fingerprint = fingerprintWhitespacePattern.ReplaceAllString(fingerprint, " ")
+	return strings.ToLower(strings.TrimSpace(fingerprint))
+}
@@ -0,0 +1,304 @@
+package databaseutil
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+var ErrDatabaseUnavailable = errors.New("database unavailable")
+
+// BreakerState is the state of a CircuitBreaker
+type BreakerState int
+
+const (
+	// BreakerClosed lets every operation through
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails every operation with ErrDatabaseUnavailable until the cooldown is over
+	BreakerOpen
+	// BreakerHalfOpen lets a single probe through, its outcome closes or reopens the breaker
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// CircuitBreaker stops sending operations to a database that keeps failing, so requests fail
+// fast with ErrDatabaseUnavailable instead of piling up on pool acquisition. Only infrastructure
+// failures, see IsInfrastructureError, count toward opening it, constraint violations and other
+// query errors do not.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	logger    *zap.Logger
+	// now is time.Now, replaced in tests
+	now func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// NewCircuitBreaker creates a breaker opening after threshold consecutive infrastructure failures
+// and half-opening after cooldown
+func NewCircuitBreaker(logger *zap.Logger, threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: max(threshold, 1),
+		cooldown:  cooldown,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// State returns the current state of the breaker
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// Do runs fn unless the breaker is open, and records its outcome
+func (b *CircuitBreaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	probe, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = fn(ctx)
+	b.record(probe, err)
+	return err
+}
+
+// allow reports whether an operation may run, and whether it is the probe of a half-open breaker
+func (b *CircuitBreaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false, ErrDatabaseUnavailable
+		}
+		b.setState(BreakerHalfOpen)
+		b.probing = true
+		return true, nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return false, ErrDatabaseUnavailable
+		}
+		b.probing = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// record counts the outcome of an operation let through by allow. Only the probe moves a
+// half-open breaker, and an open breaker ignores the operations that started before it opened.
+func (b *CircuitBreaker) record(probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		return
+	case BreakerHalfOpen:
+		if !probe {
+			return
+		}
+		b.probing = false
+		if IsInfrastructureError(err) {
+			b.failures++
+			b.openedAt = b.now()
+			b.setState(BreakerOpen)
+			return
+		}
+		b.failures = 0
+		b.setState(BreakerClosed)
+		return
+	}
+
+	if !IsInfrastructureError(err) {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.setState(BreakerOpen)
+	}
+}
+
+// setState must be called with mu held
+func (b *CircuitBreaker) setState(state BreakerState) {
+	b.logger.Warn("Database circuit breaker changed state",
+		zap.String("from", b.state.String()),
+		zap.String("to", state.String()),
+		zap.Int("consecutive_failures", b.failures),
+		zap.Duration("cooldown", b.cooldown),
+	)
+	b.state = state
+}
+
+// IsInfrastructureError reports whether err means the database could not be reached or could not
+// serve the operation, rather than rejecting it: connection failures, timeouts, and the
+// connection exception, resource and shutdown classes of SQLSTATE codes
+func IsInfrastructureError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &connectErr), errors.As(err, &netErr):
+		return true
+	case errors.Is(err, ErrQueryTimeout), errors.Is(err, context.DeadlineExceeded):
+		return true
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EPIPE):
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// 08: connection exception, 53: insufficient resources, 57P: operator intervention
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "53") || strings.HasPrefix(pgErr.Code, "57P")
+	}
+	return false
+}
+
+// DBTX is the interface of the queries generated by sqlc for pgx, implemented by *pgxpool.Pool,
+// *pgx.Conn and pgx.Tx
+type DBTX interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// BreakerDB guards a DBTX with a CircuitBreaker, pass it to the sqlc constructor instead of the
+// pool. Transactions begun through it are guarded when they begin, not on every statement.
+type BreakerDB struct {
+	db      DBTX
+	breaker *CircuitBreaker
+}
+
+func NewBreakerDB(db DBTX, breaker *CircuitBreaker) *BreakerDB {
+	return &BreakerDB{db: db, breaker: breaker}
+}
+
+func (d *BreakerDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	var tag pgconn.CommandTag
+	err := d.breaker.Do(ctx, func(ctx context.Context) error {
+		var err error
+		tag, err = d.db.Exec(ctx, sql, args...)
+		return err
+	})
+	return tag, err
+}
+
+func (d *BreakerDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	probe, err := d.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := d.db.Query(ctx, sql, args...)
+	if err != nil {
+		d.breaker.record(probe, err)
+		return nil, err
+	}
+	return &breakerRows{Rows: rows, breaker: d.breaker, probe: probe}, nil
+}
+
+func (d *BreakerDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	probe, err := d.breaker.allow()
+	if err != nil {
+		return errRow{err: err}
+	}
+	return breakerRow{row: d.db.QueryRow(ctx, sql, args...), breaker: d.breaker, probe: probe}
+}
+
+// BeginTx begins a transaction when the wrapped DBTX is a TxBeginner, so BreakerDB can be used with WithTx
+func (d *BreakerDB) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	beginner, ok := d.db.(TxBeginner)
+	if !ok {
+		return nil, errors.New("wrapped database does not support transactions")
+	}
+
+	var tx pgx.Tx
+	err := d.breaker.Do(ctx, func(ctx context.Context) error {
+		var err error
+		tx, err = beginner.BeginTx(ctx, txOptions)
+		return err
+	})
+	return tx, err
+}
+
+// breakerRow records the outcome of a QueryRow once it is scanned, since pgx defers the error until then
+type breakerRow struct {
+	row     pgx.Row
+	breaker *CircuitBreaker
+	probe   bool
+}
+
+func (r breakerRow) Scan(dest ...any) error {
+	err := r.row.Scan(dest...)
+	r.breaker.record(r.probe, err)
+	return err
+}
+
+// breakerRows records the outcome of a Query once its rows are read or closed, so that failures
+// while iterating count like failures of the query itself
+type breakerRows struct {
+	pgx.Rows
+	breaker  *CircuitBreaker
+	probe    bool
+	recorded bool
+}
+
+func (r *breakerRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.record()
+	return false
+}
+
+func (r *breakerRows) Close() {
+	r.Rows.Close()
+	r.record()
+}
+
+func (r *breakerRows) record() {
+	if r.recorded {
+		return
+	}
+	r.recorded = true
+	r.breaker.record(r.probe, r.Rows.Err())
+}
+
+type errRow struct {
+	err error
+}
+
+func (r errRow) Scan(...any) error {
+	return r.err
+}
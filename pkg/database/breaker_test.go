@@ -0,0 +1,267 @@
+package databaseutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+var (
+	errBreakerTestInfrastructure = &pgconn.PgError{Code: "08006", Message: "connection failure"}
+	errBreakerTestQuery          = &pgconn.PgError{Code: "23505", Message: "duplicate key value"}
+)
+
+// breakerStep is one step of a breaker scenario: it starts the operation start, finishes the
+// operation finish with err, or advances the clock by wait, then checks the state
+type breakerStep struct {
+	wait   time.Duration
+	start  string
+	finish string
+	err    error
+
+	wantErr   error
+	wantState BreakerState
+}
+
+func start(name string, wantState BreakerState) breakerStep {
+	return breakerStep{start: name, wantState: wantState}
+}
+
+func rejected(wantState BreakerState) breakerStep {
+	return breakerStep{start: "rejected", wantErr: ErrDatabaseUnavailable, wantState: wantState}
+}
+
+func finish(name string, err error, wantState BreakerState) breakerStep {
+	return breakerStep{finish: name, err: err, wantState: wantState}
+}
+
+func advance(d time.Duration, wantState BreakerState) breakerStep {
+	return breakerStep{wait: d, wantState: wantState}
+}
+
+// tripBreaker opens a breaker with a threshold of 3, leaving the operations started before untouched
+func tripBreaker() []breakerStep {
+	return []breakerStep{
+		start("f1", BreakerClosed), finish("f1", errBreakerTestInfrastructure, BreakerClosed),
+		start("f2", BreakerClosed), finish("f2", errBreakerTestInfrastructure, BreakerClosed),
+		start("f3", BreakerClosed), finish("f3", errBreakerTestInfrastructure, BreakerOpen),
+	}
+}
+
+func breakerSteps(groups ...[]breakerStep) []breakerStep {
+	var all []breakerStep
+	for _, group := range groups {
+		all = append(all, group...)
+	}
+	return all
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []breakerStep
+	}{
+		{
+			name:  "Should open at the threshold of consecutive infrastructure failures",
+			steps: breakerSteps(tripBreaker(), []breakerStep{rejected(BreakerOpen)}),
+		},
+		{
+			name: "Should reset the failure count after a success",
+			steps: []breakerStep{
+				start("f1", BreakerClosed), finish("f1", errBreakerTestInfrastructure, BreakerClosed),
+				start("f2", BreakerClosed), finish("f2", errBreakerTestInfrastructure, BreakerClosed),
+				start("ok", BreakerClosed), finish("ok", nil, BreakerClosed),
+				start("f3", BreakerClosed), finish("f3", errBreakerTestInfrastructure, BreakerClosed),
+				start("f4", BreakerClosed), finish("f4", errBreakerTestInfrastructure, BreakerClosed),
+			},
+		},
+		{
+			name: "Should not count query errors",
+			steps: []breakerStep{
+				start("q1", BreakerClosed), finish("q1", errBreakerTestQuery, BreakerClosed),
+				start("q2", BreakerClosed), finish("q2", errBreakerTestQuery, BreakerClosed),
+				start("q3", BreakerClosed), finish("q3", errBreakerTestQuery, BreakerClosed),
+			},
+		},
+		{
+			name: "Should stay open until the cooldown is over",
+			steps: breakerSteps(tripBreaker(), []breakerStep{
+				advance(9*time.Second, BreakerOpen), rejected(BreakerOpen),
+				advance(time.Second, BreakerOpen), start("probe", BreakerHalfOpen),
+			}),
+		},
+		{
+			name: "Should let a single probe through when half-open",
+			steps: breakerSteps(tripBreaker(), []breakerStep{
+				advance(10*time.Second, BreakerOpen), start("probe", BreakerHalfOpen),
+				rejected(BreakerHalfOpen), rejected(BreakerHalfOpen),
+			}),
+		},
+		{
+			name: "Should close when the probe succeeds",
+			steps: breakerSteps(tripBreaker(), []breakerStep{
+				advance(10*time.Second, BreakerOpen), start("probe", BreakerHalfOpen),
+				finish("probe", nil, BreakerClosed), start("next", BreakerClosed),
+			}),
+		},
+		{
+			name: "Should close when the probe fails with a query error",
+			steps: breakerSteps(tripBreaker(), []breakerStep{
+				advance(10*time.Second, BreakerOpen), start("probe", BreakerHalfOpen),
+				finish("probe", errBreakerTestQuery, BreakerClosed),
+			}),
+		},
+		{
+			name: "Should reopen for a new cooldown when the probe fails",
+			steps: breakerSteps(tripBreaker(), []breakerStep{
+				advance(10*time.Second, BreakerOpen), start("probe", BreakerHalfOpen),
+				finish("probe", errBreakerTestInfrastructure, BreakerOpen),
+				advance(9*time.Second, BreakerOpen), rejected(BreakerOpen),
+				advance(time.Second, BreakerOpen), start("probe2", BreakerHalfOpen),
+			}),
+		},
+		{
+			name: "Should ignore results finishing while open",
+			steps: breakerSteps([]breakerStep{start("slow", BreakerClosed), start("slow2", BreakerClosed)}, tripBreaker(), []breakerStep{
+				finish("slow", nil, BreakerOpen),
+				finish("slow2", errBreakerTestInfrastructure, BreakerOpen),
+				rejected(BreakerOpen),
+			}),
+		},
+		{
+			name: "Should only move a half-open breaker on the outcome of the probe",
+			steps: breakerSteps([]breakerStep{start("slow", BreakerClosed), start("slow2", BreakerClosed)}, tripBreaker(), []breakerStep{
+				advance(10*time.Second, BreakerOpen), start("probe", BreakerHalfOpen),
+				finish("slow", nil, BreakerHalfOpen),
+				rejected(BreakerHalfOpen),
+				finish("slow2", errBreakerTestInfrastructure, BreakerHalfOpen),
+				finish("probe", nil, BreakerClosed),
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+			breaker := NewCircuitBreaker(zap.NewNop(), 3, 10*time.Second)
+			breaker.now = func() time.Time { return now }
+
+			probes := map[string]bool{}
+			for i, step := range tt.steps {
+				switch {
+				case step.wait > 0:
+					now = now.Add(step.wait)
+				case step.start != "":
+					probe, err := breaker.allow()
+					if !errors.Is(err, step.wantErr) {
+						t.Fatalf("step %d: start %s error = %v, want %v", i, step.start, err, step.wantErr)
+					}
+					probes[step.start] = probe
+				case step.finish != "":
+					breaker.record(probes[step.finish], step.err)
+				}
+
+				if got := breaker.State(); got != step.wantState {
+					t.Fatalf("step %d: state = %s, want %s", i, got, step.wantState)
+				}
+			}
+		})
+	}
+}
+
+// breakerTestRows are rows failing with err once read
+type breakerTestRows struct {
+	pgx.Rows
+	rows int
+	err  error
+}
+
+func (r *breakerTestRows) Next() bool {
+	if r.rows == 0 {
+		return false
+	}
+	r.rows--
+	return true
+}
+
+func (r *breakerTestRows) Err() error {
+	if r.rows == 0 {
+		return r.err
+	}
+	return nil
+}
+
+func (r *breakerTestRows) Close() {
+	r.rows = 0
+}
+
+type breakerTestDB struct {
+	DBTX
+	rows *breakerTestRows
+}
+
+func (d breakerTestDB) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return d.rows, nil
+}
+
+func TestBreakerDB_Query(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		read      func(rows pgx.Rows)
+		wantState BreakerState
+	}{
+		{
+			name: "Should count a failure while iterating",
+			err:  errBreakerTestInfrastructure,
+			read: func(rows pgx.Rows) {
+				for rows.Next() {
+				}
+			},
+			wantState: BreakerOpen,
+		},
+		{
+			name:      "Should count a failure reported on close",
+			err:       errBreakerTestInfrastructure,
+			read:      func(rows pgx.Rows) { rows.Close() },
+			wantState: BreakerOpen,
+		},
+		{
+			name:      "Should not count rows that are not read yet",
+			err:       errBreakerTestInfrastructure,
+			read:      func(rows pgx.Rows) {},
+			wantState: BreakerClosed,
+		},
+		{
+			name: "Should count rows read without error as a success",
+			read: func(rows pgx.Rows) {
+				for rows.Next() {
+				}
+				rows.Close()
+			},
+			wantState: BreakerClosed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := NewCircuitBreaker(zap.NewNop(), 1, time.Minute)
+			db := NewBreakerDB(breakerTestDB{rows: &breakerTestRows{rows: 2, err: tt.err}}, breaker)
+
+			rows, err := db.Query(context.Background(), "SELECT id FROM users")
+			if err != nil {
+				t.Fatalf("Query() unexpected error: %v", err)
+			}
+			tt.read(rows)
+
+			if got := breaker.State(); got != tt.wantState {
+				t.Errorf("state = %s, want %s", got, tt.wantState)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package databaseutil
+
+import (
+	"sync"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+var (
+	codesMu sync.RWMutex
+
+	pgCodes = map[string]error{
+		PGErrUniqueViolation:      ErrUniqueViolation,
+		PGErrForeignKeyViolation:  ErrForeignKeyViolation,
+		PGErrCheckViolation:       ErrCheckViolation,
+		PGErrNotNullViolation:     ErrNotNullViolation,
+		PGErrDeadlockDetected:     ErrDeadlockDetected,
+		PGErrSerializationFailure: ErrSerializationFailure,
+	}
+
+	mssqlNumbers = map[int32]error{
+		MSSQLErrUniqueViolation:     ErrUniqueViolation,
+		MSSQLErrUniqueIndex:         ErrUniqueViolation,
+		MSSQLErrForeignKeyViolation: ErrForeignKeyViolation,
+		MSSQLErrNotNullViolation:    ErrNotNullViolation,
+		MSSQLErrDeadlockDetected:    ErrDeadlockDetected,
+		MSSQLErrLockTimeout:         ErrQueryTimeout,
+	}
+)
+
+// RegisterPgCode makes WrapDBError and WrapDBErrorWithKeyValue wrap the Postgres errors with
+// SQLSTATE code into sentinel, e.g. exclusion violations (23P01) or the codes raised by the
+// application's own functions. It is meant to be called once at startup and replaces the
+// built-in mapping of code, if any.
+func RegisterPgCode(code string, sentinel error) {
+	codesMu.Lock()
+	defer codesMu.Unlock()
+
+	pgCodes[code] = sentinel
+}
+
+// RegisterMSSQLNumber is the SQL Server counterpart of RegisterPgCode, keyed by error number
+func RegisterMSSQLNumber(number int32, sentinel error) {
+	codesMu.Lock()
+	defer codesMu.Unlock()
+
+	mssqlNumbers[number] = sentinel
+}
+
+func pgSentinel(code string) (error, bool) {
+	codesMu.RLock()
+	defer codesMu.RUnlock()
+
+	sentinel, ok := pgCodes[code]
+	return sentinel, ok
+}
+
+func mssqlSentinel(err mssql.Error) (error, bool) {
+	codesMu.RLock()
+	defer codesMu.RUnlock()
+
+	sentinel, ok := mssqlNumbers[err.Number]
+	if ok && sentinel == ErrForeignKeyViolation && isMSSQLCheckViolation(err) {
+		return ErrCheckViolation, true
+	}
+	return sentinel, ok
+}
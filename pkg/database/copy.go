@@ -0,0 +1,142 @@
+package databaseutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Copier is implemented by pgx.Tx, *pgx.Conn and *pgxpool.Pool
+type Copier interface {
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+// CopyFrom inserts rows into table with the COPY protocol, which is much faster than one INSERT per
+// row for imports. The value of every column is read from the struct field with the matching `db`
+// tag, columns defaults to every tagged field in declaration order:
+//
+//	type userRow struct {
+//		ID    uuid.UUID `db:"id"`
+//		Email string    `db:"email"`
+//		Note  string    // not copied
+//	}
+//
+//	n, err := databaseutil.CopyFrom(ctx, tx, "public.users", nil, rows)
+//
+// The rows are read one at a time while they are sent, without building an intermediate copy.
+// Database errors are wrapped like WrapDBError does, e.g. a duplicate row matches ErrUniqueViolation.
+// COPY aborts on the first failing row, so nothing is inserted when an error is returned.
+func CopyFrom[T any](ctx context.Context, tx Copier, table string, columns []string, rows []T) (int64, error) {
+	if table == "" {
+		return 0, errors.New("copy needs a table")
+	}
+
+	rowType := reflect.TypeOf((*T)(nil)).Elem()
+	pointer := rowType.Kind() == reflect.Pointer
+	if pointer {
+		rowType = rowType.Elem()
+	}
+	if rowType.Kind() != reflect.Struct {
+		return 0, fmt.Errorf("copy rows must be structs, got %s", rowType)
+	}
+
+	fields := dbTagFields(rowType)
+	if columns == nil {
+		for _, field := range fields {
+			columns = append(columns, field.name)
+		}
+	}
+
+	indexes := make([][]int, len(columns))
+	for i, column := range columns {
+		index, ok := fieldIndex(fields, column)
+		if !ok {
+			return 0, fmt.Errorf("copy column %q has no field tagged `db:%q` in %s", column, column, rowType)
+		}
+		indexes[i] = index
+	}
+
+	source := &structCopySource[T]{rows: rows, indexes: indexes, pointer: pointer, current: -1}
+	copied, err := tx.CopyFrom(ctx, pgx.Identifier(strings.Split(table, ".")), columns, source)
+	if err != nil {
+		if wrappedErr := wrapPgError(err); wrappedErr != nil {
+			err = wrappedErr
+		}
+		return copied, fmt.Errorf("failed to copy rows into %s: %w", table, err)
+	}
+	return copied, nil
+}
+
+type dbField struct {
+	name  string
+	index []int
+}
+
+// dbTagFields lists the fields tagged with `db`, including the ones promoted from embedded structs
+func dbTagFields(t reflect.Type) []dbField {
+	var fields []dbField
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("db"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		fields = append(fields, dbField{name: name, index: field.Index})
+	}
+	return fields
+}
+
+func fieldIndex(fields []dbField, column string) ([]int, bool) {
+	for _, field := range fields {
+		if field.name == column {
+			return field.index, true
+		}
+	}
+	return nil, false
+}
+
+// structCopySource is a pgx.CopyFromSource reading the values of a row when pgx asks for it
+type structCopySource[T any] struct {
+	rows    []T
+	indexes [][]int
+	pointer bool
+	current int
+	err     error
+}
+
+func (s *structCopySource[T]) Next() bool {
+	s.current++
+	return s.current < len(s.rows)
+}
+
+func (s *structCopySource[T]) Values() ([]any, error) {
+	row := reflect.ValueOf(&s.rows[s.current]).Elem()
+	if s.pointer {
+		if row.IsNil() {
+			s.err = fmt.Errorf("copy row %d is nil", s.current)
+			return nil, s.err
+		}
+		row = row.Elem()
+	}
+
+	values := make([]any, len(s.indexes))
+	for i, index := range s.indexes {
+		field, err := row.FieldByIndexErr(index)
+		if err != nil {
+			// A nil embedded struct pointer, the column is NULL
+			continue
+		}
+		values[i] = field.Interface()
+	}
+	return values, nil
+}
+
+func (s *structCopySource[T]) Err() error {
+	return s.err
+}
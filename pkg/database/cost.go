@@ -0,0 +1,36 @@
+package databaseutil
+
+import (
+	"context"
+
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"github.com/jackc/pgx/v5"
+)
+
+// CostTracer is a pgx.QueryTracer reporting the queries of a request and the rows they read or
+// wrote to the request's cost counter, so they end up as attributes of the request span. Combine
+// it with other tracers, such as the IndexAdvisor, using pgx's multitracer package.
+type CostTracer struct{}
+
+func (CostTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, _ pgx.TraceQueryStartData) context.Context {
+	return ctx
+}
+
+func (CostTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	counter := logutil.CostCounterFromContext(ctx)
+	if counter == nil {
+		return
+	}
+
+	counter.Add("db.queries", 1)
+	if data.Err != nil {
+		return
+	}
+
+	rows := data.CommandTag.RowsAffected()
+	if data.CommandTag.Select() {
+		counter.Add("db.rows_read", rows)
+	} else if data.CommandTag.Insert() || data.CommandTag.Update() || data.CommandTag.Delete() {
+		counter.Add("db.rows_written", rows)
+	}
+}
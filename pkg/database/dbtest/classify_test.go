@@ -0,0 +1,71 @@
+//go:build integration
+
+package dbtest
+
+import (
+	"context"
+	"testing"
+
+	databaseutil "github.com/NYCU-SDC/summer/pkg/database"
+	handlerutil "github.com/NYCU-SDC/summer/pkg/handler"
+)
+
+// TestErrorClassification checks both dialects classify the same failures as the same sentinel
+// errors. Run it with: go test -tags integration ./pkg/database/dbtest
+func TestErrorClassification(t *testing.T) {
+	Run(t, func(t *testing.T, db Target) {
+		db.MustExec(t, "CREATE TABLE dbtest_teams (id INT PRIMARY KEY)")
+		db.MustExec(t, "CREATE TABLE dbtest_users (id INT PRIMARY KEY, email VARCHAR(100) NOT NULL UNIQUE, team_id INT REFERENCES dbtest_teams (id), age INT CHECK (age >= 0))")
+		t.Cleanup(func() {
+			db.MustExec(t, "DROP TABLE dbtest_users")
+			db.MustExec(t, "DROP TABLE dbtest_teams")
+		})
+		db.MustExec(t, "INSERT INTO dbtest_teams (id) VALUES (1)")
+		db.MustExec(t, "INSERT INTO dbtest_users (id, email, team_id) VALUES (1, 'a@example.com', 1)")
+
+		ctx := context.Background()
+		tests := []struct {
+			name  string
+			query string
+			args  []any
+			want  error
+		}{
+			{
+				name:  "Should classify unique violation",
+				query: "INSERT INTO dbtest_users (id, email) VALUES ($1, $2)",
+				args:  []any{2, "a@example.com"},
+				want:  databaseutil.ErrUniqueViolation,
+			},
+			{
+				name:  "Should classify foreign key violation",
+				query: "INSERT INTO dbtest_users (id, email, team_id) VALUES ($1, $2, $3)",
+				args:  []any{3, "c@example.com", 42},
+				want:  databaseutil.ErrForeignKeyViolation,
+			},
+			{
+				name:  "Should classify not null violation",
+				query: "INSERT INTO dbtest_users (id, email) VALUES ($1, NULL)",
+				args:  []any{4},
+				want:  databaseutil.ErrNotNullViolation,
+			},
+			{
+				name:  "Should classify check violation",
+				query: "INSERT INTO dbtest_users (id, email, age) VALUES ($1, $2, $3)",
+				args:  []any{5, "e@example.com", -1},
+				want:  databaseutil.ErrCheckViolation,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				db.AssertIs(t, db.Exec(ctx, tt.query, tt.args...), tt.want)
+			})
+		}
+
+		t.Run("Should classify missing row", func(t *testing.T) {
+			var email string
+			err := db.QueryRow(ctx, "SELECT email FROM dbtest_users WHERE id = $1", []any{404}, &email)
+			db.AssertIs(t, err, handlerutil.ErrNotFound)
+		})
+	})
+}
@@ -0,0 +1,177 @@
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	databaseutil "github.com/NYCU-SDC/summer/pkg/database"
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/microsoft/go-mssqldb"
+	"go.uber.org/zap"
+)
+
+// Environment variables holding the connection strings of the test databases, a dialect without
+// one is skipped. CI typically starts both as service containers and sets them.
+const (
+	PostgresURLEnv = "SUMMER_TEST_POSTGRES_URL"
+	MSSQLURLEnv    = "SUMMER_TEST_MSSQL_URL"
+)
+
+// Dialect is a database the matrix runs against
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	MSSQL    Dialect = "mssql"
+)
+
+// Target is one database of the matrix. Queries are written once with Postgres $n placeholders and
+// rewritten for the dialect, and errors go through the wrapper of the dialect, so a store test
+// written against Target checks the same classification on both.
+type Target struct {
+	Dialect Dialect
+
+	// Pool is set for Postgres and DB for SQL Server
+	Pool *pgxpool.Pool
+	DB   *sql.DB
+
+	logger *zap.Logger
+}
+
+// Run runs fn as a subtest against every database configured through the environment, and skips
+// the test when none is. Each subtest fails on its own, so dialect drift shows which side broke:
+//
+//	func TestUserStore(t *testing.T) {
+//		dbtest.Run(t, func(t *testing.T, db dbtest.Target) {
+//			db.MustExec(t, "CREATE TABLE users (id INT PRIMARY KEY, email VARCHAR(100) UNIQUE)")
+//			t.Cleanup(func() { db.MustExec(t, "DROP TABLE users") })
+//			// ...
+//		})
+//	}
+func Run(t *testing.T, fn func(t *testing.T, db Target)) {
+	t.Helper()
+
+	targets := []struct {
+		dialect Dialect
+		env     string
+	}{
+		{dialect: Postgres, env: PostgresURLEnv},
+		{dialect: MSSQL, env: MSSQLURLEnv},
+	}
+
+	configured := 0
+	for _, target := range targets {
+		url := os.Getenv(target.env)
+		if url == "" {
+			continue
+		}
+		configured++
+
+		t.Run(string(target.dialect), func(t *testing.T) {
+			fn(t, open(t, target.dialect, url))
+		})
+	}
+
+	if configured == 0 {
+		t.Skipf("no test database configured, set %s and/or %s", PostgresURLEnv, MSSQLURLEnv)
+	}
+}
+
+func open(t *testing.T, dialect Dialect, url string) Target {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	target := Target{Dialect: dialect, logger: zap.NewNop()}
+	switch dialect {
+	case Postgres:
+		pool, err := pgxpool.New(ctx, url)
+		if err == nil {
+			err = pool.Ping(ctx)
+		}
+		if err != nil {
+			t.Fatalf("failed to connect to %s: %v", dialect, err)
+		}
+		t.Cleanup(pool.Close)
+		target.Pool = pool
+	case MSSQL:
+		db, err := sql.Open("sqlserver", url)
+		if err == nil {
+			err = db.PingContext(ctx)
+		}
+		if err != nil {
+			t.Fatalf("failed to connect to %s: %v", dialect, err)
+		}
+		t.Cleanup(func() { _ = db.Close() })
+		target.DB = db
+	}
+	return target
+}
+
+// Exec runs a statement and returns its error wrapped like the stores of the dialect do
+func (d Target) Exec(ctx context.Context, query string, args ...any) error {
+	var err error
+	if d.Dialect == Postgres {
+		_, err = d.Pool.Exec(ctx, query, args...)
+	} else {
+		_, err = d.DB.ExecContext(ctx, d.Rebind(query), args...)
+	}
+	return d.Wrap(err, "exec")
+}
+
+// QueryRow runs a query returning one row, scans it into dest and returns the wrapped error
+func (d Target) QueryRow(ctx context.Context, query string, args []any, dest ...any) error {
+	var err error
+	if d.Dialect == Postgres {
+		err = d.Pool.QueryRow(ctx, query, args...).Scan(dest...)
+	} else {
+		err = d.DB.QueryRowContext(ctx, d.Rebind(query), args...).Scan(dest...)
+	}
+	return d.Wrap(err, "query row")
+}
+
+// MustExec runs a statement, e.g. a fixture, and fails the test on error
+func (d Target) MustExec(t testing.TB, query string, args ...any) {
+	t.Helper()
+
+	err := d.Exec(context.Background(), query, args...)
+	if err != nil {
+		t.Fatalf("failed to exec %q on %s: %v", query, d.Dialect, err)
+	}
+}
+
+// Wrap classifies err with WrapDBError or WrapMSSQLError
+func (d Target) Wrap(err error, operation string) error {
+	if err == nil {
+		return nil
+	}
+	if d.Dialect == Postgres {
+		return databaseutil.WrapDBError(err, d.logger, operation)
+	}
+	return databaseutil.WrapMSSQLError(err, d.logger, operation)
+}
+
+var placeholderPattern = regexp.MustCompile(`\$(\d+)`)
+
+// Rebind rewrites the $n placeholders of query for the dialect, @pn for SQL Server
+func (d Target) Rebind(query string) string {
+	if d.Dialect != MSSQL {
+		return query
+	}
+	return placeholderPattern.ReplaceAllString(query, "@p$1")
+}
+
+// AssertIs fails the test when err does not match target, naming the dialect
+func (d Target) AssertIs(t testing.TB, err, target error) {
+	t.Helper()
+
+	if !errors.Is(err, target) {
+		t.Errorf("%s: error = %v, want %v", d.Dialect, err, target)
+	}
+}
@@ -0,0 +1,156 @@
+//go:build integration
+
+package dbtest
+
+import (
+	"context"
+	"testing"
+
+	databaseutil "github.com/NYCU-SDC/summer/pkg/database"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// noCopyBeginner begins transactions rejecting COPY, like the poolers that do not support it
+type noCopyBeginner struct {
+	databaseutil.TxBeginner
+	copies *int
+}
+
+func (b noCopyBeginner) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	tx, err := b.TxBeginner.BeginTx(ctx, txOptions)
+	if err != nil {
+		return nil, err
+	}
+	return noCopyTx{Tx: tx, copies: b.copies}, nil
+}
+
+type noCopyTx struct {
+	pgx.Tx
+	copies *int
+}
+
+func (tx noCopyTx) CopyFrom(context.Context, pgx.Identifier, []string, pgx.CopyFromSource) (int64, error) {
+	*tx.copies++
+	return 0, &pgconn.PgError{Code: databaseutil.PGErrFeatureNotSupported, Message: "COPY is not supported"}
+}
+
+// TestBulkUpsert merges rows with COPY, and with INSERT once COPY is rejected. It only runs on
+// Postgres. Run it with: go test -tags integration ./pkg/database/dbtest
+func TestBulkUpsert(t *testing.T) {
+	Run(t, func(t *testing.T, db Target) {
+		if db.Dialect != Postgres {
+			t.Skip("BulkUpsert only supports Postgres")
+		}
+
+		db.MustExec(t, "CREATE TABLE dbtest_upsert_users (id INT PRIMARY KEY, email TEXT NOT NULL, name TEXT)")
+		t.Cleanup(func() { db.MustExec(t, "DROP TABLE dbtest_upsert_users") })
+
+		ctx := context.Background()
+		opts := databaseutil.BulkUpsertOptions{
+			Table:           "dbtest_upsert_users",
+			Columns:         []string{"id", "email", "name"},
+			ConflictColumns: []string{"id"},
+			ChunkSize:       2,
+		}
+
+		t.Run("Should upsert with COPY", func(t *testing.T) {
+			db.MustExec(t, "TRUNCATE dbtest_upsert_users")
+			db.MustExec(t, "INSERT INTO dbtest_upsert_users (id, email, name) VALUES (1, 'old@example.com', 'Old')")
+
+			affected, err := databaseutil.BulkUpsert(ctx, db.Pool, [][]any{
+				{1, "a@example.com", "Alice"},
+				{2, "b@example.com", "Bob"},
+				{3, "c@example.com", nil},
+			}, opts)
+			if err != nil {
+				t.Fatalf("BulkUpsert() unexpected error: %v", err)
+			}
+			if affected != 3 {
+				t.Errorf("BulkUpsert() affected = %d, want 3", affected)
+			}
+			assertUpsertedEmail(t, db, 1, "a@example.com")
+			assertUpsertedCount(t, db, 3)
+		})
+
+		t.Run("Should fall back to INSERT when COPY is not supported", func(t *testing.T) {
+			db.MustExec(t, "TRUNCATE dbtest_upsert_users")
+			db.MustExec(t, "INSERT INTO dbtest_upsert_users (id, email, name) VALUES (1, 'old@example.com', 'Old')")
+
+			core, logs := observer.New(zapcore.WarnLevel)
+			fallbackOpts := opts
+			fallbackOpts.Logger = zap.New(core)
+			copies := 0
+
+			affected, err := databaseutil.BulkUpsert(ctx, noCopyBeginner{TxBeginner: db.Pool, copies: &copies}, [][]any{
+				{1, "a@example.com", "Alice"},
+				{2, "b@example.com", "Bob"},
+				{3, "c@example.com", nil},
+			}, fallbackOpts)
+			if err != nil {
+				t.Fatalf("BulkUpsert() unexpected error: %v", err)
+			}
+			if affected != 3 {
+				t.Errorf("BulkUpsert() affected = %d, want 3", affected)
+			}
+			if copies != 1 {
+				t.Errorf("BulkUpsert() tried COPY %d times, want once before falling back", copies)
+			}
+			if logs.FilterMessage("COPY is not supported, falling back to INSERT for bulk upsert").Len() != 1 {
+				t.Errorf("BulkUpsert() did not log the fallback once: %v", logs.All())
+			}
+			assertUpsertedEmail(t, db, 1, "a@example.com")
+			assertUpsertedCount(t, db, 3)
+		})
+
+		t.Run("Should keep existing rows without update columns", func(t *testing.T) {
+			db.MustExec(t, "TRUNCATE dbtest_upsert_users")
+			db.MustExec(t, "INSERT INTO dbtest_upsert_users (id, email, name) VALUES (1, 'old@example.com', 'Old')")
+
+			keepOpts := opts
+			keepOpts.UpdateColumns = []string{}
+			keepOpts.Method = databaseutil.UpsertInsert
+
+			affected, err := databaseutil.BulkUpsert(ctx, db.Pool, [][]any{
+				{1, "a@example.com", "Alice"},
+				{2, "b@example.com", "Bob"},
+			}, keepOpts)
+			if err != nil {
+				t.Fatalf("BulkUpsert() unexpected error: %v", err)
+			}
+			if affected != 1 {
+				t.Errorf("BulkUpsert() affected = %d, want 1", affected)
+			}
+			assertUpsertedEmail(t, db, 1, "old@example.com")
+		})
+	})
+}
+
+func assertUpsertedEmail(t *testing.T, db Target, id int, want string) {
+	t.Helper()
+
+	var email string
+	err := db.QueryRow(context.Background(), "SELECT email FROM dbtest_upsert_users WHERE id = $1", []any{id}, &email)
+	if err != nil {
+		t.Fatalf("failed to read user %d: %v", id, err)
+	}
+	if email != want {
+		t.Errorf("user %d email = %q, want %q", id, email, want)
+	}
+}
+
+func assertUpsertedCount(t *testing.T, db Target, want int) {
+	t.Helper()
+
+	var count int
+	err := db.QueryRow(context.Background(), "SELECT count(*) FROM dbtest_upsert_users", nil, &count)
+	if err != nil {
+		t.Fatalf("failed to count users: %v", err)
+	}
+	if count != want {
+		t.Errorf("user count = %d, want %d", count, want)
+	}
+}
@@ -0,0 +1,158 @@
+package databaseutil
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"slices"
+	"strings"
+
+	errorPkg "github.com/NYCU-SDC/summer/pkg/handler"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Enum maps a Go string type to a Postgres enum, so values are checked when they are parsed from
+// clients, encoded into queries and scanned from rows, instead of failing later as internal errors.
+// Declare it next to the type and delegate the database/sql interfaces to it, pgx uses them:
+//
+//	type Role string
+//
+//	var Roles = databaseutil.NewEnum("role", RoleAdmin, RoleMember)
+//
+//	func (r *Role) Scan(src any) error          { return Roles.Scan(src, r) }
+//	func (r Role) Value() (driver.Value, error) { return Roles.Value(r) }
+type Enum[E ~string] struct {
+	name   string
+	values []E
+}
+
+// NewEnum describes the Postgres enum type name, schema qualified if needed, with values in the
+// order of the type
+func NewEnum[E ~string](name string, values ...E) Enum[E] {
+	return Enum[E]{name: name, values: values}
+}
+
+// Name returns the Postgres type name
+func (e Enum[E]) Name() string {
+	return e.name
+}
+
+// Values returns a copy of the values
+func (e Enum[E]) Values() []E {
+	return slices.Clone(e.values)
+}
+
+// Valid reports whether v is one of the values
+func (e Enum[E]) Valid(v E) bool {
+	return slices.Contains(e.values, v)
+}
+
+// Parse converts a value sent by a client, an unknown value returns a handlerutil.ValidationError
+// listing the allowed values, written as a 400 problem
+func (e Enum[E]) Parse(s string) (E, error) {
+	v := E(s)
+	if !e.Valid(v) {
+		return "", errorPkg.NewValidationError(e.name, s, fmt.Sprintf("'%s' is not a valid %s, must be one of: %s", s, e.name, e.quotedValues()))
+	}
+	return v, nil
+}
+
+// Scan stores src into dst, it fails on values unknown to the Go type, e.g. a value added to the
+// Postgres type before the code was deployed
+func (e Enum[E]) Scan(src any, dst *E) error {
+	var s string
+	switch src := src.(type) {
+	case string:
+		s = src
+	case []byte:
+		s = string(src)
+	case nil:
+		return fmt.Errorf("cannot scan NULL into %s, use a pointer", e.name)
+	default:
+		return fmt.Errorf("cannot scan %T into %s", src, e.name)
+	}
+
+	v := E(s)
+	if !e.Valid(v) {
+		return fmt.Errorf("%w: unknown %s %q read from the database", ErrEnumMismatch, e.name, s)
+	}
+	*dst = v
+	return nil
+}
+
+// Value encodes v for a query, it fails with ErrInvalidEnumValue before the query is sent when v
+// is not one of the values
+func (e Enum[E]) Value(v E) (driver.Value, error) {
+	if !e.Valid(v) {
+		return nil, fmt.Errorf("%w: %q is not a valid %s", ErrInvalidEnumValue, string(v), e.name)
+	}
+	return string(v), nil
+}
+
+// CreateTypeSQL returns the statement creating the Postgres type, for the up migration
+func (e Enum[E]) CreateTypeSQL() string {
+	return fmt.Sprintf("CREATE TYPE %s AS ENUM (%s);", e.identifier(), e.quotedValues())
+}
+
+// AddValueSQL returns the statements adding values to the Postgres type, for the migration shipping
+// them. Postgres cannot remove enum values, dropping one needs a new type.
+func (e Enum[E]) AddValueSQL(values ...E) string {
+	statements := make([]string, 0, len(values))
+	for _, v := range values {
+		statements = append(statements, fmt.Sprintf("ALTER TYPE %s ADD VALUE IF NOT EXISTS %s;", e.identifier(), quoteLiteral(string(v))))
+	}
+	return strings.Join(statements, "\n")
+}
+
+// Verify compares the values with the ones of the Postgres type and returns ErrEnumMismatch listing
+// the differences, so a mismatch fails at startup instead of on the first request using the value
+func (e Enum[E]) Verify(ctx context.Context, db DBTX) error {
+	rows, err := db.Query(ctx, "SELECT e.enumlabel FROM pg_enum e WHERE e.enumtypid = $1::regtype ORDER BY e.enumsortorder", e.name)
+	if err != nil {
+		return fmt.Errorf("failed to read the values of enum %s: %w", e.name, err)
+	}
+	labels, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("failed to read the values of enum %s: %w", e.name, err)
+	}
+
+	var missing, unknown []string
+	for _, v := range e.values {
+		if !slices.Contains(labels, string(v)) {
+			missing = append(missing, string(v))
+		}
+	}
+	for _, label := range labels {
+		if !e.Valid(E(label)) {
+			unknown = append(unknown, label)
+		}
+	}
+
+	if len(missing) > 0 || len(unknown) > 0 {
+		return fmt.Errorf("%w: %s is missing %v in the database and %v in Go", ErrEnumMismatch, e.name, missing, unknown)
+	}
+	return nil
+}
+
+func (e Enum[E]) identifier() string {
+	return pgx.Identifier(strings.Split(e.name, ".")).Sanitize()
+}
+
+// quotedValues returns the values as a list of SQL literals
+func (e Enum[E]) quotedValues() string {
+	quoted := make([]string, 0, len(e.values))
+	for _, v := range e.values {
+		quoted = append(quoted, quoteLiteral(string(v)))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// isPgEnumError reports whether pgErr is Postgres rejecting a value of an enum type
+func isPgEnumError(pgErr *pgconn.PgError) bool {
+	return pgErr.Code == PGErrInvalidTextRepresentation && strings.Contains(pgErr.Message, "invalid input value for enum")
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"regexp"
+	"strings"
+
 	errorPkg "github.com/NYCU-SDC/summer/pkg/handler"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -11,18 +14,96 @@ import (
 )
 
 const (
-	PGErrUniqueViolation     = "23505"
-	PGErrForeignKeyViolation = "23503"
-	PGErrDeadlockDetected    = "40P01"
+	PGErrUniqueViolation      = "23505"
+	PGErrForeignKeyViolation  = "23503"
+	PGErrCheckViolation       = "23514"
+	PGErrNotNullViolation     = "23502"
+	PGErrDeadlockDetected     = "40P01"
+	PGErrSerializationFailure = "40001"
+
+	PGErrInvalidTextRepresentation = "22P02"
+	PGErrQueryCanceled             = "57014"
 )
 
 var (
-	ErrUniqueViolation     = errors.New("unique constraint violation")
-	ErrForeignKeyViolation = errors.New("foreign key violation")
-	ErrDeadlockDetected    = errors.New("deadlock detected")
-	ErrQueryTimeout        = errors.New("query timed out")
+	ErrUniqueViolation      = errors.New("unique constraint violation")
+	ErrForeignKeyViolation  = errors.New("foreign key violation")
+	ErrCheckViolation       = errors.New("check constraint violation")
+	ErrNotNullViolation     = errors.New("not null violation")
+	ErrDeadlockDetected     = errors.New("deadlock detected")
+	ErrSerializationFailure = errors.New("serialization failure")
+	ErrQueryTimeout         = errors.New("query timed out")
+	ErrStatementTimeout     = errors.New("statement timed out on the server")
+	ErrInvalidEnumValue     = errors.New("invalid enum value")
+	ErrEnumMismatch         = errors.New("enum values differ from the database")
 )
 
+// UniqueViolationError is a unique violation with the conflicting columns and values parsed from
+// the error detail, e.g. "Key (email)=(alice@example.com) already exists." Columns and Values are
+// empty when the detail cannot be parsed, such as when the database hides it from the client.
+type UniqueViolationError struct {
+	Constraint string
+	Table      string
+	Columns    []string
+	Values     []string
+	Source     error
+}
+
+func (e UniqueViolationError) Error() string {
+	return fmt.Sprintf("%s: %v", ErrUniqueViolation.Error(), e.Source)
+}
+
+func (e UniqueViolationError) Is(target error) bool {
+	return errors.Is(target, ErrUniqueViolation)
+}
+
+var uniqueDetailPattern = regexp.MustCompile(`^Key \((.+)\)=\((.*)\) already exists\.?$`)
+
+func newUniqueViolationError(pgErr *pgconn.PgError, err error) UniqueViolationError {
+	violation := UniqueViolationError{
+		Constraint: pgErr.ConstraintName,
+		Table:      pgErr.TableName,
+		Source:     err,
+	}
+
+	match := uniqueDetailPattern.FindStringSubmatch(pgErr.Detail)
+	if match == nil {
+		return violation
+	}
+	violation.Columns = strings.Split(match[1], ", ")
+	// Values containing ", " cannot be told apart, they are only kept when they split evenly
+	if values := strings.Split(match[2], ", "); len(values) == len(violation.Columns) {
+		violation.Values = values
+	}
+	return violation
+}
+
+// wrapPgError wraps a Postgres error into its registered sentinel, it returns nil for other errors
+func wrapPgError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return nil
+	}
+
+	sentinel, ok := pgSentinel(pgErr.Code)
+	switch {
+	case !ok && isPgEnumError(pgErr):
+		return fmt.Errorf("%w: %v", ErrInvalidEnumValue, err)
+	case !ok && pgErr.Code == PGErrQueryCanceled:
+		// The statement_timeout of the server, or the cancel request pgx sends when the context is done
+		if strings.Contains(pgErr.Message, "statement timeout") {
+			return fmt.Errorf("%w: %v", ErrStatementTimeout, err)
+		}
+		return fmt.Errorf("%w: %v", ErrQueryTimeout, err)
+	case !ok:
+		return nil
+	case sentinel == ErrUniqueViolation:
+		return newUniqueViolationError(pgErr, err)
+	default:
+		return fmt.Errorf("%w: %v", sentinel, err)
+	}
+}
+
 type InternalServerError struct {
 	Source error
 }
@@ -45,18 +126,10 @@ func WrapDBError(err error, logger *zap.Logger, operation string) error {
 		wrappedErr = fmt.Errorf("%w: %v", errorPkg.ErrNotFound, err)
 	case errors.Is(err, context.DeadlineExceeded):
 		wrappedErr = fmt.Errorf("%w: %v", ErrQueryTimeout, err)
+	case errors.Is(err, ErrInvalidEnumValue):
+		wrappedErr = err
 	default:
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			switch pgErr.Code {
-			case PGErrUniqueViolation:
-				wrappedErr = fmt.Errorf("%w: %v", ErrUniqueViolation, err)
-			case PGErrForeignKeyViolation:
-				wrappedErr = fmt.Errorf("%w: %v", ErrForeignKeyViolation, err)
-			case PGErrDeadlockDetected:
-				wrappedErr = fmt.Errorf("%w: %v", ErrDeadlockDetected, err)
-			}
-		}
+		wrappedErr = wrapPgError(err)
 	}
 
 	isUnknownError := false
@@ -84,18 +157,10 @@ func WrapDBErrorWithKeyValue(err error, table, key, value string, logger *zap.Lo
 		wrappedErr = errorPkg.NewNotFoundError(table, key, value, "")
 	case errors.Is(err, context.DeadlineExceeded):
 		wrappedErr = fmt.Errorf("%w: %v", ErrQueryTimeout, err)
+	case errors.Is(err, ErrInvalidEnumValue):
+		wrappedErr = err
 	default:
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) {
-			switch pgErr.Code {
-			case PGErrUniqueViolation:
-				wrappedErr = fmt.Errorf("%w: %v", ErrUniqueViolation, err)
-			case PGErrForeignKeyViolation:
-				wrappedErr = fmt.Errorf("%w: %v", ErrForeignKeyViolation, err)
-			case PGErrDeadlockDetected:
-				wrappedErr = fmt.Errorf("%w: %v", ErrDeadlockDetected, err)
-			}
-		}
+		wrappedErr = wrapPgError(err)
 	}
 
 	isUnknownError := false
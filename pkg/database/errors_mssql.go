@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 
 	errorPkg "github.com/NYCU-SDC/summer/pkg/handler"
 	mssql "github.com/microsoft/go-mssqldb"
@@ -15,8 +16,10 @@ const (
 	// SQL Server error numbers
 	MSSQLErrUniqueViolation     = 2627 // Unique constraint violation
 	MSSQLErrUniqueIndex         = 2601 // Duplicate key in unique index
-	MSSQLErrForeignKeyViolation = 547  // Foreign key violation
+	MSSQLErrForeignKeyViolation = 547  // Foreign key or check constraint violation
+	MSSQLErrNotNullViolation    = 515  // Cannot insert NULL into a column
 	MSSQLErrDeadlockDetected    = 1205 // Deadlock detected
+	MSSQLErrLockTimeout         = 1222 // Lock request time out period exceeded
 )
 
 func WrapMSSQLError(err error, logger *zap.Logger, operation string) error {
@@ -24,7 +27,7 @@ func WrapMSSQLError(err error, logger *zap.Logger, operation string) error {
 		return nil
 	}
 
-	logger.Error("Failed to "+operation, zap.Error(err))
+	logger.WithOptions(zap.AddCallerSkip(1)).Error("Failed to "+operation, zap.Error(err))
 
 	var wrappedErr error
 
@@ -36,13 +39,8 @@ func WrapMSSQLError(err error, logger *zap.Logger, operation string) error {
 	default:
 		var mssqlErr mssql.Error
 		if errors.As(err, &mssqlErr) {
-			switch mssqlErr.Number {
-			case MSSQLErrUniqueViolation, MSSQLErrUniqueIndex:
-				wrappedErr = fmt.Errorf("%w: %v", ErrUniqueViolation, err)
-			case MSSQLErrForeignKeyViolation:
-				wrappedErr = fmt.Errorf("%w: %v", ErrForeignKeyViolation, err)
-			case MSSQLErrDeadlockDetected:
-				wrappedErr = fmt.Errorf("%w: %v", ErrDeadlockDetected, err)
+			if sentinel, ok := mssqlSentinel(mssqlErr); ok {
+				wrappedErr = fmt.Errorf("%w: %v", sentinel, err)
 			}
 		}
 	}
@@ -53,7 +51,7 @@ func WrapMSSQLError(err error, logger *zap.Logger, operation string) error {
 		isUnknownError = true
 	}
 
-	logger.Warn("Wrapped database error", zap.Error(wrappedErr), zap.String("operation", operation), zap.Bool("unknown_error", isUnknownError))
+	logger.WithOptions(zap.AddCallerSkip(1)).Warn("Wrapped database error", zap.Error(wrappedErr), zap.String("operation", operation), zap.Bool("unknown_error", isUnknownError))
 
 	return wrappedErr
 }
@@ -63,7 +61,7 @@ func WrapMSSQLErrorWithKeyValue(err error, table, key, value string, logger *zap
 		return nil
 	}
 
-	logger.Error("Failed to "+operation, zap.Error(err))
+	logger.WithOptions(zap.AddCallerSkip(1)).Error("Failed to "+operation, zap.Error(err))
 
 	var wrappedErr error
 
@@ -75,13 +73,8 @@ func WrapMSSQLErrorWithKeyValue(err error, table, key, value string, logger *zap
 	default:
 		var mssqlErr mssql.Error
 		if errors.As(err, &mssqlErr) {
-			switch mssqlErr.Number {
-			case MSSQLErrUniqueViolation, MSSQLErrUniqueIndex:
-				wrappedErr = fmt.Errorf("%w: %v", ErrUniqueViolation, err)
-			case MSSQLErrForeignKeyViolation:
-				wrappedErr = fmt.Errorf("%w: %v", ErrForeignKeyViolation, err)
-			case MSSQLErrDeadlockDetected:
-				wrappedErr = fmt.Errorf("%w: %v", ErrDeadlockDetected, err)
+			if sentinel, ok := mssqlSentinel(mssqlErr); ok {
+				wrappedErr = fmt.Errorf("%w: %v", sentinel, err)
 			}
 		}
 	}
@@ -92,7 +85,13 @@ func WrapMSSQLErrorWithKeyValue(err error, table, key, value string, logger *zap
 		isUnknownError = true
 	}
 
-	logger.Warn("Wrapped database error with key value", zap.Error(wrappedErr), zap.String("table", table), zap.String("key", key), zap.String("value", value), zap.String("operation", operation), zap.Bool("unknown_error", isUnknownError))
+	logger.WithOptions(zap.AddCallerSkip(1)).Warn("Wrapped database error with key value", zap.Error(wrappedErr), zap.String("table", table), zap.String("key", key), zap.String("value", value), zap.String("operation", operation), zap.Bool("unknown_error", isUnknownError))
 
 	return wrappedErr
 }
+
+// isMSSQLCheckViolation tells check constraint conflicts apart from foreign key ones, SQL Server
+// reports both as error 547 and only the message names the kind of constraint
+func isMSSQLCheckViolation(err mssql.Error) bool {
+	return strings.Contains(err.Message, "CHECK constraint")
+}
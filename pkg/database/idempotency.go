@@ -0,0 +1,102 @@
+package databaseutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// IdempotencyTableSQL creates the table used by the idempotency helpers, add it to a migration
+const IdempotencyTableSQL = `CREATE TABLE IF NOT EXISTS idempotency_keys (
+    scope        TEXT        NOT NULL,
+    key          TEXT        NOT NULL,
+    request_hash TEXT        NOT NULL,
+    response     BYTEA,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (scope, key)
+);`
+
+var ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+
+// IdempotencyTx is implemented by pgx.Tx, the helpers must run in the transaction of the
+// mutation they protect so that the key and the effect are committed or rolled back together
+type IdempotencyTx interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// IdempotencyRecord is a stored operation, Response is nil until CompleteIdempotencyKey is called
+type IdempotencyRecord struct {
+	Scope       string
+	Key         string
+	RequestHash string
+	Response    []byte
+	CreatedAt   time.Time
+}
+
+// ClaimIdempotencyKey inserts the key, or returns the record already stored for it. When claimed
+// is true the caller performs the mutation and calls CompleteIdempotencyKey in the same
+// transaction, otherwise the operation already happened and the stored record is returned.
+//
+// A concurrent transaction holding the same key blocks the insert until it finishes, so exactly
+// one of them claims the key. The same key with a different requestHash returns ErrIdempotencyKeyReused.
+func ClaimIdempotencyKey(ctx context.Context, tx IdempotencyTx, scope, key, requestHash string) (IdempotencyRecord, bool, error) {
+	record := IdempotencyRecord{Scope: scope, Key: key, RequestHash: requestHash}
+
+	err := tx.QueryRow(ctx,
+		`INSERT INTO idempotency_keys (scope, key, request_hash) VALUES ($1, $2, $3)
+		ON CONFLICT (scope, key) DO NOTHING
+		RETURNING created_at`,
+		scope, key, requestHash,
+	).Scan(&record.CreatedAt)
+	if err == nil {
+		return record, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return IdempotencyRecord{}, false, fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+
+	err = tx.QueryRow(ctx,
+		`SELECT request_hash, response, created_at FROM idempotency_keys WHERE scope = $1 AND key = $2`,
+		scope, key,
+	).Scan(&record.RequestHash, &record.Response, &record.CreatedAt)
+	if err != nil {
+		return IdempotencyRecord{}, false, fmt.Errorf("failed to load idempotency key: %w", err)
+	}
+
+	if record.RequestHash != requestHash {
+		return IdempotencyRecord{}, false, fmt.Errorf("%w: %s", ErrIdempotencyKeyReused, key)
+	}
+	return record, false, nil
+}
+
+// CompleteIdempotencyKey stores the response of a claimed key, so that retries receive the original result
+func CompleteIdempotencyKey(ctx context.Context, tx IdempotencyTx, scope, key string, response []byte) error {
+	tag, err := tx.Exec(ctx,
+		`UPDATE idempotency_keys SET response = $3 WHERE scope = $1 AND key = $2`,
+		scope, key, response,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("failed to complete idempotency key: %s was not claimed", key)
+	}
+	return nil
+}
+
+// PurgeIdempotencyKeys deletes the keys older than retention and returns how many were removed
+func PurgeIdempotencyKeys(ctx context.Context, tx IdempotencyTx, retention time.Duration) (int64, error) {
+	tag, err := tx.Exec(ctx,
+		`DELETE FROM idempotency_keys WHERE created_at < $1`,
+		time.Now().Add(-retention),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge idempotency keys: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
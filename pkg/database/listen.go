@@ -0,0 +1,173 @@
+package databaseutil
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// Notification is a payload sent with NOTIFY or pg_notify
+type Notification struct {
+	Channel string
+	Payload string
+	// PID is the backend process of the sender, e.g. to skip notifications sent by the service itself
+	PID uint32
+}
+
+// ListenerOption configures NewListener
+type ListenerOption func(*Listener)
+
+// WithListenerBackoff sets the delay before the first reconnection, doubled for every further
+// failed attempt up to maxDelay
+func WithListenerBackoff(baseDelay, maxDelay time.Duration) ListenerOption {
+	return func(l *Listener) {
+		l.baseDelay = baseDelay
+		l.maxDelay = maxDelay
+	}
+}
+
+// WithListenerBuffer sets the capacity of the notifications channel, 64 by default
+func WithListenerBuffer(size int) ListenerOption {
+	return func(l *Listener) {
+		l.bufferSize = size
+	}
+}
+
+// WithListenerOnConnect sets a function called every time the channels are listened to again.
+// Notifications sent while the listener was disconnected are lost, so a cache invalidated by
+// notifications should be flushed here.
+func WithListenerOnConnect(fn func(ctx context.Context)) ListenerOption {
+	return func(l *Listener) {
+		l.onConnect = fn
+	}
+}
+
+// Listener subscribes to Postgres channels with LISTEN and delivers their notifications on a Go
+// channel. It holds a connection taken out of the pool for as long as it runs, and reconnects with
+// exponential backoff and jitter when that connection is lost.
+type Listener struct {
+	pool     *pgxpool.Pool
+	channels []string
+	logger   *zap.Logger
+
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	bufferSize int
+	onConnect  func(ctx context.Context)
+
+	notifications chan Notification
+}
+
+// NewListener creates a listener of channels on a connection of pool, call Run to start it
+func NewListener(pool *pgxpool.Pool, logger *zap.Logger, channels []string, opts ...ListenerOption) *Listener {
+	l := &Listener{
+		pool:       pool,
+		channels:   channels,
+		logger:     logger,
+		baseDelay:  100 * time.Millisecond,
+		maxDelay:   30 * time.Second,
+		bufferSize: 64,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	l.notifications = make(chan Notification, l.bufferSize)
+	return l
+}
+
+// Notifications returns the channel the notifications are delivered on, it is closed when Run returns.
+// A slow reader blocks the listener once the buffer is full, Postgres then queues the notifications.
+func (l *Listener) Notifications() <-chan Notification {
+	return l.notifications
+}
+
+// Run listens until ctx is canceled, reconnecting whenever the connection is lost, and returns the
+// error of ctx. It must be called once.
+//
+//	listener := databaseutil.NewListener(pool, logger, []string{"users_changed"},
+//		databaseutil.WithListenerOnConnect(func(ctx context.Context) { cache.Flush() }))
+//	go listener.Run(ctx)
+//
+//	for notification := range listener.Notifications() {
+//		cache.Delete(notification.Payload)
+//	}
+func (l *Listener) Run(ctx context.Context) error {
+	defer close(l.notifications)
+
+	delay := l.baseDelay
+	for {
+		listened, err := l.listen(ctx)
+		if ctx.Err() != nil {
+			l.logger.Info("Stopped listening to Postgres channels", zap.Strings("channels", l.channels))
+			return ctx.Err()
+		}
+		if listened {
+			delay = l.baseDelay
+		}
+
+		wait := jitter(delay)
+		l.logger.Warn("Lost Postgres listen connection, reconnecting", zap.Error(err), zap.Strings("channels", l.channels), zap.Duration("delay", wait))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			l.logger.Info("Stopped listening to Postgres channels", zap.Strings("channels", l.channels))
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, l.maxDelay)
+	}
+}
+
+// listen delivers notifications until the connection fails, listened reports whether every channel
+// was listened to before the failure
+func (l *Listener) listen(ctx context.Context) (listened bool, err error) {
+	pooled, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	// The connection keeps its subscriptions, it is not given back to the pool
+	conn := pooled.Hijack()
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		_ = conn.Close(closeCtx)
+	}()
+
+	for _, channel := range l.channels {
+		_, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize())
+		if err != nil {
+			return false, err
+		}
+	}
+	l.logger.Info("Listening to Postgres channels", zap.Strings("channels", l.channels))
+
+	if l.onConnect != nil {
+		l.onConnect(ctx)
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, err
+		}
+		if notification == nil {
+			return true, errors.New("listen connection returned no notification")
+		}
+
+		l.logger.Debug("Received Postgres notification", zap.String("channel", notification.Channel), zap.Int("payload_size", len(notification.Payload)))
+
+		select {
+		case l.notifications <- Notification{Channel: notification.Channel, Payload: notification.Payload, PID: notification.PID}:
+		case <-ctx.Done():
+			return true, ctx.Err()
+		}
+	}
+}
@@ -76,3 +76,24 @@ func MigrationDown(sourceURL, databaseURL string, logger *zap.Logger) error {
 	logger.Info("Database migration down completed successfully")
 	return nil
 }
+
+// MigrationVersion returns the current migration version, zero means no migration has been applied
+func MigrationVersion(sourceURL, databaseURL string) (uint, bool, error) {
+	m, err := migrate.New(sourceURL, databaseURL)
+	if err != nil {
+		return 0, false, err
+	}
+	defer func() {
+		_, _ = m.Close()
+	}()
+
+	version, dirty, err := m.Version()
+	if err != nil {
+		if errors.Is(err, migrate.ErrNilVersion) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	return version, dirty, nil
+}
@@ -0,0 +1,145 @@
+package databaseutil
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/multitracer"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// PoolConfig configures NewPool, the zero values fall back to the defaults of DefaultPoolConfig
+type PoolConfig struct {
+	URL string
+
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+
+	// StatementTimeout sets the statement_timeout of every connection when positive, so the server
+	// aborts statements running longer, which WrapDBError reports as ErrStatementTimeout
+	StatementTimeout time.Duration
+
+	// SlowQueryThreshold installs a SlowQueryTracer when positive
+	SlowQueryThreshold time.Duration
+
+	// Tracers are installed after the CostTracer and the SlowQueryTracer, e.g. an IndexAdvisor
+	Tracers []pgx.QueryTracer
+
+	Logger *zap.Logger
+}
+
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxConns:          10,
+		MinConns:          0,
+		MaxConnLifetime:   time.Hour,
+		MaxConnIdleTime:   5 * time.Minute,
+		HealthCheckPeriod: time.Minute,
+	}
+}
+
+// NewPool builds a pgxpool.Pool from cfg, with the CostTracer and RegisterUUIDType installed and
+// the pool statistics exported as "db.pool.*" metrics through the global meter provider. The pool
+// is pinged before it is returned, so a wrong URL or an unreachable database fails at startup.
+func NewPool(ctx context.Context, cfg PoolConfig) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database url: %w", err)
+	}
+
+	defaults := DefaultPoolConfig()
+	poolConfig.MaxConns = valueOr(cfg.MaxConns, defaults.MaxConns)
+	poolConfig.MinConns = valueOr(cfg.MinConns, defaults.MinConns)
+	poolConfig.MaxConnLifetime = valueOr(cfg.MaxConnLifetime, defaults.MaxConnLifetime)
+	poolConfig.MaxConnIdleTime = valueOr(cfg.MaxConnIdleTime, defaults.MaxConnIdleTime)
+	poolConfig.HealthCheckPeriod = valueOr(cfg.HealthCheckPeriod, defaults.HealthCheckPeriod)
+
+	if cfg.StatementTimeout > 0 {
+		poolConfig.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10)
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	tracers := []pgx.QueryTracer{CostTracer{}}
+	if cfg.SlowQueryThreshold > 0 {
+		tracers = append(tracers, NewSlowQueryTracer(logger, cfg.SlowQueryThreshold, true))
+	}
+	tracers = append(tracers, cfg.Tracers...)
+	poolConfig.ConnConfig.Tracer = multitracer.New(tracers...)
+	poolConfig.AfterConnect = RegisterUUIDType
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create database pool: %w", err)
+	}
+
+	err = pool.Ping(ctx)
+	if err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	err = registerPoolMetrics(pool)
+	if err != nil {
+		logger.Warn("Failed to register database pool metrics", zap.Error(err))
+	}
+
+	return pool, nil
+}
+
+func valueOr[T comparable](value, fallback T) T {
+	var zero T
+	if value == zero {
+		return fallback
+	}
+	return value
+}
+
+// registerPoolMetrics reports the statistics of pool on every collection
+func registerPoolMetrics(pool *pgxpool.Pool) error {
+	meter := otel.Meter("internal/database")
+
+	totalConns, err := meter.Int64ObservableGauge("db.pool.connections", metric.WithDescription("Number of connections in the pool"))
+	if err != nil {
+		return err
+	}
+	idleConns, err := meter.Int64ObservableGauge("db.pool.idle_connections", metric.WithDescription("Number of idle connections in the pool"))
+	if err != nil {
+		return err
+	}
+	acquires, err := meter.Int64ObservableCounter("db.pool.acquires", metric.WithDescription("Number of connections acquired from the pool"))
+	if err != nil {
+		return err
+	}
+	waitedAcquires, err := meter.Int64ObservableCounter("db.pool.waited_acquires", metric.WithDescription("Number of acquires that waited for a connection"))
+	if err != nil {
+		return err
+	}
+	acquireDuration, err := meter.Float64ObservableCounter("db.pool.acquire_duration", metric.WithDescription("Total time spent acquiring connections"), metric.WithUnit("s"))
+	if err != nil {
+		return err
+	}
+
+	_, err = meter.RegisterCallback(func(_ context.Context, observer metric.Observer) error {
+		stat := pool.Stat()
+		observer.ObserveInt64(totalConns, int64(stat.TotalConns()))
+		observer.ObserveInt64(idleConns, int64(stat.IdleConns()))
+		observer.ObserveInt64(acquires, stat.AcquireCount())
+		observer.ObserveInt64(waitedAcquires, stat.EmptyAcquireCount())
+		observer.ObserveFloat64(acquireDuration, stat.AcquireDuration().Seconds())
+		return nil
+	}, totalConns, idleConns, acquires, waitedAcquires, acquireDuration)
+	return err
+}
@@ -0,0 +1,111 @@
+package databaseutil
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// RetryPolicy configures Retry
+type RetryPolicy struct {
+	// MaxAttempts caps the number of times fn is called, 1 disables retries
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry, doubled for every further retry up to MaxDelay.
+	// The actual delay is picked at random between half and all of it, so that clients failing
+	// together do not retry together. MaxDelay defaults to the one of DefaultRetryPolicy, or to
+	// BaseDelay when it is longer.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// Retryable classifies the errors worth retrying, IsTransient when nil
+	Retryable func(error) bool
+
+	// Logger receives an entry for every failed attempt, nil disables logging
+	Logger *zap.Logger
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   20 * time.Millisecond,
+		MaxDelay:    time.Second,
+	}
+}
+
+// IsTransient reports whether err is likely to go away on its own: deadlocks, serialization
+// failures, query timeouts and lost connections, from pgx or wrapped by WrapDBError
+func IsTransient(err error) bool {
+	switch {
+	case errors.Is(err, ErrDeadlockDetected), errors.Is(err, ErrSerializationFailure), errors.Is(err, ErrQueryTimeout):
+		return true
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.EPIPE):
+		return true
+	case pgconn.SafeToRetry(err):
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == PGErrDeadlockDetected || pgErr.Code == PGErrSerializationFailure
+	}
+	return false
+}
+
+// Retry calls fn until it succeeds, returns an error that policy does not classify as retryable,
+// or policy.MaxAttempts is reached, waiting with exponential backoff and jitter in between.
+// It returns the last error of fn, or the error of ctx when it is canceled while waiting.
+func Retry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) error) error {
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = IsTransient
+	}
+	logger := policy.Logger
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+
+	maxDelay := policy.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = max(DefaultRetryPolicy().MaxDelay, policy.BaseDelay)
+	}
+
+	delay := min(policy.BaseDelay, maxDelay)
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if !retryable(err) || attempt >= policy.MaxAttempts {
+			return err
+		}
+
+		wait := jitter(delay)
+		logger.Warn("Retrying after transient error", zap.Error(err), zap.Int("attempt", attempt), zap.Int("max_attempts", policy.MaxAttempts), zap.Duration("delay", wait))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+
+		delay = min(delay*2, maxDelay)
+	}
+}
+
+// jitter returns a random duration between half of delay and delay
+func jitter(delay time.Duration) time.Duration {
+	if delay <= 1 {
+		return delay
+	}
+	half := delay / 2
+	return half + rand.N(delay-half)
+}
@@ -0,0 +1,182 @@
+package databaseutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "Should retry a deadlock", err: &pgconn.PgError{Code: PGErrDeadlockDetected}, want: true},
+		{name: "Should retry a serialization failure", err: &pgconn.PgError{Code: PGErrSerializationFailure}, want: true},
+		{name: "Should retry a wrapped deadlock", err: fmt.Errorf("create order: %w", ErrDeadlockDetected), want: true},
+		{name: "Should retry a query timeout", err: ErrQueryTimeout, want: true},
+		{name: "Should retry a reset connection", err: fmt.Errorf("read: %w", syscall.ECONNRESET), want: true},
+		{name: "Should retry a refused connection", err: syscall.ECONNREFUSED, want: true},
+		{name: "Should not retry a unique violation", err: &pgconn.PgError{Code: "23505"}, want: false},
+		{name: "Should not retry a wrapped unique violation", err: ErrUniqueViolation, want: false},
+		{name: "Should not retry a canceled context", err: context.Canceled, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsTransient(tt.err); got != tt.want {
+				t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	errTransient := &pgconn.PgError{Code: PGErrDeadlockDetected}
+	errPermanent := &pgconn.PgError{Code: "23505"}
+
+	tests := []struct {
+		name         string
+		maxAttempts  int
+		errs         []error
+		wantErr      error
+		wantAttempts int
+	}{
+		{
+			name:         "Should return after the first success",
+			maxAttempts:  3,
+			errs:         []error{nil},
+			wantAttempts: 1,
+		},
+		{
+			name:         "Should retry transient errors until success",
+			maxAttempts:  3,
+			errs:         []error{errTransient, errTransient, nil},
+			wantAttempts: 3,
+		},
+		{
+			name:         "Should not retry an error that is not retryable",
+			maxAttempts:  3,
+			errs:         []error{errPermanent, nil},
+			wantErr:      errPermanent,
+			wantAttempts: 1,
+		},
+		{
+			name:         "Should stop retrying at the attempt cap",
+			maxAttempts:  3,
+			errs:         []error{errTransient, errTransient, errTransient, nil},
+			wantErr:      errTransient,
+			wantAttempts: 3,
+		},
+		{
+			name:         "Should not retry with a single attempt",
+			maxAttempts:  1,
+			errs:         []error{errTransient, nil},
+			wantErr:      errTransient,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := RetryPolicy{MaxAttempts: tt.maxAttempts, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+
+			attempts := 0
+			err := Retry(context.Background(), policy, func(ctx context.Context) error {
+				err := tt.errs[attempts]
+				attempts++
+				return err
+			})
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Retry() error = %v, want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("Retry() called fn %d times, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRetry_Delays(t *testing.T) {
+	tests := []struct {
+		name      string
+		baseDelay time.Duration
+		maxDelay  time.Duration
+		// wantMax is the backoff of each retry, the delay waited is between half of it and all of it
+		wantMax []time.Duration
+	}{
+		{
+			name:      "Should double the delay up to MaxDelay",
+			baseDelay: time.Millisecond,
+			maxDelay:  4 * time.Millisecond,
+			wantMax:   []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond},
+		},
+		{
+			name:      "Should cap BaseDelay at MaxDelay",
+			baseDelay: 4 * time.Millisecond,
+			maxDelay:  2 * time.Millisecond,
+			wantMax:   []time.Duration{2 * time.Millisecond, 2 * time.Millisecond},
+		},
+		{
+			name:      "Should default MaxDelay when zero",
+			baseDelay: time.Millisecond,
+			wantMax:   []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.WarnLevel)
+			policy := RetryPolicy{
+				MaxAttempts: len(tt.wantMax) + 1,
+				BaseDelay:   tt.baseDelay,
+				MaxDelay:    tt.maxDelay,
+				Logger:      zap.New(core),
+			}
+
+			_ = Retry(context.Background(), policy, func(ctx context.Context) error {
+				return ErrDeadlockDetected
+			})
+
+			entries := logs.All()
+			if len(entries) != len(tt.wantMax) {
+				t.Fatalf("Retry() logged %d retries, want %d", len(entries), len(tt.wantMax))
+			}
+			for i, entry := range entries {
+				delay, _ := entry.ContextMap()["delay"].(time.Duration)
+				if delay < tt.wantMax[i]/2 || delay > tt.wantMax[i] {
+					t.Errorf("retry %d: delay = %v, want between %v and %v", i+1, delay, tt.wantMax[i]/2, tt.wantMax[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRetry_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+
+	attempts := 0
+	err := Retry(ctx, policy, func(ctx context.Context) error {
+		attempts++
+		cancel()
+		return ErrDeadlockDetected
+	})
+
+	if !errors.Is(err, context.Canceled) || !errors.Is(err, ErrDeadlockDetected) {
+		t.Errorf("Retry() error = %v, want both the last error and context.Canceled", err)
+	}
+	if attempts != 1 {
+		t.Errorf("Retry() called fn %d times, want 1", attempts)
+	}
+}
@@ -0,0 +1,124 @@
+package databaseutil
+
+import (
+	"context"
+	"math/rand/v2"
+	"reflect"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// DefaultShadowTimeout bounds a shadow read, which outlives the request it was started for
+const DefaultShadowTimeout = 5 * time.Second
+
+// Shadow read outcomes, recorded as the "result" attribute of the comparison counter
+const (
+	ShadowMatch        = "match"
+	ShadowMismatch     = "mismatch"
+	ShadowError        = "shadow_error"
+	ShadowPrimaryError = "primary_error"
+)
+
+// ShadowReader de-risks a change of a read path, such as a rewritten query or a table move, by
+// running the new path next to the old one on a sample of the calls and reporting when they
+// disagree. The caller always gets the primary result, the shadow result is only compared.
+type ShadowReader[T any] struct {
+	Name string
+
+	// SampleRate is the fraction of calls, between 0 and 1, that also run the shadow read
+	SampleRate float64
+
+	// Timeout bounds the shadow read, DefaultShadowTimeout is used when zero
+	Timeout time.Duration
+
+	// Equal compares the primary and shadow results, reflect.DeepEqual is used when nil
+	Equal func(primary, shadow T) bool
+
+	logger      *zap.Logger
+	comparisons metric.Int64Counter
+}
+
+func NewShadowReader[T any](name string, sampleRate float64, logger *zap.Logger) *ShadowReader[T] {
+	comparisons, err := otel.Meter("internal/database").Int64Counter(
+		"database.shadow_read.comparisons",
+		metric.WithDescription("Number of shadow reads compared to their primary read, by result"),
+	)
+	if err != nil {
+		logger.Warn("Failed to create shadow read counter", zap.Error(err))
+	}
+
+	return &ShadowReader[T]{
+		Name:        name,
+		SampleRate:  sampleRate,
+		logger:      logger,
+		comparisons: comparisons,
+	}
+}
+
+// Read returns the result of primary. On sampled calls shadow runs concurrently with a context
+// that is not canceled with ctx, and the results are compared in the background once both are
+// done, so the shadow path never delays nor fails the caller.
+func (s *ShadowReader[T]) Read(ctx context.Context, primary, shadow func(ctx context.Context) (T, error)) (T, error) {
+	if s.SampleRate <= 0 || rand.Float64() >= s.SampleRate {
+		return primary(ctx)
+	}
+
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = DefaultShadowTimeout
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	shadowResult := make(chan result, 1)
+	go func() {
+		shadowCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		defer cancel()
+
+		value, err := shadow(shadowCtx)
+		shadowResult <- result{value: value, err: err}
+	}()
+
+	value, err := primary(ctx)
+
+	go func() {
+		shadowValue := <-shadowResult
+		s.compare(context.WithoutCancel(ctx), value, err, shadowValue.value, shadowValue.err)
+	}()
+
+	return value, err
+}
+
+func (s *ShadowReader[T]) compare(ctx context.Context, primary T, primaryErr error, shadow T, shadowErr error) {
+	var outcome string
+	switch {
+	case primaryErr != nil:
+		// Nothing meaningful to compare against, the caller already got the error
+		outcome = ShadowPrimaryError
+	case shadowErr != nil:
+		outcome = ShadowError
+		s.logger.Warn("Shadow read failed", zap.String("shadow", s.Name), zap.Error(shadowErr))
+	case !s.equal(primary, shadow):
+		outcome = ShadowMismatch
+		s.logger.Warn("Shadow read mismatch", zap.String("shadow", s.Name), zap.Any("primary", primary), zap.Any("shadow_result", shadow))
+	default:
+		outcome = ShadowMatch
+	}
+
+	if s.comparisons != nil {
+		s.comparisons.Add(ctx, 1, metric.WithAttributes(attribute.String("name", s.Name), attribute.String("result", outcome)))
+	}
+}
+
+func (s *ShadowReader[T]) equal(primary, shadow T) bool {
+	if s.Equal != nil {
+		return s.Equal(primary, shadow)
+	}
+	return reflect.DeepEqual(primary, shadow)
+}
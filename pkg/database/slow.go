@@ -0,0 +1,71 @@
+package databaseutil
+
+import (
+	"context"
+	"time"
+
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type slowQueryContextKey struct{}
+
+type slowQueryStart struct {
+	sql   string
+	start time.Time
+}
+
+// SlowQueryTracer is a pgx.QueryTracer logging the queries that take longer than Threshold at Warn
+// level, with their duration, fingerprint and a "db.slow" field to alert on. With SpanEvents set,
+// a "db.slow_query" event is also added to the span of the request.
+type SlowQueryTracer struct {
+	Threshold  time.Duration
+	SpanEvents bool
+
+	logger *zap.Logger
+}
+
+func NewSlowQueryTracer(logger *zap.Logger, threshold time.Duration, spanEvents bool) *SlowQueryTracer {
+	return &SlowQueryTracer{
+		Threshold:  threshold,
+		SpanEvents: spanEvents,
+		logger:     logger,
+	}
+}
+
+func (t *SlowQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, slowQueryContextKey{}, slowQueryStart{sql: data.SQL, start: time.Now()})
+}
+
+func (t *SlowQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	started, ok := ctx.Value(slowQueryContextKey{}).(slowQueryStart)
+	if !ok {
+		return
+	}
+
+	duration := time.Since(started.start)
+	if duration < t.Threshold {
+		return
+	}
+
+	fingerprint := Fingerprint(started.sql)
+	logutil.WithContext(ctx, t.logger).Warn("Slow query",
+		zap.Bool("db.slow", true),
+		zap.Duration("duration", duration),
+		zap.Duration("threshold", t.Threshold),
+		zap.String("fingerprint", fingerprint),
+		zap.Int64("rows", data.CommandTag.RowsAffected()),
+		zap.Error(data.Err),
+	)
+
+	if t.SpanEvents {
+		trace.SpanFromContext(ctx).AddEvent("db.slow_query", trace.WithAttributes(
+			attribute.Bool("db.slow", true),
+			attribute.Int64("db.duration_ms", duration.Milliseconds()),
+			attribute.String("db.fingerprint", fingerprint),
+		))
+	}
+}
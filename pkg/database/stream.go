@@ -0,0 +1,92 @@
+package databaseutil
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// RowQuerier is implemented by *pgxpool.Pool, *pgx.Conn and pgx.Tx
+type RowQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// StreamOptions configures ForEachRow
+type StreamOptions struct {
+	// BatchSize is the number of rows after which OnBatch is called, zero disables batching
+	BatchSize int
+
+	// OnBatch is called after every BatchSize rows and once more for the remaining rows,
+	// it is the place to commit the writes buffered by the row callback
+	OnBatch func(ctx context.Context, processed int64) error
+
+	// Logger receives a progress entry after every batch, nil disables progress logging
+	Logger *zap.Logger
+}
+
+// ForEachRow streams the rows of the query into fn one at a time without loading the whole result.
+// The next row is only read after fn returns, so a slow fn applies backpressure to the query.
+// It returns the number of rows processed, stopping at the first error or when ctx is canceled.
+func ForEachRow(ctx context.Context, db RowQuerier, sql string, args []any, fn func(pgx.Rows) error, opts StreamOptions) (int64, error) {
+	rows, err := db.Query(ctx, sql, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	var processed int64
+	var pending int
+
+	flush := func() error {
+		if pending == 0 {
+			return nil
+		}
+		if opts.OnBatch != nil {
+			err := opts.OnBatch(ctx, processed)
+			if err != nil {
+				return fmt.Errorf("failed to process batch ending at row %d: %w", processed, err)
+			}
+		}
+		if opts.Logger != nil {
+			opts.Logger.Info("Streamed rows", zap.Int64("processed", processed), zap.Int("batch", pending))
+		}
+		pending = 0
+		return nil
+	}
+
+	for rows.Next() {
+		err := ctx.Err()
+		if err != nil {
+			return processed, err
+		}
+
+		err = fn(rows)
+		if err != nil {
+			return processed, fmt.Errorf("failed to process row %d: %w", processed+1, err)
+		}
+
+		processed++
+		pending++
+
+		if opts.BatchSize > 0 && pending >= opts.BatchSize {
+			err = flush()
+			if err != nil {
+				return processed, err
+			}
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return processed, err
+	}
+
+	err = flush()
+	if err != nil {
+		return processed, err
+	}
+
+	return processed, nil
+}
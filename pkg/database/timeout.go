@@ -0,0 +1,21 @@
+package databaseutil
+
+import (
+	"context"
+	"time"
+)
+
+// WithQueryTimeout returns a context for queries that should take at most d, a non-positive d sets
+// no timeout. An earlier deadline of ctx is kept. When the deadline passes, pgx cancels the running
+// query and WrapDBError returns ErrQueryTimeout, unlike the statement_timeout of the server, see
+// PoolConfig.StatementTimeout, which is returned as ErrStatementTimeout.
+//
+//	ctx, cancel := databaseutil.WithQueryTimeout(ctx, 2*time.Second)
+//	defer cancel()
+//	report, err := s.queries.BuildReport(ctx, teamID)
+func WithQueryTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
@@ -0,0 +1,37 @@
+package databaseutil
+
+import (
+	"context"
+
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"go.uber.org/zap"
+)
+
+// WrapDBErrorWithTracker runs fn as an operation of tracker and wraps its error with WrapDBError,
+// so the operation is timed, counted on the request and ended with the wrapped error:
+//
+//	tracker := logutil.NewDBTracker(logger, logutil.WithSpan("internal/database"))
+//
+//	var user User
+//	err := databaseutil.WrapDBErrorWithTracker(ctx, tracker, logger, "get user by id", func(ctx context.Context) error {
+//		var err error
+//		user, err = s.queries.GetUserByID(ctx, id)
+//		return err
+//	})
+func WrapDBErrorWithTracker(ctx context.Context, tracker logutil.Tracker, logger *zap.Logger, operation string, fn func(ctx context.Context) error) error {
+	ctx, done := tracker.Track(ctx, operation)
+
+	err := WrapDBError(fn(ctx), logger.WithOptions(zap.AddCallerSkip(1)), operation)
+	done(nil, err)
+	return err
+}
+
+// WrapMSSQLErrorWithTracker is the SQL Server counterpart of WrapDBErrorWithTracker, wrapping the
+// error of fn with WrapMSSQLError, so services using both databases track them the same way
+func WrapMSSQLErrorWithTracker(ctx context.Context, tracker logutil.Tracker, logger *zap.Logger, operation string, fn func(ctx context.Context) error) error {
+	ctx, done := tracker.Track(ctx, operation)
+
+	err := WrapMSSQLError(fn(ctx), logger.WithOptions(zap.AddCallerSkip(1)), operation)
+	done(nil, err)
+	return err
+}
@@ -0,0 +1,45 @@
+package databaseutil
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWrapErrorWithTracker_Caller(t *testing.T) {
+	tests := []struct {
+		name string
+		wrap func(ctx context.Context, tracker logutil.Tracker, logger *zap.Logger, operation string, fn func(ctx context.Context) error) error
+	}{
+		{name: "Should report the caller of WrapDBErrorWithTracker", wrap: WrapDBErrorWithTracker},
+		{name: "Should report the caller of WrapMSSQLErrorWithTracker", wrap: WrapMSSQLErrorWithTracker},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			logger := zap.New(core, zap.AddCaller())
+			tracker := logutil.NewDBTracker(zap.NewNop())
+
+			_ = tt.wrap(context.Background(), tracker, logger, "get user by id", func(ctx context.Context) error {
+				return errors.New("connection lost")
+			})
+
+			entries := logs.All()
+			if len(entries) == 0 {
+				t.Fatal("no entry was logged")
+			}
+			for _, entry := range entries {
+				if file := filepath.Base(entry.Caller.File); file != "tracked_test.go" {
+					t.Errorf("%q caller = %s, want tracked_test.go", entry.Message, entry.Caller.TrimmedPath())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package databaseutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// TxBeginner is implemented by *pgxpool.Pool and *pgx.Conn
+type TxBeginner interface {
+	BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error)
+}
+
+type txConfig struct {
+	txOptions pgx.TxOptions
+	policy    RetryPolicy
+}
+
+// TxOption configures WithTx
+type TxOption func(*txConfig)
+
+// WithTxOptions sets the isolation level and access mode of the transaction
+func WithTxOptions(txOptions pgx.TxOptions) TxOption {
+	return func(c *txConfig) {
+		c.txOptions = txOptions
+	}
+}
+
+// WithTxMaxAttempts caps the number of times the transaction is run, 1 disables retries
+func WithTxMaxAttempts(maxAttempts int) TxOption {
+	return func(c *txConfig) {
+		c.policy.MaxAttempts = maxAttempts
+	}
+}
+
+// WithTxBackoff sets the delay before the first retry, doubled for every further retry up to maxDelay
+func WithTxBackoff(baseDelay, maxDelay time.Duration) TxOption {
+	return func(c *txConfig) {
+		c.policy.BaseDelay = baseDelay
+		c.policy.MaxDelay = maxDelay
+	}
+}
+
+// WithTxLogger sets the logger used to report retries and wrap database errors
+func WithTxLogger(logger *zap.Logger) TxOption {
+	return func(c *txConfig) {
+		c.policy.Logger = logger
+	}
+}
+
+// WithTx runs fn in a transaction, committing it when fn returns nil and rolling it back otherwise.
+// Deadlocks and serialization failures, from fn or from the commit, run the whole transaction again
+// with Retry, 3 attempts by default, so fn must not have effects outside of tx.
+//
+// Database errors from beginning or committing the transaction, and pgconn errors returned by fn,
+// are wrapped with WrapDBError. Other errors of fn, such as domain errors, are returned as is.
+//
+// Pass Nested(tx) as db to run fn inside a transaction that is already open, see Nested.
+func WithTx(ctx context.Context, db TxBeginner, fn func(tx pgx.Tx) error, opts ...TxOption) error {
+	config := txConfig{policy: DefaultRetryPolicy()}
+	config.policy.Logger = zap.NewNop()
+	config.policy.Retryable = isRetryableTxError
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	// A deadlock or serialization failure aborts the outer transaction too, only the outermost
+	// WithTx can run it again
+	if _, nested := db.(nestedTx); nested {
+		config.policy.MaxAttempts = 1
+	}
+
+	var fromFn bool
+	err := Retry(ctx, config.policy, func(ctx context.Context) error {
+		var err error
+		fromFn, err = runTx(ctx, db, config.txOptions, fn)
+		return err
+	})
+	if err != nil {
+		return wrapTxError(err, fromFn, config.policy.Logger)
+	}
+	return nil
+}
+
+// Nested lets WithTx run inside tx, so services composing each other can all use WithTx:
+//
+//	err := databaseutil.WithTx(ctx, pool, func(tx pgx.Tx) error {
+//		err := s.orders.Create(ctx, tx, order)
+//		if err != nil {
+//			return err
+//		}
+//		// A failed reward is rolled back alone, the order is still committed
+//		_ = databaseutil.WithTx(ctx, databaseutil.Nested(tx), func(tx pgx.Tx) error {
+//			return s.rewards.Grant(ctx, tx, order.UserID)
+//		})
+//		return nil
+//	})
+//
+// The inner transaction is a savepoint of tx, released when fn succeeds and rolled back to when it
+// fails, which leaves tx usable. It is never retried, deadlocks and serialization failures are
+// returned so the outermost WithTx runs everything again. The options of the inner WithTx, such as
+// the isolation level, are ignored.
+func Nested(tx pgx.Tx) TxBeginner {
+	return nestedTx{tx: tx}
+}
+
+// nestedTx begins savepoints, pgx turns Begin on a transaction into SAVEPOINT and the Commit and
+// Rollback of the result into RELEASE SAVEPOINT and ROLLBACK TO SAVEPOINT
+type nestedTx struct {
+	tx pgx.Tx
+}
+
+func (n nestedTx) BeginTx(ctx context.Context, _ pgx.TxOptions) (pgx.Tx, error) {
+	return n.tx.Begin(ctx)
+}
+
+// runTx runs fn in a single transaction, fromFn reports whether err was returned by fn
+func runTx(ctx context.Context, db TxBeginner, txOptions pgx.TxOptions, fn func(tx pgx.Tx) error) (fromFn bool, err error) {
+	tx, err := db.BeginTx(ctx, txOptions)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	err = fn(tx)
+	if err != nil {
+		// The rollback error is not interesting, the transaction is discarded either way
+		_ = tx.Rollback(ctx)
+		return true, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return false, nil
+}
+
+// isRetryableTxError reports whether running the transaction again may succeed,
+// err may come straight from pgx or already be wrapped by WrapDBError in fn
+func isRetryableTxError(err error) bool {
+	if errors.Is(err, ErrDeadlockDetected) || errors.Is(err, ErrSerializationFailure) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == PGErrDeadlockDetected || pgErr.Code == PGErrSerializationFailure
+	}
+	return false
+}
+
+// wrapTxError wraps err with WrapDBError unless it is an error of fn that does not come from the database
+func wrapTxError(err error, fromFn bool, logger *zap.Logger) error {
+	var pgErr *pgconn.PgError
+	if fromFn && !errors.As(err, &pgErr) {
+		return err
+	}
+	return WrapDBError(err, logger, "run transaction")
+}
@@ -0,0 +1,220 @@
+package databaseutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// PGErrFeatureNotSupported is returned by servers and poolers that do not support COPY
+const PGErrFeatureNotSupported = "0A000"
+
+// errCopyUnsupported is returned by the copy chunk instead of the database error, so that it is
+// not wrapped by WithTx and BulkUpsert can fall back to the insert method
+var errCopyUnsupported = errors.New("copy is not supported")
+
+// maxBindParameters is the number of parameters a single Postgres statement accepts
+const maxBindParameters = 65535
+
+// UpsertMethod selects how BulkUpsert sends the rows
+type UpsertMethod int
+
+const (
+	// UpsertCopy stages the rows with COPY into a temporary table and merges them with a single
+	// INSERT ... SELECT ... ON CONFLICT, falling back to UpsertInsert when COPY is not supported
+	UpsertCopy UpsertMethod = iota
+
+	// UpsertInsert sends the rows as a multi-row INSERT ... ON CONFLICT, for databases and
+	// poolers without COPY support
+	UpsertInsert
+)
+
+// BulkUpsertOptions configures BulkUpsert
+type BulkUpsertOptions struct {
+	// Table is the target table, optionally qualified with its schema, e.g. "public.users"
+	Table string
+
+	// Columns are the columns of every row, in order
+	Columns []string
+
+	// ConflictColumns are the columns of the unique constraint the rows are merged on
+	ConflictColumns []string
+
+	// UpdateColumns are the columns overwritten on conflict, all non-conflict columns when nil.
+	// An empty non-nil slice keeps the existing rows as they are.
+	UpdateColumns []string
+
+	// ChunkSize is the number of rows merged per transaction, 1000 when zero
+	ChunkSize int
+
+	Method UpsertMethod
+
+	// Logger receives a progress entry after every chunk, nil disables progress logging
+	Logger *zap.Logger
+
+	// TxOptions configure the transaction of every chunk, see WithTx
+	TxOptions []TxOption
+}
+
+// BulkUpsert inserts rows into opts.Table, updating the rows that conflict on opts.ConflictColumns.
+// Every chunk is staged and merged in its own transaction run by WithTx, so a chunk hitting a
+// deadlock is retried, and the chunks committed before an error stay committed. It returns the
+// number of rows inserted or updated.
+//
+// The conflict columns must be unique within a chunk, Postgres rejects a statement updating
+// the same row twice.
+func BulkUpsert(ctx context.Context, db TxBeginner, rows [][]any, opts BulkUpsertOptions) (int64, error) {
+	if opts.Table == "" || len(opts.Columns) == 0 || len(opts.ConflictColumns) == 0 {
+		return 0, errors.New("bulk upsert needs a table, columns and conflict columns")
+	}
+
+	chunkSize := upsertChunkSize(opts.ChunkSize, len(opts.Columns))
+
+	updateColumns := opts.UpdateColumns
+	if updateColumns == nil {
+		for _, column := range opts.Columns {
+			if !slices.Contains(opts.ConflictColumns, column) {
+				updateColumns = append(updateColumns, column)
+			}
+		}
+	}
+	conflictClause := upsertConflictClause(opts.ConflictColumns, updateColumns)
+
+	method := opts.Method
+	var affected int64
+	for start := 0; start < len(rows); start += chunkSize {
+		chunk := rows[start:min(start+chunkSize, len(rows))]
+
+		var chunkAffected int64
+		err := WithTx(ctx, db, func(tx pgx.Tx) error {
+			var err error
+			if method == UpsertCopy {
+				chunkAffected, err = copyUpsertChunk(ctx, tx, opts, chunk, conflictClause)
+				if isCopyUnsupported(err) {
+					return fmt.Errorf("%w: %v", errCopyUnsupported, err)
+				}
+				return err
+			}
+			chunkAffected, err = insertUpsertChunk(ctx, tx, opts, chunk, conflictClause)
+			return err
+		}, opts.TxOptions...)
+
+		if errors.Is(err, errCopyUnsupported) {
+			if opts.Logger != nil {
+				opts.Logger.Warn("COPY is not supported, falling back to INSERT for bulk upsert", zap.String("table", opts.Table), zap.Error(err))
+			}
+			method = UpsertInsert
+			start -= chunkSize
+			continue
+		}
+		if err != nil {
+			return affected, fmt.Errorf("failed to upsert rows %d to %d into %s: %w", start, start+len(chunk), opts.Table, err)
+		}
+
+		affected += chunkAffected
+		if opts.Logger != nil {
+			opts.Logger.Info("Upserted chunk", zap.String("table", opts.Table), zap.Int("processed", start+len(chunk)), zap.Int("total", len(rows)), zap.Int64("affected", affected))
+		}
+	}
+
+	return affected, nil
+}
+
+func copyUpsertChunk(ctx context.Context, tx pgx.Tx, opts BulkUpsertOptions, chunk [][]any, conflictClause string) (int64, error) {
+	table := quoteTable(opts.Table)
+	staging := pgx.Identifier{"bulk_upsert_staging"}.Sanitize()
+	columns := quoteColumns(opts.Columns)
+
+	_, err := tx.Exec(ctx, fmt.Sprintf("CREATE TEMPORARY TABLE %s (LIKE %s INCLUDING DEFAULTS) ON COMMIT DROP", staging, table))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create staging table: %w", err)
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"bulk_upsert_staging"}, opts.Columns, pgx.CopyFromRows(chunk))
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy rows into staging table: %w", err)
+	}
+
+	tag, err := tx.Exec(ctx, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s %s", table, columns, columns, staging, conflictClause))
+	if err != nil {
+		return 0, fmt.Errorf("failed to merge staging table: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+func insertUpsertChunk(ctx context.Context, tx pgx.Tx, opts BulkUpsertOptions, chunk [][]any, conflictClause string) (int64, error) {
+	var query strings.Builder
+	fmt.Fprintf(&query, "INSERT INTO %s (%s) VALUES ", quoteTable(opts.Table), quoteColumns(opts.Columns))
+
+	args := make([]any, 0, len(chunk)*len(opts.Columns))
+	for i, row := range chunk {
+		if len(row) != len(opts.Columns) {
+			return 0, fmt.Errorf("row has %d values, expected %d", len(row), len(opts.Columns))
+		}
+
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		query.WriteByte('(')
+		for j, value := range row {
+			if j > 0 {
+				query.WriteString(", ")
+			}
+			args = append(args, value)
+			fmt.Fprintf(&query, "$%d", len(args))
+		}
+		query.WriteByte(')')
+	}
+	query.WriteString(" " + conflictClause)
+
+	tag, err := tx.Exec(ctx, query.String(), args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// upsertChunkSize returns the number of rows per chunk, capped so that the insert method, which
+// binds every value as a parameter, stays within maxBindParameters
+func upsertChunkSize(chunkSize, columns int) int {
+	if chunkSize <= 0 {
+		chunkSize = 1000
+	}
+	return min(chunkSize, maxBindParameters/columns)
+}
+
+func upsertConflictClause(conflictColumns, updateColumns []string) string {
+	if len(updateColumns) == 0 {
+		return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", quoteColumns(conflictColumns))
+	}
+
+	assignments := make([]string, 0, len(updateColumns))
+	for _, column := range updateColumns {
+		quoted := pgx.Identifier{column}.Sanitize()
+		assignments = append(assignments, quoted+" = EXCLUDED."+quoted)
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", quoteColumns(conflictColumns), strings.Join(assignments, ", "))
+}
+
+func quoteTable(table string) string {
+	return pgx.Identifier(strings.Split(table, ".")).Sanitize()
+}
+
+func quoteColumns(columns []string) string {
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, pgx.Identifier{column}.Sanitize())
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func isCopyUnsupported(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == PGErrFeatureNotSupported
+}
@@ -0,0 +1,74 @@
+package databaseutil
+
+import "testing"
+
+func TestUpsertConflictClause(t *testing.T) {
+	tests := []struct {
+		name            string
+		conflictColumns []string
+		updateColumns   []string
+		want            string
+	}{
+		{
+			name:            "Should update the given columns from the excluded row",
+			conflictColumns: []string{"id"},
+			updateColumns:   []string{"email", "name"},
+			want:            `ON CONFLICT ("id") DO UPDATE SET "email" = EXCLUDED."email", "name" = EXCLUDED."name"`,
+		},
+		{
+			name:            "Should list every conflict column",
+			conflictColumns: []string{"tenant_id", "email"},
+			updateColumns:   []string{"name"},
+			want:            `ON CONFLICT ("tenant_id", "email") DO UPDATE SET "name" = EXCLUDED."name"`,
+		},
+		{
+			name:            "Should do nothing without update columns",
+			conflictColumns: []string{"id"},
+			updateColumns:   []string{},
+			want:            `ON CONFLICT ("id") DO NOTHING`,
+		},
+		{
+			name:            "Should quote identifiers",
+			conflictColumns: []string{`we"ird`},
+			updateColumns:   []string{"Name"},
+			want:            `ON CONFLICT ("we""ird") DO UPDATE SET "Name" = EXCLUDED."Name"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := upsertConflictClause(tt.conflictColumns, tt.updateColumns)
+			if got != tt.want {
+				t.Errorf("upsertConflictClause() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpsertChunkSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		chunkSize int
+		columns   int
+		want      int
+	}{
+		{name: "Should default to 1000 rows", chunkSize: 0, columns: 3, want: 1000},
+		{name: "Should default a negative size", chunkSize: -1, columns: 3, want: 1000},
+		{name: "Should keep a size within the parameter limit", chunkSize: 500, columns: 10, want: 500},
+		{name: "Should cap the size at the parameter limit", chunkSize: 10000, columns: 10, want: 6553},
+		{name: "Should cap the default size for wide rows", chunkSize: 0, columns: 100, want: 655},
+		{name: "Should allow the parameter limit for one column", chunkSize: 100000, columns: 1, want: maxBindParameters},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := upsertChunkSize(tt.chunkSize, tt.columns)
+			if got != tt.want {
+				t.Errorf("upsertChunkSize(%d, %d) = %d, want %d", tt.chunkSize, tt.columns, got, tt.want)
+			}
+			if got*tt.columns > maxBindParameters {
+				t.Errorf("upsertChunkSize(%d, %d) = %d binds %d parameters, want at most %d", tt.chunkSize, tt.columns, got, got*tt.columns, maxBindParameters)
+			}
+		})
+	}
+}
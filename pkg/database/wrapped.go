@@ -0,0 +1,121 @@
+package databaseutil
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	errorPkg "github.com/NYCU-SDC/summer/pkg/handler"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+// WrappedDB runs every error of a DBTX through WrapDBError, pass it to the sqlc constructor instead
+// of the pool or transaction so no query method can forget to wrap:
+//
+//	queries := db.New(databaseutil.NewWrappedDB(pool, logger))
+//	user, err := queries.GetUserByID(ctx, id) // pgx.ErrNoRows is returned as handlerutil.ErrNotFound
+//
+// The operation logged with the error is the query name from the "-- name: GetUserByID :one"
+// comment sqlc puts on every query, or the first keyword of other statements. Errors already
+// wrapped by WrapDBError are returned as is, so repositories wrapping by hand keep working.
+type WrappedDB struct {
+	db     DBTX
+	logger *zap.Logger
+}
+
+func NewWrappedDB(db DBTX, logger *zap.Logger) *WrappedDB {
+	return &WrappedDB{db: db, logger: logger.WithOptions(zap.AddCallerSkip(1))}
+}
+
+func (d *WrappedDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	tag, err := d.db.Exec(ctx, sql, args...)
+	return tag, d.wrap(err, sql)
+}
+
+func (d *WrappedDB) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	rows, err := d.db.Query(ctx, sql, args...)
+	if err != nil {
+		return rows, d.wrap(err, sql)
+	}
+	return &wrappedRows{Rows: rows, db: d, sql: sql}, nil
+}
+
+func (d *WrappedDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return wrappedRow{row: d.db.QueryRow(ctx, sql, args...), db: d, sql: sql}
+}
+
+// BeginTx begins a transaction when the wrapped DBTX is a TxBeginner, so WrappedDB can be used with
+// WithTx. The transaction itself is not wrapped, pass it to NewWrappedDB for its queries.
+func (d *WrappedDB) BeginTx(ctx context.Context, txOptions pgx.TxOptions) (pgx.Tx, error) {
+	beginner, ok := d.db.(TxBeginner)
+	if !ok {
+		return nil, errors.New("wrapped database does not support transactions")
+	}
+
+	tx, err := beginner.BeginTx(ctx, txOptions)
+	return tx, d.wrap(err, "begin transaction")
+}
+
+func (d *WrappedDB) wrap(err error, sql string) error {
+	if err == nil || isWrappedDBError(err) {
+		return err
+	}
+	return WrapDBError(err, d.logger, queryName(sql))
+}
+
+// isWrappedDBError reports whether err already went through WrapDBError
+func isWrappedDBError(err error) bool {
+	var internalErr InternalServerError
+	return errors.As(err, &internalErr) || errors.Is(err, errorPkg.ErrNotFound) ||
+		errors.Is(err, ErrUniqueViolation) || errors.Is(err, ErrForeignKeyViolation) ||
+		errors.Is(err, ErrCheckViolation) || errors.Is(err, ErrNotNullViolation) ||
+		errors.Is(err, ErrDeadlockDetected) || errors.Is(err, ErrSerializationFailure) ||
+		errors.Is(err, ErrQueryTimeout) || errors.Is(err, ErrStatementTimeout) || errors.Is(err, ErrInvalidEnumValue)
+}
+
+// queryName returns the name sqlc gives a query in its leading comment, or the first keyword of sql
+func queryName(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if rest, ok := strings.CutPrefix(sql, "-- name:"); ok {
+		fields := strings.Fields(rest)
+		if len(fields) > 0 {
+			return fields[0]
+		}
+	}
+
+	keyword, _, _ := strings.Cut(sql, " ")
+	return "run " + strings.ToLower(keyword) + " statement"
+}
+
+// wrappedRows wraps the errors of the rows, pgx reports most query errors once the rows are read
+type wrappedRows struct {
+	pgx.Rows
+	db  *WrappedDB
+	sql string
+
+	err error
+}
+
+func (r *wrappedRows) Scan(dest ...any) error {
+	return r.db.wrap(r.Rows.Scan(dest...), r.sql)
+}
+
+func (r *wrappedRows) Err() error {
+	if r.err == nil {
+		r.err = r.db.wrap(r.Rows.Err(), r.sql)
+	}
+	return r.err
+}
+
+// wrappedRow wraps the error of a QueryRow once it is scanned, since pgx defers the error until then
+type wrappedRow struct {
+	row pgx.Row
+	db  *WrappedDB
+	sql string
+}
+
+func (r wrappedRow) Scan(dest ...any) error {
+	return r.db.wrap(r.row.Scan(dest...), r.sql)
+}
@@ -0,0 +1,414 @@
+package handlerutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+)
+
+var uuidType = reflect.TypeOf(uuid.UUID{})
+
+// BindPath fills the fields of dst tagged with `path:"name"` from r.PathValue(name).
+// uuid.UUID fields are parsed with ParseUUID, so malformed values wrap ErrInvalidUUID,
+// other conversion failures are returned as ValidationError.
+func BindPath(r *http.Request, dst any) error {
+	fieldErrs, err := bindTagged(dst, "path", true, pathLookup(r))
+	if err != nil {
+		return err
+	}
+	return firstFieldError(fieldErrs)
+}
+
+// BindForm fills the fields of dst tagged with `form:"name"` from an application/x-www-form-urlencoded
+// body and validates dst with v, slice fields collect every value of a repeated key. Conversion and
+// validation failures are returned as ValidationError, like BindJSON.
+func BindForm(ctx context.Context, v *validator.Validate, r *http.Request, dst any) error {
+	_, span := otel.Tracer("internal/handler").Start(ctx, "BindForm")
+	defer span.End()
+
+	err := r.ParseForm()
+	if err != nil {
+		span.RecordError(err)
+		return NewValidationErrorWithErrors("invalid form payload", []string{err.Error()})
+	}
+
+	fieldErrs, err := bindTagged(dst, "form", false, func(name string) ([]string, bool) {
+		values, ok := r.PostForm[name]
+		return values, ok && len(values) > 0
+	})
+	if err == nil {
+		err = firstFieldError(fieldErrs)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	err = v.Struct(dst)
+	if err != nil {
+		span.RecordError(err)
+		return TranslateValidationErrors(err)
+	}
+
+	return nil
+}
+
+// BindHeader fills the fields of dst tagged with `header:"X-Name"` from the request headers and
+// validates dst with v, slice fields collect every value of a repeated header. Failures are returned as
+// ValidationError with messages naming the header, e.g. "X-API-Key is required".
+func BindHeader(ctx context.Context, v *validator.Validate, r *http.Request, dst any) error {
+	_, span := otel.Tracer("internal/handler").Start(ctx, "BindHeader")
+	defer span.End()
+
+	fieldErrs, err := bindTagged(dst, "header", false, headerLookup(r))
+	if err == nil {
+		err = firstFieldError(fieldErrs)
+	}
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	err = v.Struct(dst)
+	if err != nil {
+		span.RecordError(err)
+		messages, violations, ok := translateTaggedValidationErrors(err, reflect.TypeOf(dst).Elem())
+		if !ok {
+			return err
+		}
+
+		validationErr := NewValidationErrorWithErrors("invalid request headers", messages)
+		validationErr.Violations = violations
+		if len(violations) == 1 {
+			validationErr.Field = violations[0].Header
+		}
+		return validationErr
+	}
+
+	return nil
+}
+
+// Bind fills a new T from every part of the request: the JSON body, then the fields tagged with
+// `path:"name"`, `query:"name"` and `header:"X-Name"`, and validates the result once. Conversion,
+// decoding and validation failures of all sources are merged into a single ValidationError whose
+// Violations locate each failure with a body pointer, a parameter or a header name. opts configure
+// the body decoding as for BindJSON, and which parts are read with WithBody and WithSources. By
+// default an empty body is skipped.
+//
+// Fields bound from the path, query or headers should be tagged `json:"-"`, so a body cannot set
+// them when the other source is missing.
+func Bind[T any](ctx context.Context, v *validator.Validate, r *http.Request, opts ...BindOption) (T, error) {
+	_, span := otel.Tracer("internal/handler").Start(ctx, "Bind")
+	defer span.End()
+
+	var config bindConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	var value T
+	var messages []string
+	var violations []FieldViolation
+
+	hasBody := false
+	if config.body != BodyIgnored && r.Body != nil && r.Body != http.NoBody {
+		body, err := io.ReadAll(r.Body)
+		closeErr := r.Body.Close()
+		if closeErr != nil {
+			logutil.FromContext(ctx).Warn("Failed to close request body", zap.Error(closeErr))
+		}
+		if err != nil {
+			span.RecordError(err)
+			return value, err
+		}
+
+		if len(bytes.TrimSpace(body)) > 0 {
+			hasBody = true
+			err = decodeJSON(body, &value, opts)
+			if err != nil {
+				span.RecordError(err)
+
+				// Only errors located on a field leave the rest of the body usable
+				var validationErr ValidationError
+				if !errors.As(err, &validationErr) || len(validationErr.Violations) == 0 {
+					return value, err
+				}
+				messages = append(messages, validationErr.Errors...)
+				violations = append(violations, validationErr.Violations...)
+			}
+		}
+	}
+
+	if config.body == BodyRequired && !hasBody {
+		messages = append(messages, "request body is required")
+	}
+
+	query := r.URL.Query()
+	sources := []struct {
+		tag         string
+		requireUUID bool
+		lookup      func(name string) ([]string, bool)
+	}{
+		{tag: "path", requireUUID: true, lookup: pathLookup(r)},
+		{tag: "query", lookup: func(name string) ([]string, bool) {
+			values, ok := query[name]
+			return values, ok && len(values) > 0
+		}},
+		{tag: "header", lookup: headerLookup(r)},
+	}
+
+	for _, source := range sources {
+		if len(config.sources) > 0 && !slices.Contains(config.sources, source.tag) {
+			continue
+		}
+
+		fieldErrs, err := bindTagged(&value, source.tag, source.requireUUID, source.lookup)
+		if err != nil {
+			span.RecordError(err)
+			return value, err
+		}
+
+		for _, fieldErr := range fieldErrs {
+			var message string
+			var validationErr ValidationError
+			switch {
+			case errors.As(fieldErr.err, &validationErr):
+				message = validationErr.Message
+			case errors.Is(fieldErr.err, ErrInvalidUUID):
+				message = fmt.Sprintf("'%s' must be a valid UUID", fieldErr.name)
+			default:
+				// Unsupported field types are a programming error, not a client one
+				span.RecordError(fieldErr.err)
+				return value, fieldErr.err
+			}
+
+			violation := sourceViolation(source.tag, fieldErr.name)
+			violation.Message = message
+			messages = append(messages, message)
+			violations = append(violations, violation)
+		}
+	}
+
+	// Fields that failed to bind are left at their zero value, validating them again would only
+	// repeat the failure, e.g. with an additional "is required"
+	if len(messages) == 0 {
+		err := v.Struct(value)
+		if err != nil {
+			validationMessages, validationViolations, ok := translateTaggedValidationErrors(err, reflect.TypeOf(value))
+			if !ok {
+				span.RecordError(err)
+				return value, err
+			}
+			messages = append(messages, validationMessages...)
+			violations = append(violations, validationViolations...)
+		}
+	}
+
+	if len(messages) > 0 {
+		validationErr := NewValidationErrorWithErrors("validation failed", messages)
+		validationErr.Violations = violations
+		span.RecordError(validationErr)
+		return value, validationErr
+	}
+
+	return value, nil
+}
+
+func pathLookup(r *http.Request) func(name string) ([]string, bool) {
+	return func(name string) ([]string, bool) {
+		value := r.PathValue(name)
+		return []string{value}, value != ""
+	}
+}
+
+func headerLookup(r *http.Request) func(name string) ([]string, bool) {
+	return func(name string) ([]string, bool) {
+		values := r.Header.Values(name)
+		return values, len(values) > 0
+	}
+}
+
+// sourceViolation locates a failed field of the path, query or headers
+func sourceViolation(tag, name string) FieldViolation {
+	if tag == "header" {
+		return FieldViolation{Header: name}
+	}
+	return FieldViolation{Parameter: name}
+}
+
+// translateTaggedValidationErrors is TranslateValidationErrors for the bindings of t, failed top level
+// fields bound from the path, query or headers are named and located by their tag, the others get the
+// usual body pointer. ok is false when err is not a validator.ValidationErrors.
+func translateTaggedValidationErrors(err error, t reflect.Type) ([]string, []FieldViolation, bool) {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return nil, nil, false
+	}
+
+	validationMessagesMu.RLock()
+	defer validationMessagesMu.RUnlock()
+
+	messages := make([]string, 0, len(validationErrors))
+	violations := make([]FieldViolation, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		var violation FieldViolation
+		var message string
+
+		tag, name := "", ""
+		if strings.Count(fe.StructNamespace(), ".") == 1 {
+			if field, ok := t.FieldByName(fe.StructField()); ok {
+				tag, name = requestSourceTag(field)
+			}
+		}
+
+		if tag != "" {
+			message = validationMessage(name, fe)
+			violation = sourceViolation(tag, name)
+		} else {
+			message = validationMessage(displayFieldName(fe.Field()), fe)
+			violation = FieldViolation{Pointer: fieldPointer(fe.Namespace())}
+		}
+
+		violation.Message = message
+		messages = append(messages, message)
+		violations = append(violations, violation)
+	}
+
+	return messages, violations, true
+}
+
+// requestSourceTag returns the first of the path, query and header tags set on field with its name
+func requestSourceTag(field reflect.StructField) (string, string) {
+	for _, tag := range []string{"path", "query", "header"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name != "" && name != "-" {
+			return tag, name
+		}
+	}
+	return "", ""
+}
+
+// bindFieldError is the failure to bind the field tagged name
+type bindFieldError struct {
+	name string
+	err  error
+}
+
+// firstFieldError returns the error of the first field that failed to bind, if any
+func firstFieldError(fieldErrs []bindFieldError) error {
+	if len(fieldErrs) == 0 {
+		return nil
+	}
+	return fieldErrs[0].err
+}
+
+// bindTagged walks the struct pointed by dst and sets every field tagged with tag from lookup, the
+// fields that fail to convert are returned in order. Missing values leave the field untouched, except
+// for uuid.UUID fields when requireUUID is set. err is only set when dst is not a struct pointer.
+func bindTagged(dst any, tag string, requireUUID bool, lookup func(name string) ([]string, bool)) (fieldErrs []bindFieldError, err error) {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return nil, errors.New("bind destination must be a non-nil pointer to a struct")
+	}
+	v = v.Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+		name, _, _ := strings.Cut(fieldType.Tag.Get(tag), ",")
+		if name == "" || name == "-" || !fieldType.IsExported() {
+			continue
+		}
+
+		values, ok := lookup(name)
+		if !ok && (!requireUUID || fieldType.Type != uuidType) {
+			continue
+		}
+
+		err := setValues(v.Field(i), name, values)
+		if err != nil {
+			fieldErrs = append(fieldErrs, bindFieldError{name: name, err: err})
+		}
+	}
+
+	return fieldErrs, nil
+}
+
+// setValues sets field from the values of name, slice fields get every value and other fields the first one
+func setValues(field reflect.Value, name string, values []string) error {
+	if field.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for j, raw := range values {
+			err := setField(slice.Index(j), name, raw)
+			if err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+
+	var raw string
+	if len(values) > 0 {
+		raw = values[0]
+	}
+	return setField(field, name, raw)
+}
+
+// setField converts raw into the type of field, name is used in the returned errors
+func setField(field reflect.Value, name, raw string) error {
+	if field.Type() == uuidType {
+		parsed, err := ParseUUID(raw)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(parsed))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return NewValidationError(name, raw, fmt.Sprintf("'%s' must be an integer", name))
+		}
+		field.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+		if err != nil {
+			return NewValidationError(name, raw, fmt.Sprintf("'%s' must be a non-negative integer", name))
+		}
+		field.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return NewValidationError(name, raw, fmt.Sprintf("'%s' must be a number", name))
+		}
+		field.SetFloat(parsed)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return NewValidationError(name, raw, fmt.Sprintf("'%s' must be a boolean", name))
+		}
+		field.SetBool(parsed)
+	default:
+		return fmt.Errorf("unsupported field type %s for '%s'", field.Type(), name)
+	}
+
+	return nil
+}
@@ -0,0 +1,465 @@
+package handlerutil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type bindPathTestParams struct {
+	ID     uuid.UUID `path:"id"`
+	Slug   string    `path:"slug"`
+	Number int       `path:"number"`
+}
+
+func TestBindPath(t *testing.T) {
+	id := uuid.MustParse("7a1b5f59-2a64-4c36-9b5b-95e8f1f0a2f1")
+
+	tests := []struct {
+		name      string
+		pattern   string
+		target    string
+		want      bindPathTestParams
+		wantErrIs error
+	}{
+		{
+			name:    "Should bind all path values",
+			pattern: "GET /posts/{id}/{slug}/{number}",
+			target:  "/posts/" + id.String() + "/hello/3",
+			want:    bindPathTestParams{ID: id, Slug: "hello", Number: 3},
+		},
+		{
+			name:      "Should wrap ErrInvalidUUID for malformed UUID",
+			pattern:   "GET /posts/{id}/{slug}/{number}",
+			target:    "/posts/not-a-uuid/hello/3",
+			wantErrIs: ErrInvalidUUID,
+		},
+		{
+			name:      "Should return validation error for malformed integer",
+			pattern:   "GET /posts/{id}/{slug}/{number}",
+			target:    "/posts/" + id.String() + "/hello/three",
+			wantErrIs: ErrValidation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bindPathTestParams
+			var err error
+
+			mux := http.NewServeMux()
+			mux.HandleFunc(tt.pattern, func(w http.ResponseWriter, r *http.Request) {
+				err = BindPath(r, &got)
+			})
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if tt.wantErrIs != nil {
+				if !errors.Is(err, tt.wantErrIs) {
+					t.Fatalf("BindPath() error = %v, want %v", err, tt.wantErrIs)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("BindPath() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("BindPath() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+type bindFormTestRequest struct {
+	Name  string      `form:"name" validate:"required"`
+	Age   int         `form:"age" validate:"gte=0"`
+	Owner uuid.UUID   `form:"owner"`
+	Tags  []string    `form:"tag"`
+	Refs  []uuid.UUID `form:"ref"`
+	// handle is a custom tag registered on the validator of the test
+	Handle string `form:"handle" validate:"omitempty,handle"`
+}
+
+func TestBindForm(t *testing.T) {
+	owner := uuid.MustParse("7a1b5f59-2a64-4c36-9b5b-95e8f1f0a2f1")
+	ref := uuid.MustParse("0b6f1f3e-5c1d-4b8a-9d7e-2f4a6c8e0b1d")
+
+	tests := []struct {
+		name      string
+		body      string
+		want      bindFormTestRequest
+		wantErrIs error
+	}{
+		{
+			name: "Should bind scalar and repeated values",
+			body: "name=alice&age=20&owner=" + owner.String() + "&tag=a&tag=b&ref=" + ref.String(),
+			want: bindFormTestRequest{Name: "alice", Age: 20, Owner: owner, Tags: []string{"a", "b"}, Refs: []uuid.UUID{ref}},
+		},
+		{
+			name: "Should leave missing optional fields untouched",
+			body: "name=alice",
+			want: bindFormTestRequest{Name: "alice"},
+		},
+		{
+			name:      "Should return validation error for missing required field",
+			body:      "age=20",
+			wantErrIs: ErrValidation,
+		},
+		{
+			name:      "Should return validation error for malformed integer",
+			body:      "name=alice&age=twenty",
+			wantErrIs: ErrValidation,
+		},
+		{
+			name:      "Should wrap ErrInvalidUUID for malformed UUID in a repeated field",
+			body:      "name=alice&ref=" + ref.String() + "&ref=not-a-uuid",
+			wantErrIs: ErrInvalidUUID,
+		},
+		{
+			name: "Should validate custom tags with the given validator",
+			body: "name=alice&handle=alice_01",
+			want: bindFormTestRequest{Name: "alice", Handle: "alice_01"},
+		},
+		{
+			name:      "Should return validation error for a failed custom tag",
+			body:      "name=alice&handle=alice+lin",
+			wantErrIs: ErrValidation,
+		},
+	}
+
+	v := validator.New()
+	err := v.RegisterValidation("handle", func(fl validator.FieldLevel) bool {
+		return !strings.ContainsRune(fl.Field().String(), ' ')
+	})
+	if err != nil {
+		t.Fatalf("failed to register validation: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			var got bindFormTestRequest
+			err := BindForm(r.Context(), v, r, &got)
+
+			if tt.wantErrIs != nil {
+				if !errors.Is(err, tt.wantErrIs) {
+					t.Fatalf("BindForm() error = %v, want %v", err, tt.wantErrIs)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("BindForm() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BindForm() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+type bindHeaderTestRequest struct {
+	APIKey   string    `header:"X-API-Key" validate:"required"`
+	TenantID uuid.UUID `header:"X-Tenant-ID"`
+	Limit    int       `header:"X-Limit" validate:"omitempty,lte=100"`
+	Tags     []string  `header:"X-Tag"`
+	Region   string    `header:"X-Region" validate:"omitempty,region"`
+}
+
+func TestBindHeader(t *testing.T) {
+	tenant := uuid.MustParse("7a1b5f59-2a64-4c36-9b5b-95e8f1f0a2f1")
+
+	tests := []struct {
+		name       string
+		headers    http.Header
+		want       bindHeaderTestRequest
+		wantErrIs  error
+		wantErrors []string
+	}{
+		{
+			name: "Should bind all headers",
+			headers: http.Header{
+				"X-Api-Key":   {"secret"},
+				"X-Tenant-Id": {tenant.String()},
+				"X-Limit":     {"20"},
+				"X-Tag":       {"a", "b"},
+			},
+			want: bindHeaderTestRequest{APIKey: "secret", TenantID: tenant, Limit: 20, Tags: []string{"a", "b"}},
+		},
+		{
+			name:       "Should name the missing required header",
+			headers:    http.Header{},
+			wantErrIs:  ErrValidation,
+			wantErrors: []string{"X-API-Key is required"},
+		},
+		{
+			name:       "Should name the header failing a format rule",
+			headers:    http.Header{"X-Api-Key": {"secret"}, "X-Limit": {"500"}},
+			wantErrIs:  ErrValidation,
+			wantErrors: []string{"X-Limit must be less than or equal to 100"},
+		},
+		{
+			name:      "Should wrap ErrInvalidUUID for malformed UUID",
+			headers:   http.Header{"X-Api-Key": {"secret"}, "X-Tenant-Id": {"acme"}},
+			wantErrIs: ErrInvalidUUID,
+		},
+		{
+			name:    "Should validate custom tags with the given validator",
+			headers: http.Header{"X-Api-Key": {"secret"}, "X-Region": {"tw"}},
+			want:    bindHeaderTestRequest{APIKey: "secret", Region: "tw"},
+		},
+		{
+			name:       "Should name the header failing a custom tag",
+			headers:    http.Header{"X-Api-Key": {"secret"}, "X-Region": {"mars"}},
+			wantErrIs:  ErrValidation,
+			wantErrors: []string{"X-Region failed on the 'region' rule"},
+		},
+	}
+
+	v := validator.New()
+	err := v.RegisterValidation("region", func(fl validator.FieldLevel) bool {
+		return slices.Contains([]string{"tw", "jp"}, fl.Field().String())
+	})
+	if err != nil {
+		t.Fatalf("failed to register validation: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/reports", nil)
+			r.Header = tt.headers
+
+			var got bindHeaderTestRequest
+			err := BindHeader(r.Context(), v, r, &got)
+
+			if tt.wantErrIs != nil {
+				if !errors.Is(err, tt.wantErrIs) {
+					t.Fatalf("BindHeader() error = %v, want %v", err, tt.wantErrIs)
+				}
+				var validationErr ValidationError
+				if tt.wantErrors != nil && (!errors.As(err, &validationErr) || !reflect.DeepEqual(validationErr.Errors, tt.wantErrors)) {
+					t.Errorf("BindHeader() errors = %v, want %v", validationErr.Errors, tt.wantErrors)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("BindHeader() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BindHeader() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+type bindTestUpdateRequest struct {
+	ID       uuid.UUID `path:"id" json:"-"`
+	DryRun   bool      `query:"dry_run" json:"-"`
+	Limit    int       `query:"limit" json:"-" validate:"omitempty,lte=100"`
+	TenantID string    `header:"X-Tenant-ID" json:"-" validate:"required"`
+	Name     string    `json:"name" validate:"required"`
+	Email    string    `json:"email" validate:"omitempty,email"`
+}
+
+func TestBind(t *testing.T) {
+	id := uuid.MustParse("7a1b5f59-2a64-4c36-9b5b-95e8f1f0a2f1")
+
+	tests := []struct {
+		name           string
+		target         string
+		headers        http.Header
+		body           string
+		want           bindTestUpdateRequest
+		wantViolations []FieldViolation
+	}{
+		{
+			name:    "Should bind every source",
+			target:  "/users/" + id.String() + "?dry_run=true&limit=10",
+			headers: http.Header{"X-Tenant-Id": {"acme"}},
+			body:    `{"name":"alice","email":"alice@example.com"}`,
+			want:    bindTestUpdateRequest{ID: id, DryRun: true, Limit: 10, TenantID: "acme", Name: "alice", Email: "alice@example.com"},
+		},
+		{
+			name:    "Should bind without body",
+			target:  "/users/" + id.String(),
+			headers: http.Header{"X-Tenant-Id": {"acme"}},
+			wantViolations: []FieldViolation{
+				{Pointer: "/name", Message: "name is required"},
+			},
+		},
+		{
+			name:    "Should merge conversion errors of every source",
+			target:  "/users/not-a-uuid?limit=ten",
+			headers: http.Header{"X-Tenant-Id": {"acme"}},
+			body:    `{"name":"alice","email":12}`,
+			wantViolations: []FieldViolation{
+				{Pointer: "/email", Message: "'email' must be a string"},
+				{Parameter: "id", Message: "'id' must be a valid UUID"},
+				{Parameter: "limit", Message: "'limit' must be an integer"},
+			},
+		},
+		{
+			name:   "Should locate validation errors by source",
+			target: "/users/" + id.String() + "?limit=500",
+			body:   `{"email":"not-an-email"}`,
+			wantViolations: []FieldViolation{
+				{Parameter: "limit", Message: "limit must be less than or equal to 100"},
+				{Header: "X-Tenant-ID", Message: "X-Tenant-ID is required"},
+				{Pointer: "/name", Message: "name is required"},
+				{Pointer: "/email", Message: "email must be a valid email address"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bindTestUpdateRequest
+			var err error
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("PUT /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+				got, err = Bind[bindTestUpdateRequest](r.Context(), validator.New(), r)
+			})
+			r := httptest.NewRequest(http.MethodPut, tt.target, strings.NewReader(tt.body))
+			for key, values := range tt.headers {
+				r.Header[key] = values
+			}
+			mux.ServeHTTP(httptest.NewRecorder(), r)
+
+			if tt.wantViolations != nil {
+				var validationErr ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("Bind() error = %v, want ValidationError", err)
+				}
+				if !reflect.DeepEqual(validationErr.Violations, tt.wantViolations) {
+					t.Errorf("Bind() violations = %+v, want %+v", validationErr.Violations, tt.wantViolations)
+				}
+				if len(validationErr.Errors) != len(tt.wantViolations) {
+					t.Errorf("Bind() errors = %v, want %d entries", validationErr.Errors, len(tt.wantViolations))
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Bind() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Bind() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBind_Options(t *testing.T) {
+	id := uuid.MustParse("7a1b5f59-2a64-4c36-9b5b-95e8f1f0a2f1")
+
+	tests := []struct {
+		name       string
+		opts       []BindOption
+		target     string
+		body       string
+		want       bindTestUpdateRequest
+		wantErrors []string
+	}{
+		{
+			name:   "Should bind the selected sources only",
+			opts:   []BindOption{WithSources("path", "header")},
+			target: "/users/" + id.String() + "?limit=500",
+			body:   `{"name":"alice"}`,
+			want:   bindTestUpdateRequest{ID: id, TenantID: "acme", Name: "alice"},
+		},
+		{
+			name:       "Should report a missing required body",
+			opts:       []BindOption{WithBody(BodyRequired), WithSources("path")},
+			target:     "/users/" + id.String(),
+			wantErrors: []string{"request body is required"},
+		},
+		{
+			name:       "Should not read an ignored body",
+			opts:       []BindOption{WithBody(BodyIgnored), WithSources("path", "header")},
+			target:     "/users/" + id.String(),
+			body:       `{"name":"alice"}`,
+			wantErrors: []string{"name is required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bindTestUpdateRequest
+			var err error
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("PUT /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+				got, err = Bind[bindTestUpdateRequest](r.Context(), validator.New(), r, tt.opts...)
+			})
+			r := httptest.NewRequest(http.MethodPut, tt.target, strings.NewReader(tt.body))
+			r.Header.Set("X-Tenant-ID", "acme")
+			mux.ServeHTTP(httptest.NewRecorder(), r)
+
+			if tt.wantErrors != nil {
+				var validationErr ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("Bind() error = %v, want ValidationError", err)
+				}
+				if !reflect.DeepEqual(validationErr.Errors, tt.wantErrors) {
+					t.Errorf("Bind() errors = %v, want %v", validationErr.Errors, tt.wantErrors)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Bind() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Bind() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// closeErrorBody is a request body whose Close fails
+type closeErrorBody struct {
+	io.Reader
+}
+
+func (closeErrorBody) Close() error {
+	return errors.New("connection reset")
+}
+
+func TestBind_LogsBodyCloseError(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	ctx := logutil.IntoContext(context.Background(), zap.New(core))
+
+	r := httptest.NewRequest(http.MethodPatch, "/users", nil)
+	r.Header.Set("X-Tenant-ID", "nycu")
+	r.Body = closeErrorBody{Reader: strings.NewReader(`{"name":"alice"}`)}
+
+	_, err := Bind[bindTestUpdateRequest](ctx, validator.New(), r, WithSources("header"))
+	if err != nil {
+		t.Fatalf("Bind() unexpected error: %v", err)
+	}
+
+	entries := logs.FilterMessage("Failed to close request body").All()
+	if len(entries) != 1 {
+		t.Fatalf("got %d close failure entries, want 1", len(entries))
+	}
+	if got := entries[0].ContextMap()["error"]; got != "connection reset" {
+		t.Errorf("error = %v, want %q", got, "connection reset")
+	}
+}
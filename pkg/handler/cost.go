@@ -0,0 +1,53 @@
+package handlerutil
+
+import (
+	"context"
+	"net/http"
+)
+
+// RequestCost classifies how expensive a route is to serve, so that limiting middlewares can give
+// expensive endpoints such as reports their own, tighter budget instead of letting them starve cheap reads
+type RequestCost int
+
+const (
+	// CostCheap is the cost of routes without an annotation
+	CostCheap RequestCost = iota
+	CostExpensive
+)
+
+func (c RequestCost) String() string {
+	switch c {
+	case CostCheap:
+		return "cheap"
+	case CostExpensive:
+		return "expensive"
+	default:
+		return "unknown"
+	}
+}
+
+type requestCostContextKey struct{}
+
+// WithRequestCost returns a copy of ctx annotated with cost
+func WithRequestCost(ctx context.Context, cost RequestCost) context.Context {
+	return context.WithValue(ctx, requestCostContextKey{}, cost)
+}
+
+// RequestCostFromContext returns the cost annotated on ctx, CostCheap when there is none
+func RequestCostFromContext(ctx context.Context) RequestCost {
+	cost, ok := ctx.Value(requestCostContextKey{}).(RequestCost)
+	if !ok {
+		return CostCheap
+	}
+	return cost
+}
+
+// WithCost annotates the requests of a route with cost. The annotation is only visible to the
+// middlewares it wraps, so it goes around the whole middleware set of the route:
+//
+//	mux.HandleFunc("GET /api/reports", handlerutil.WithCost(handlerutil.CostExpensive, set.HandlerFunc(h.Report)))
+func WithCost(cost RequestCost, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(w, r.WithContext(WithRequestCost(r.Context(), cost)))
+	}
+}
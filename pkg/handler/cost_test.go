@@ -0,0 +1,44 @@
+package handlerutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithCost(t *testing.T) {
+	tests := []struct {
+		name     string
+		annotate bool
+		cost     RequestCost
+		want     RequestCost
+	}{
+		{name: "Should default to cheap", want: CostCheap},
+		{name: "Should carry expensive annotation", annotate: true, cost: CostExpensive, want: CostExpensive},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got RequestCost
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				got = RequestCostFromContext(r.Context())
+			}
+			if tt.annotate {
+				handler = WithCost(tt.cost, handler)
+			}
+
+			handler(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if got != tt.want {
+				t.Errorf("RequestCostFromContext() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestCostFromContext_Empty(t *testing.T) {
+	if got := RequestCostFromContext(context.Background()); got != CostCheap {
+		t.Errorf("RequestCostFromContext() = %v, want %v", got, CostCheap)
+	}
+}
@@ -0,0 +1,17 @@
+package handlerutil
+
+import (
+	"github.com/NYCU-SDC/summer/pkg/pagination"
+)
+
+// EncodeCursor turns v into an opaque, URL-safe token with codec, see pagination.CursorCodec. A nil
+// codec only base64 encodes the JSON of v, so clients can read and forge it.
+func EncodeCursor(codec *pagination.CursorCodec, v any) (string, error) {
+	return codec.EncodeValue(v)
+}
+
+// DecodeCursor decodes a token from EncodeCursor into dst. Malformed tokens, and tokens that were not
+// produced with the same codec key, wrap pagination.ErrInvalidCursor and map to a 400 problem.
+func DecodeCursor(codec *pagination.CursorCodec, token string, dst any) error {
+	return codec.DecodeValue(token, dst)
+}
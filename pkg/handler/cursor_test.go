@@ -0,0 +1,95 @@
+package handlerutil
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/NYCU-SDC/summer/pkg/pagination"
+)
+
+type cursorTestValue struct {
+	CreatedAt string `json:"c"`
+	ID        string `json:"i"`
+}
+
+func newTestCursorCodec(t *testing.T, key string) *pagination.CursorCodec {
+	t.Helper()
+
+	if key == "" {
+		return nil
+	}
+	codec, err := pagination.NewCursorCodec([]byte(key))
+	if err != nil {
+		t.Fatalf("NewCursorCodec() unexpected error: %v", err)
+	}
+	return codec
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := cursorTestValue{CreatedAt: "2024-05-01T10:00:00Z", ID: "42"}
+
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{name: "Should round trip cursor without codec"},
+		{name: "Should round trip encrypted cursor", key: "0123456789abcdef"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codec := newTestCursorCodec(t, tt.key)
+
+			token, err := EncodeCursor(codec, want)
+			if err != nil {
+				t.Fatalf("EncodeCursor() unexpected error: %v", err)
+			}
+			if strings.ContainsAny(token, "+/=") {
+				t.Errorf("EncodeCursor() = %q, want URL-safe token", token)
+			}
+			if tt.key != "" && strings.Contains(token, "eyJ") {
+				t.Errorf("EncodeCursor() = %q, want encrypted payload", token)
+			}
+
+			var got cursorTestValue
+			err = DecodeCursor(codec, token, &got)
+			if err != nil {
+				t.Fatalf("DecodeCursor() unexpected error: %v", err)
+			}
+			if got != want {
+				t.Errorf("DecodeCursor() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	encrypted, _ := EncodeCursor(newTestCursorCodec(t, "0123456789abcdef"), cursorTestValue{ID: "42"})
+	plain, _ := EncodeCursor(nil, cursorTestValue{ID: "42"})
+	tampered := []byte(encrypted)
+	tampered[len(tampered)-2] ^= 1
+
+	tests := []struct {
+		name  string
+		key   string
+		token string
+	}{
+		{name: "Should reject malformed encoding", token: "not base64!"},
+		{name: "Should reject malformed payload", token: "bm90LWpzb24"},
+		{name: "Should reject plain cursor when encrypting", key: "0123456789abcdef", token: plain},
+		{name: "Should reject cursor encrypted with another key", key: "fedcba9876543210", token: encrypted},
+		{name: "Should reject tampered cursor", key: "0123456789abcdef", token: string(tampered)},
+		{name: "Should reject encrypted cursor without codec", token: encrypted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got cursorTestValue
+			err := DecodeCursor(newTestCursorCodec(t, tt.key), tt.token, &got)
+			if !errors.Is(err, pagination.ErrInvalidCursor) {
+				t.Errorf("DecodeCursor() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
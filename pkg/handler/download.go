@@ -0,0 +1,74 @@
+package handlerutil
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// FileMeta describes a file served by ServeFileDownload
+type FileMeta struct {
+	Name string
+
+	// ContentType is detected from the file extension, then from the content, when empty
+	ContentType string
+
+	// ModTime is used for Last-Modified and If-Range, zero omits them
+	ModTime time.Time
+
+	// Inline asks the browser to display the file instead of saving it
+	Inline bool
+}
+
+// ServeFileDownload writes the content of reader as a file download. Range, If-Range and
+// the conditional request headers are handled by http.ServeContent, the Content-Disposition
+// filename is encoded per RFC 6266 so non-ASCII names survive, and reading stops as soon as
+// the request context is canceled.
+func ServeFileDownload(w http.ResponseWriter, r *http.Request, reader io.ReadSeeker, meta FileMeta) {
+	disposition := "attachment"
+	if meta.Inline {
+		disposition = "inline"
+	}
+
+	if meta.Name != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": meta.Name}))
+	} else {
+		w.Header().Set("Content-Disposition", disposition)
+	}
+
+	contentType := meta.ContentType
+	if contentType == "" && meta.Name != "" {
+		contentType = mime.TypeByExtension(filepath.Ext(meta.Name))
+	}
+	// Leaving Content-Type unset lets http.ServeContent sniff it from the content
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	http.ServeContent(w, r, meta.Name, meta.ModTime, &contextReadSeeker{ctx: r.Context(), ReadSeeker: reader})
+}
+
+// WriteFile sends content as an attachment named filename, it is ServeFileDownload for the common
+// case of an export without a modification time. An empty contentType is detected as in FileMeta.
+func WriteFile(w http.ResponseWriter, r *http.Request, content io.ReadSeeker, filename, contentType string) {
+	ServeFileDownload(w, r, content, FileMeta{Name: filename, ContentType: contentType})
+}
+
+// contextReadSeeker stops reading once the context is canceled
+type contextReadSeeker struct {
+	io.ReadSeeker
+	ctx context.Context
+}
+
+func (c *contextReadSeeker) Read(p []byte) (int, error) {
+	err := c.ctx.Err()
+	if err != nil {
+		return 0, err
+	}
+	return c.ReadSeeker.Read(p)
+}
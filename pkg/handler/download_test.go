@@ -0,0 +1,95 @@
+package handlerutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServeFileDownload(t *testing.T) {
+	tests := []struct {
+		name            string
+		meta            FileMeta
+		rangeHeader     string
+		wantStatus      int
+		wantBody        string
+		wantDisposition string
+		wantContentType string
+	}{
+		{
+			name:            "Should serve whole file as attachment",
+			meta:            FileMeta{Name: "report.csv"},
+			wantStatus:      http.StatusOK,
+			wantBody:        "id,name\n1,alice\n",
+			wantDisposition: `attachment; filename=report.csv`,
+			wantContentType: "text/csv; charset=utf-8",
+		},
+		{
+			name:            "Should serve partial content for range request",
+			meta:            FileMeta{Name: "report.csv", ContentType: "text/csv"},
+			rangeHeader:     "bytes=0-6",
+			wantStatus:      http.StatusPartialContent,
+			wantBody:        "id,name",
+			wantDisposition: `attachment; filename=report.csv`,
+			wantContentType: "text/csv",
+		},
+		{
+			name:            "Should encode non ASCII filename",
+			meta:            FileMeta{Name: "報表.csv", Inline: true},
+			wantStatus:      http.StatusOK,
+			wantBody:        "id,name\n1,alice\n",
+			wantDisposition: `inline; filename*=utf-8''%E5%A0%B1%E8%A1%A8.csv`,
+			wantContentType: "text/csv; charset=utf-8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/export", nil)
+			if tt.rangeHeader != "" {
+				r.Header.Set("Range", tt.rangeHeader)
+			}
+			w := httptest.NewRecorder()
+
+			ServeFileDownload(w, r, strings.NewReader("id,name\n1,alice\n"), tt.meta)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("ServeFileDownload() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("ServeFileDownload() body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+
+			if got := w.Header().Get("Content-Disposition"); got != tt.wantDisposition {
+				t.Errorf("ServeFileDownload() Content-Disposition = %v, want %v", got, tt.wantDisposition)
+			}
+
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("ServeFileDownload() Content-Type = %v, want %v", got, tt.wantContentType)
+			}
+		})
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/export", nil)
+	r.Header.Set("Range", "bytes=8-")
+	w := httptest.NewRecorder()
+
+	WriteFile(w, r, strings.NewReader("id,name\n1,alice\n"), "匯出 2024.csv", "text/csv")
+
+	if w.Code != http.StatusPartialContent {
+		t.Errorf("WriteFile() status = %v, want %v", w.Code, http.StatusPartialContent)
+	}
+	if w.Body.String() != "1,alice\n" {
+		t.Errorf("WriteFile() body = %q, want %q", w.Body.String(), "1,alice\n")
+	}
+	if got, want := w.Header().Get("Content-Disposition"), `attachment; filename*=utf-8''%E5%8C%AF%E5%87%BA%202024.csv`; got != want {
+		t.Errorf("WriteFile() Content-Disposition = %v, want %v", got, want)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/csv" {
+		t.Errorf("WriteFile() Content-Type = %v, want text/csv", got)
+	}
+}
@@ -0,0 +1,89 @@
+package handlerutil
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/NYCU-SDC/summer/pkg/pagination"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// Envelope is the opt-in success shape {"data": ..., "meta": {...}}, the counterpart of problem+json for errors
+type Envelope struct {
+	Data any          `json:"data"`
+	Meta EnvelopeMeta `json:"meta"`
+}
+
+type EnvelopeMeta struct {
+	RequestID  string          `json:"requestId,omitempty"`
+	Timestamp  time.Time       `json:"timestamp"`
+	Pagination *PaginationMeta `json:"pagination,omitempty"`
+	Extra      map[string]any  `json:"extra,omitempty"`
+}
+
+// PaginationMeta is pagination.Response without the items, which go into the envelope data
+type PaginationMeta struct {
+	TotalPages  int  `json:"totalPages"`
+	TotalItems  int  `json:"totalItems"`
+	CurrentPage int  `json:"currentPage"`
+	PageSize    int  `json:"pageSize"`
+	HasNextPage bool `json:"hasNextPage"`
+}
+
+// EnvelopeOption adds metadata to an envelope
+type EnvelopeOption func(*EnvelopeMeta)
+
+// WithRequestID overrides the request id, which defaults to the X-Request-ID request header
+func WithRequestID(requestID string) EnvelopeOption {
+	return func(m *EnvelopeMeta) {
+		m.RequestID = requestID
+	}
+}
+
+func WithPagination(meta PaginationMeta) EnvelopeOption {
+	return func(m *EnvelopeMeta) {
+		m.Pagination = &meta
+	}
+}
+
+// WithMeta adds a service specific entry under meta.extra
+func WithMeta(key string, value any) EnvelopeOption {
+	return func(m *EnvelopeMeta) {
+		if m.Extra == nil {
+			m.Extra = map[string]any{}
+		}
+		m.Extra[key] = value
+	}
+}
+
+// NewEnvelope wraps data with the request id of r and the current time
+func NewEnvelope(r *http.Request, data any, opts ...EnvelopeOption) Envelope {
+	meta := EnvelopeMeta{Timestamp: time.Now().UTC()}
+	if r != nil {
+		meta.RequestID = r.Header.Get(RequestIDHeader)
+	}
+
+	for _, opt := range opts {
+		opt(&meta)
+	}
+	return Envelope{Data: data, Meta: meta}
+}
+
+// WriteEnvelope writes data wrapped in an Envelope with WriteJSONResponse
+func WriteEnvelope(w http.ResponseWriter, r *http.Request, status int, data any, opts ...EnvelopeOption) {
+	WriteJSONResponse(w, status, NewEnvelope(r, data, opts...))
+}
+
+// WritePageEnvelope writes the items of a page as the envelope data and the rest of the page as meta.pagination
+func WritePageEnvelope[T any](w http.ResponseWriter, r *http.Request, status int, page pagination.Response[T], opts ...EnvelopeOption) {
+	opts = append([]EnvelopeOption{WithPagination(PaginationMeta{
+		TotalPages:  page.TotalPages,
+		TotalItems:  page.TotalItems,
+		CurrentPage: page.CurrentPage,
+		PageSize:    page.PageSize,
+		HasNextPage: page.HasNextPage,
+	})}, opts...)
+
+	WriteEnvelope(w, r, status, page.Items, opts...)
+}
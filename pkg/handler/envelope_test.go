@@ -0,0 +1,76 @@
+package handlerutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NYCU-SDC/summer/pkg/pagination"
+)
+
+func TestWriteEnvelope(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	r.Header.Set(RequestIDHeader, "req-123")
+	w := httptest.NewRecorder()
+
+	WriteEnvelope(w, r, http.StatusOK, map[string]string{"name": "alice"}, WithMeta("version", "v2"))
+
+	var got struct {
+		Data map[string]string `json:"data"`
+		Meta struct {
+			RequestID  string          `json:"requestId"`
+			Timestamp  string          `json:"timestamp"`
+			Pagination *PaginationMeta `json:"pagination"`
+			Extra      map[string]any  `json:"extra"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode envelope: %v", err)
+	}
+
+	if got.Data["name"] != "alice" {
+		t.Errorf("expected data name alice, got %v", got.Data)
+	}
+	if got.Meta.RequestID != "req-123" {
+		t.Errorf("expected request id req-123, got %q", got.Meta.RequestID)
+	}
+	if got.Meta.Timestamp == "" {
+		t.Errorf("expected a timestamp")
+	}
+	if got.Meta.Pagination != nil {
+		t.Errorf("expected no pagination, got %+v", got.Meta.Pagination)
+	}
+	if got.Meta.Extra["version"] != "v2" {
+		t.Errorf("expected extra version v2, got %v", got.Meta.Extra)
+	}
+}
+
+func TestWritePageEnvelope(t *testing.T) {
+	page := pagination.NewFactory[string](100, nil).NewResponse([]string{"a", "b"}, 5, 1, 2)
+
+	w := httptest.NewRecorder()
+	WritePageEnvelope(w, httptest.NewRequest(http.MethodGet, "/items", nil), http.StatusOK, page, WithRequestID("req-456"))
+
+	var got struct {
+		Data []string `json:"data"`
+		Meta struct {
+			RequestID  string         `json:"requestId"`
+			Pagination PaginationMeta `json:"pagination"`
+		} `json:"meta"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode envelope: %v", err)
+	}
+
+	if len(got.Data) != 2 {
+		t.Errorf("expected 2 items, got %v", got.Data)
+	}
+	if got.Meta.RequestID != "req-456" {
+		t.Errorf("expected request id req-456, got %q", got.Meta.RequestID)
+	}
+	want := PaginationMeta{TotalPages: page.TotalPages, TotalItems: 5, CurrentPage: page.CurrentPage, PageSize: 2, HasNextPage: page.HasNextPage}
+	if got.Meta.Pagination != want {
+		t.Errorf("expected pagination %+v, got %+v", want, got.Meta.Pagination)
+	}
+}
@@ -3,6 +3,8 @@ package handlerutil
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 )
 
 var (
@@ -14,6 +16,13 @@ var (
 	ErrInternalServer    = errors.New("internal server error")
 	ErrInvalidUUID       = errors.New("failed to parse UUID")
 	ErrValidation        = errors.New("validation error")
+
+	ErrConflict            = errors.New("conflict")
+	ErrPayloadTooLarge     = errors.New("payload too large")
+	ErrTooManyRequests     = errors.New("too many requests")
+	ErrPreconditionFailed  = errors.New("precondition failed")
+	ErrUnprocessableEntity = errors.New("unprocessable entity")
+	ErrServiceUnavailable  = errors.New("service unavailable")
 )
 
 type NotFoundError struct {
@@ -51,6 +60,18 @@ type ValidationError struct {
 	Value   interface{}
 	Message string
 	Errors  []string
+
+	// Violations locates each failed field in the request body, when known
+	Violations []FieldViolation
+}
+
+// FieldViolation is a failed field. Pointer is a JSON Pointer (RFC 6901) into the request body,
+// Parameter names a path or query parameter and Header a request header, only one of them is set.
+type FieldViolation struct {
+	Pointer   string
+	Parameter string
+	Header    string
+	Message   string
 }
 
 func (e ValidationError) Error() string {
@@ -81,3 +102,151 @@ func NewValidationErrorWithErrors(message string, errs []string) ValidationError
 		Errors:  errs,
 	}
 }
+
+// ConflictError reports a write clashing with the current state of a resource,
+// Constraint names the rule that was violated, e.g. "unique_email"
+type ConflictError struct {
+	Resource   string
+	Constraint string
+	Message    string
+}
+
+func (e ConflictError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Resource != "" && e.Constraint != "" {
+		return fmt.Sprintf("conflict on %s: %s", e.Resource, e.Constraint)
+	}
+	if e.Resource != "" {
+		return fmt.Sprintf("conflict on %s", e.Resource)
+	}
+	return ErrConflict.Error()
+}
+
+func (e ConflictError) Is(target error) bool {
+	return errors.Is(target, ErrConflict)
+}
+
+func NewConflictError(resource, constraint, message string) ConflictError {
+	return ConflictError{
+		Resource:   resource,
+		Constraint: constraint,
+		Message:    message,
+	}
+}
+
+// PayloadTooLargeError reports a request body over the accepted size, both in bytes
+type PayloadTooLargeError struct {
+	Limit   int64
+	Size    int64
+	Message string
+}
+
+func (e PayloadTooLargeError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Limit > 0 {
+		return fmt.Sprintf("payload exceeds the limit of %d bytes", e.Limit)
+	}
+	return ErrPayloadTooLarge.Error()
+}
+
+func (e PayloadTooLargeError) Is(target error) bool {
+	return errors.Is(target, ErrPayloadTooLarge)
+}
+
+func NewPayloadTooLargeError(limit, size int64, message string) PayloadTooLargeError {
+	return PayloadTooLargeError{
+		Limit:   limit,
+		Size:    size,
+		Message: message,
+	}
+}
+
+// TooManyRequestsError reports a rate limit, a positive RetryAfter is sent in the Retry-After header
+type TooManyRequestsError struct {
+	RetryAfter time.Duration
+	Message    string
+}
+
+func (e TooManyRequestsError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("too many requests, retry after %s", e.RetryAfter)
+	}
+	return ErrTooManyRequests.Error()
+}
+
+func (e TooManyRequestsError) Is(target error) bool {
+	return errors.Is(target, ErrTooManyRequests)
+}
+
+func NewTooManyRequestsError(retryAfter time.Duration, message string) TooManyRequestsError {
+	return TooManyRequestsError{
+		RetryAfter: retryAfter,
+		Message:    message,
+	}
+}
+
+// PreconditionFailedError reports a conditional request whose precondition, e.g. "If-Match", does not hold
+type PreconditionFailedError struct {
+	Resource     string
+	Precondition string
+	Message      string
+}
+
+func (e PreconditionFailedError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Resource != "" && e.Precondition != "" {
+		return fmt.Sprintf("%s precondition failed for %s", e.Precondition, e.Resource)
+	}
+	return ErrPreconditionFailed.Error()
+}
+
+func (e PreconditionFailedError) Is(target error) bool {
+	return errors.Is(target, ErrPreconditionFailed)
+}
+
+func NewPreconditionFailedError(resource, precondition, message string) PreconditionFailedError {
+	return PreconditionFailedError{
+		Resource:     resource,
+		Precondition: precondition,
+		Message:      message,
+	}
+}
+
+// DependencyUnavailableError reports a request refused because critical dependencies are failing,
+// a positive RetryAfter is sent in the Retry-After header
+type DependencyUnavailableError struct {
+	Dependencies []string
+	RetryAfter   time.Duration
+	Message      string
+}
+
+func (e DependencyUnavailableError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if len(e.Dependencies) > 0 {
+		return fmt.Sprintf("dependencies unavailable: %s", strings.Join(e.Dependencies, ", "))
+	}
+	return ErrServiceUnavailable.Error()
+}
+
+func (e DependencyUnavailableError) Is(target error) bool {
+	return errors.Is(target, ErrServiceUnavailable)
+}
+
+func NewDependencyUnavailableError(dependencies []string, retryAfter time.Duration, message string) DependencyUnavailableError {
+	return DependencyUnavailableError{
+		Dependencies: dependencies,
+		RetryAfter:   retryAfter,
+		Message:      message,
+	}
+}
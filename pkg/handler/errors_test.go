@@ -3,6 +3,7 @@ package handlerutil
 import (
 	"errors"
 	"testing"
+	"time"
 )
 
 func TestValidationError_Error(t *testing.T) {
@@ -269,3 +270,60 @@ func TestValidationError_ErrorInterface(t *testing.T) {
 		})
 	}
 }
+
+func TestTypedErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		sentinel error
+		want     string
+	}{
+		{
+			name:     "Should describe conflict with resource and constraint",
+			err:      NewConflictError("users", "unique_email", ""),
+			sentinel: ErrConflict,
+			want:     "conflict on users: unique_email",
+		},
+		{
+			name:     "Should prefer conflict message",
+			err:      NewConflictError("users", "unique_email", "email already taken"),
+			sentinel: ErrConflict,
+			want:     "email already taken",
+		},
+		{
+			name:     "Should describe payload limit",
+			err:      NewPayloadTooLargeError(1024, 4096, ""),
+			sentinel: ErrPayloadTooLarge,
+			want:     "payload exceeds the limit of 1024 bytes",
+		},
+		{
+			name:     "Should describe retry delay",
+			err:      NewTooManyRequestsError(30*time.Second, ""),
+			sentinel: ErrTooManyRequests,
+			want:     "too many requests, retry after 30s",
+		},
+		{
+			name:     "Should describe failed precondition",
+			err:      NewPreconditionFailedError("order", "If-Match", ""),
+			sentinel: ErrPreconditionFailed,
+			want:     "If-Match precondition failed for order",
+		},
+		{
+			name:     "Should fall back to sentinel message",
+			err:      PreconditionFailedError{},
+			sentinel: ErrPreconditionFailed,
+			want:     "precondition failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %v, want %v", got, tt.want)
+			}
+			if !errors.Is(tt.err, tt.sentinel) {
+				t.Errorf("errors.Is(%v, %v) = false, want true", tt.err, tt.sentinel)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package handlerutil
+
+import (
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// List formats served by NegotiateList, the first media type of each is sent as the Content-Type
+var listFormats = []struct {
+	name       string
+	mediaTypes []string
+}{
+	{name: "json", mediaTypes: []string{"application/json"}},
+	{name: "csv", mediaTypes: []string{"text/csv"}},
+	{name: "ndjson", mediaTypes: []string{"application/x-ndjson", "application/ndjson"}},
+}
+
+// NegotiateList writes the items of seq as a JSON array, CSV or NDJSON according to the Accept
+// header, so a list endpoint can serve exports from the same query. JSON is used when the header
+// asks for nothing else. CSV and NDJSON are streamed and sent as an attachment named filename
+// with the format extension appended, an empty filename leaves them inline.
+//
+// Errors yielded before the first item are returned before anything is written, so the caller can
+// still answer with a problem. Later errors truncate a streamed body, see WriteNDJSONSeq.
+//
+// CSV columns follow the `csv:"name"` tags of T, then its json tags, then the field names, a "-"
+// tag skips the field. Values implementing encoding.TextMarshaler or fmt.Stringer use them, times
+// are written in RFC 3339 and other non scalar values as JSON. Text starting with =, +, -, @, a
+// tab or a carriage return is prefixed with a single quote, so that spreadsheet applications do
+// not run it as a formula.
+func NegotiateList[T any](w http.ResponseWriter, r *http.Request, seq iter.Seq2[T, error], filename string) error {
+	format := negotiateListFormat(r.Header.Get("Accept"))
+	w.Header().Add("Vary", "Accept")
+
+	next, stop := iter.Pull2(seq)
+	defer stop()
+
+	first, err, ok := next()
+	if ok && err != nil {
+		return err
+	}
+
+	// remaining yields the first item again, followed by the rest of seq
+	remaining := func(yield func(T, error) bool) {
+		if !ok || !yield(first, nil) {
+			return
+		}
+		for {
+			item, err, ok := next()
+			if !ok || !yield(item, err) {
+				return
+			}
+		}
+	}
+
+	if format == "json" {
+		items := []T{}
+		for item, err := range remaining {
+			if err != nil {
+				return err
+			}
+			items = append(items, item)
+		}
+		WriteJSONResponse(w, http.StatusOK, items)
+		return nil
+	}
+
+	if filename != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename + "." + format}))
+	}
+
+	if format == "ndjson" {
+		return WriteNDJSONSeq(w, remaining)
+	}
+	return writeCSVSeq(w, remaining)
+}
+
+// negotiateListFormat picks the list format like negotiateCodec picks a codec
+func negotiateListFormat(accept string) string {
+	ranges := parseAccept(accept)
+
+	best := listFormats[0].name
+	bestQ, bestSpecificity := 0.0, -1
+	for _, format := range listFormats {
+		for _, mediaType := range format.mediaTypes {
+			q, specificity := acceptQuality(ranges, mediaType)
+			if q > bestQ || (q > 0 && q == bestQ && specificity > bestSpecificity) {
+				best = format.name
+				bestQ, bestSpecificity = q, specificity
+			}
+		}
+	}
+	return best
+}
+
+func writeCSVSeq[T any](w http.ResponseWriter, seq iter.Seq2[T, error]) error {
+	columns := csvColumnsOf(reflect.TypeFor[T]())
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	controller := http.NewResponseController(w)
+	writer := csv.NewWriter(w)
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.name
+	}
+	err := writer.Write(header)
+	if err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	pending := 0
+	lastFlush := time.Now()
+	flush := func() error {
+		writer.Flush()
+		pending = 0
+		lastFlush = time.Now()
+		// See ndjsonWriter.flush for why the flush error is ignored
+		_ = controller.Flush()
+		return writer.Error()
+	}
+
+	record := make([]string, len(columns))
+	for item, err := range seq {
+		if err != nil {
+			_ = flush()
+			return err
+		}
+
+		value := reflect.ValueOf(item)
+		for value.Kind() == reflect.Pointer && !value.IsNil() {
+			value = value.Elem()
+		}
+		for i, column := range columns {
+			switch {
+			case column.index == nil:
+				record[i] = csvValue(value)
+			case value.Kind() == reflect.Struct:
+				// Fails when the field is promoted through a nil embedded pointer
+				field, err := value.FieldByIndexErr(column.index)
+				record[i] = ""
+				if err == nil {
+					record[i] = csvValue(field)
+				}
+			default:
+				record[i] = ""
+			}
+		}
+
+		err = writer.Write(record)
+		if err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
+		}
+
+		pending++
+		if pending >= ndjsonFlushItems || time.Since(lastFlush) >= ndjsonFlushInterval {
+			err = flush()
+			if err != nil {
+				return fmt.Errorf("failed to write CSV record: %w", err)
+			}
+		}
+	}
+
+	err = flush()
+	if err != nil {
+		return fmt.Errorf("failed to write CSV record: %w", err)
+	}
+	return nil
+}
+
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+var csvColumnsCache sync.Map // reflect.Type -> []csvColumn
+
+// csvColumnsOf returns the columns of a struct type, or of the struct a pointer type points to
+func csvColumnsOf(t reflect.Type) []csvColumn {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Scalars are written as a single column
+	if t.Kind() != reflect.Struct {
+		return []csvColumn{{name: "value"}}
+	}
+
+	if cached, ok := csvColumnsCache.Load(t); ok {
+		return cached.([]csvColumn)
+	}
+
+	var columns []csvColumn
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("csv"), ",")
+		if name == "" {
+			name, _, _ = strings.Cut(field.Tag.Get("json"), ",")
+		}
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		columns = append(columns, csvColumn{name: name, index: field.Index})
+	}
+
+	csvColumnsCache.Store(t, columns)
+	return columns
+}
+
+var (
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	stringerType      = reflect.TypeFor[fmt.Stringer]()
+	timeType          = reflect.TypeFor[time.Time]()
+)
+
+func csvValue(v reflect.Value) string {
+	if !v.IsValid() {
+		return ""
+	}
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == timeType:
+		return v.Interface().(time.Time).Format(time.RFC3339)
+	case v.Type().Implements(textMarshalerType):
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err == nil {
+			return csvText(string(text))
+		}
+	case v.Type().Implements(stringerType):
+		return csvText(v.Interface().(fmt.Stringer).String())
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return csvText(v.String())
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	}
+
+	encoded, err := json.Marshal(v.Interface())
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// csvText escapes text that a spreadsheet would read as a formula, numbers are written by
+// csvValue without it so negative values stay numeric
+func csvText(s string) string {
+	if s != "" && strings.IndexByte("=+-@\t\r", s[0]) >= 0 {
+		return "'" + s
+	}
+	return s
+}
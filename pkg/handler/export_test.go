@@ -0,0 +1,175 @@
+package handlerutil
+
+import (
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type exportTestItem struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name" csv:"full_name"`
+	Score     float64   `json:"score"`
+	CreatedAt time.Time `json:"createdAt"`
+	Secret    string    `json:"-"`
+}
+
+func exportTestSeq(items []exportTestItem, err error) iter.Seq2[exportTestItem, error] {
+	return func(yield func(exportTestItem, error) bool) {
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(exportTestItem{}, err)
+		}
+	}
+}
+
+func TestNegotiateList(t *testing.T) {
+	id := uuid.MustParse("7a1b5f59-2a64-4c36-9b5b-95e8f1f0a2f1")
+	created := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	items := []exportTestItem{
+		{ID: id, Name: "Lin, Alice", Score: 92.5, CreatedAt: created, Secret: "s"},
+		{ID: id, Name: "Bob", Score: 80, CreatedAt: created},
+	}
+
+	tests := []struct {
+		name            string
+		accept          string
+		filename        string
+		wantContentType string
+		wantDisposition string
+		wantBody        string
+	}{
+		{
+			name:            "Should default to JSON array",
+			wantContentType: "application/json",
+			wantBody:        `[{"id":"7a1b5f59-2a64-4c36-9b5b-95e8f1f0a2f1","name":"Lin, Alice","score":92.5,"createdAt":"2024-05-01T10:00:00Z"},{"id":"7a1b5f59-2a64-4c36-9b5b-95e8f1f0a2f1","name":"Bob","score":80,"createdAt":"2024-05-01T10:00:00Z"}]`,
+		},
+		{
+			name:            "Should serve CSV export as attachment",
+			accept:          "text/csv",
+			filename:        "scores",
+			wantContentType: "text/csv; charset=utf-8",
+			wantDisposition: "attachment; filename=scores.csv",
+			wantBody: "id,full_name,score,createdAt\n" +
+				"7a1b5f59-2a64-4c36-9b5b-95e8f1f0a2f1,\"Lin, Alice\",92.5,2024-05-01T10:00:00Z\n" +
+				"7a1b5f59-2a64-4c36-9b5b-95e8f1f0a2f1,Bob,80,2024-05-01T10:00:00Z\n",
+		},
+		{
+			name:            "Should serve NDJSON when preferred",
+			accept:          "text/csv;q=0.5, application/x-ndjson",
+			wantContentType: "application/x-ndjson",
+			wantBody: `{"id":"7a1b5f59-2a64-4c36-9b5b-95e8f1f0a2f1","name":"Lin, Alice","score":92.5,"createdAt":"2024-05-01T10:00:00Z"}` + "\n" +
+				`{"id":"7a1b5f59-2a64-4c36-9b5b-95e8f1f0a2f1","name":"Bob","score":80,"createdAt":"2024-05-01T10:00:00Z"}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/scores", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			err := NegotiateList(w, r, exportTestSeq(items, nil), tt.filename)
+			if err != nil {
+				t.Fatalf("NegotiateList() unexpected error: %v", err)
+			}
+
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("NegotiateList() Content-Type = %q, want %q", got, tt.wantContentType)
+			}
+			if got := w.Header().Get("Content-Disposition"); got != tt.wantDisposition {
+				t.Errorf("NegotiateList() Content-Disposition = %q, want %q", got, tt.wantDisposition)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("NegotiateList() body = %q, want %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestNegotiateList_CSVFormulas(t *testing.T) {
+	tests := []struct {
+		name     string
+		itemName string
+		score    float64
+		wantRow  string
+	}{
+		{name: "Should escape a leading equals sign", itemName: "=HYPERLINK(\"http://evil\")", wantRow: "\"'=HYPERLINK(\"\"http://evil\"\")\",0"},
+		{name: "Should escape a leading plus sign", itemName: "+1+1", wantRow: "'+1+1,0"},
+		{name: "Should escape a leading minus sign", itemName: "-2+3", wantRow: "'-2+3,0"},
+		{name: "Should escape a leading at sign", itemName: "@SUM(A1)", wantRow: "'@SUM(A1),0"},
+		{name: "Should escape a leading tab", itemName: "\t=1", wantRow: "'\t=1,0"},
+		{name: "Should escape a leading carriage return", itemName: "\r=1", wantRow: "\"'\r=1\",0"},
+		{name: "Should keep text with a formula character inside", itemName: "Bob = Alice", wantRow: "Bob = Alice,0"},
+		{name: "Should keep negative numbers", itemName: "Bob", score: -1.5, wantRow: "Bob,-1.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			type formulaItem struct {
+				Name  string  `csv:"name"`
+				Score float64 `csv:"score"`
+			}
+			seq := func(yield func(formulaItem, error) bool) {
+				yield(formulaItem{Name: tt.itemName, Score: tt.score}, nil)
+			}
+			r := httptest.NewRequest(http.MethodGet, "/scores", nil)
+			r.Header.Set("Accept", "text/csv")
+			w := httptest.NewRecorder()
+
+			err := NegotiateList(w, r, seq, "")
+			if err != nil {
+				t.Fatalf("NegotiateList() unexpected error: %v", err)
+			}
+
+			want := "name,score\n" + tt.wantRow + "\n"
+			if w.Body.String() != want {
+				t.Errorf("NegotiateList() body = %q, want %q", w.Body.String(), want)
+			}
+		})
+	}
+}
+
+func TestNegotiateList_Errors(t *testing.T) {
+	errQuery := errors.New("query failed")
+
+	t.Run("Should return early error before writing", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/scores", nil)
+		r.Header.Set("Accept", "text/csv")
+		w := httptest.NewRecorder()
+
+		err := NegotiateList(w, r, exportTestSeq(nil, errQuery), "scores")
+		if !errors.Is(err, errQuery) {
+			t.Fatalf("NegotiateList() error = %v, want %v", err, errQuery)
+		}
+		if w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" {
+			t.Errorf("NegotiateList() wrote a response before the error: %q", w.Body.String())
+		}
+	})
+
+	t.Run("Should truncate stream on later error", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/scores", nil)
+		r.Header.Set("Accept", "text/csv")
+		w := httptest.NewRecorder()
+
+		err := NegotiateList(w, r, exportTestSeq([]exportTestItem{{Name: "Bob"}}, errQuery), "")
+		if !errors.Is(err, errQuery) {
+			t.Fatalf("NegotiateList() error = %v, want %v", err, errQuery)
+		}
+		want := "id,full_name,score,createdAt\n00000000-0000-0000-0000-000000000000,Bob,0,0001-01-01T00:00:00Z\n"
+		if w.Body.String() != want {
+			t.Errorf("NegotiateList() body = %q, want %q", w.Body.String(), want)
+		}
+	})
+}
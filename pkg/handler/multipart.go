@@ -0,0 +1,212 @@
+package handlerutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// MultipartOptions bounds what ParseMultipart accepts, the zero values of MaxMemory, MaxFiles and
+// MaxTotalSize fall back to the ones of DefaultMultipartOptions
+type MultipartOptions struct {
+	// MaxMemory is the total size of the non-file form values kept in memory
+	MaxMemory int64
+
+	// MaxFileSize is the maximum size of a single uploaded file, zero only applies MaxTotalSize
+	MaxFileSize int64
+
+	// MaxFiles is the maximum number of uploaded files
+	MaxFiles int
+
+	// MaxTotalSize is the maximum size of all the uploaded files together, which bounds the
+	// temporary disk space a request can use
+	MaxTotalSize int64
+
+	// AllowedMIMETypes lists the accepted file types, detected from the content rather than
+	// trusting the client supplied Content-Type, empty allows every type
+	AllowedMIMETypes []string
+
+	// TempDir is where uploaded files are streamed to, empty uses os.TempDir
+	TempDir string
+}
+
+func DefaultMultipartOptions() MultipartOptions {
+	return MultipartOptions{
+		MaxMemory:    1 << 20,
+		MaxFileSize:  10 << 20,
+		MaxFiles:     10,
+		MaxTotalSize: 32 << 20,
+	}
+}
+
+// UploadedFile is a file part of a multipart request streamed to a temporary file.
+// The caller owns the temporary file and should call Remove once done with it.
+type UploadedFile struct {
+	FieldName   string
+	Filename    string
+	ContentType string
+	Size        int64
+	Path        string
+}
+
+func (f UploadedFile) Open() (*os.File, error) {
+	return os.Open(f.Path)
+}
+
+func (f UploadedFile) Remove() error {
+	return os.Remove(f.Path)
+}
+
+// MultipartForm is the result of ParseMultipart
+type MultipartForm struct {
+	Values map[string][]string
+	Files  []UploadedFile
+}
+
+// RemoveAll removes the temporary files of every uploaded file
+func (f *MultipartForm) RemoveAll() {
+	for _, file := range f.Files {
+		_ = file.Remove()
+	}
+}
+
+// ParseMultipart streams a multipart/form-data request without buffering files in memory.
+// Values or files over the size limits are returned as PayloadTooLargeError, too many files and
+// disallowed file types as ValidationError, and no temporary file is left behind when an error
+// is returned.
+func ParseMultipart(r *http.Request, opts MultipartOptions) (*MultipartForm, error) {
+	defaults := DefaultMultipartOptions()
+	if opts.MaxMemory <= 0 {
+		opts.MaxMemory = defaults.MaxMemory
+	}
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = defaults.MaxFiles
+	}
+	if opts.MaxTotalSize <= 0 {
+		opts.MaxTotalSize = defaults.MaxTotalSize
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, NewValidationError("", nil, "request is not multipart/form-data")
+	}
+
+	form := &MultipartForm{Values: map[string][]string{}}
+	remainingMemory := opts.MaxMemory
+	remainingTotal := opts.MaxTotalSize
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			form.RemoveAll()
+			return nil, NewValidationErrorWithErrors("malformed multipart body", []string{err.Error()})
+		}
+
+		name := part.FormName()
+		if name == "" {
+			_ = part.Close()
+			continue
+		}
+
+		if part.FileName() == "" {
+			var value bytes.Buffer
+			n, err := io.Copy(&value, io.LimitReader(part, remainingMemory+1))
+			_ = part.Close()
+			if err != nil {
+				form.RemoveAll()
+				return nil, fmt.Errorf("failed to read form value '%s': %w", name, err)
+			}
+			if n > remainingMemory {
+				form.RemoveAll()
+				return nil, NewPayloadTooLargeError(opts.MaxMemory, 0, "form values exceed the maximum size")
+			}
+			remainingMemory -= n
+			form.Values[name] = append(form.Values[name], value.String())
+			continue
+		}
+
+		if len(form.Files) >= opts.MaxFiles {
+			_ = part.Close()
+			form.RemoveAll()
+			return nil, NewValidationError(name, nil, fmt.Sprintf("request exceeds the maximum of %d files", opts.MaxFiles))
+		}
+
+		file, err := saveUploadedFile(part, opts, remainingTotal)
+		_ = part.Close()
+		if err != nil {
+			form.RemoveAll()
+			return nil, err
+		}
+		remainingTotal -= file.Size
+		form.Files = append(form.Files, file)
+	}
+
+	return form, nil
+}
+
+// saveUploadedFile sniffs the content type of the part and streams it to a temporary file, of at
+// most remainingTotal bytes
+func saveUploadedFile(part *multipart.Part, opts MultipartOptions, remainingTotal int64) (UploadedFile, error) {
+	name := part.FormName()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(part, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return UploadedFile{}, fmt.Errorf("failed to read file '%s': %w", name, err)
+	}
+	head = head[:n]
+
+	contentType := http.DetectContentType(head)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if len(opts.AllowedMIMETypes) > 0 && !slices.Contains(opts.AllowedMIMETypes, mediaType) {
+		return UploadedFile{}, NewValidationError(name, mediaType, fmt.Sprintf("file type '%s' is not allowed", mediaType))
+	}
+
+	tempFile, err := os.CreateTemp(opts.TempDir, "upload-*")
+	if err != nil {
+		return UploadedFile{}, fmt.Errorf("failed to create temporary file: %w", err)
+	}
+
+	limit := remainingTotal
+	if opts.MaxFileSize > 0 {
+		limit = min(limit, opts.MaxFileSize)
+	}
+	source := io.LimitReader(io.MultiReader(bytes.NewReader(head), part), limit+1)
+
+	size, err := io.Copy(tempFile, source)
+	closeErr := tempFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tempFile.Name())
+		return UploadedFile{}, fmt.Errorf("failed to save file '%s': %w", name, err)
+	}
+
+	if opts.MaxFileSize > 0 && size > opts.MaxFileSize {
+		_ = os.Remove(tempFile.Name())
+		return UploadedFile{}, NewPayloadTooLargeError(opts.MaxFileSize, 0, fmt.Sprintf("file '%s' exceeds the maximum size of %d bytes", name, opts.MaxFileSize))
+	}
+	if size > remainingTotal {
+		_ = os.Remove(tempFile.Name())
+		return UploadedFile{}, NewPayloadTooLargeError(opts.MaxTotalSize, 0, fmt.Sprintf("files exceed the maximum total size of %d bytes", opts.MaxTotalSize))
+	}
+
+	return UploadedFile{
+		FieldName:   name,
+		Filename:    filepath.Base(part.FileName()),
+		ContentType: mediaType,
+		Size:        size,
+		Path:        tempFile.Name(),
+	}, nil
+}
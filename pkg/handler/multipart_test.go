@@ -0,0 +1,154 @@
+package handlerutil
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func newMultipartRequest(t *testing.T, values map[string]string, fileField, filename string, content []byte) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for k, v := range values {
+		if err := writer.WriteField(k, v); err != nil {
+			t.Fatalf("Failed to write field: %v", err)
+		}
+	}
+	if fileField != "" {
+		part, err := writer.CreateFormFile(fileField, filename)
+		if err != nil {
+			t.Fatalf("Failed to create form file: %v", err)
+		}
+		_, _ = part.Write(content)
+	}
+	_ = writer.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	return r
+}
+
+func TestParseMultipart(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+
+	tests := []struct {
+		name     string
+		opts     MultipartOptions
+		content  []byte
+		wantErr  error
+		wantType string
+	}{
+		{
+			name:     "Should save allowed file",
+			opts:     MultipartOptions{MaxMemory: 1024, MaxFileSize: 1024, AllowedMIMETypes: []string{"image/png"}},
+			content:  png,
+			wantType: "image/png",
+		},
+		{
+			name:    "Should reject disallowed type",
+			opts:    MultipartOptions{MaxMemory: 1024, MaxFileSize: 1024, AllowedMIMETypes: []string{"image/png"}},
+			content: []byte("plain text pretending to be an image"),
+			wantErr: ErrValidation,
+		},
+		{
+			name:    "Should reject file over the maximum size",
+			opts:    MultipartOptions{MaxMemory: 1024, MaxFileSize: 16},
+			content: png,
+			wantErr: ErrPayloadTooLarge,
+		},
+		{
+			name:    "Should reject files over the maximum total size",
+			opts:    MultipartOptions{MaxMemory: 1024, MaxFileSize: 1024, MaxTotalSize: 16},
+			content: png,
+			wantErr: ErrPayloadTooLarge,
+		},
+		{
+			name:    "Should reject values over the maximum memory",
+			opts:    MultipartOptions{MaxMemory: 4, MaxFileSize: 1024},
+			content: png,
+			wantErr: ErrPayloadTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.TempDir = t.TempDir()
+			r := newMultipartRequest(t, map[string]string{"title": "avatar"}, "file", "../avatar.png", tt.content)
+
+			form, err := ParseMultipart(r, tt.opts)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseMultipart() error = %v, want %v", err, tt.wantErr)
+				}
+				entries, _ := os.ReadDir(tt.opts.TempDir)
+				if len(entries) != 0 {
+					t.Errorf("ParseMultipart() left %d temporary files behind", len(entries))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMultipart() unexpected error: %v", err)
+			}
+			defer form.RemoveAll()
+
+			if got := form.Values["title"]; len(got) != 1 || got[0] != "avatar" {
+				t.Errorf("ParseMultipart() values = %v, want title=avatar", form.Values)
+			}
+			if len(form.Files) != 1 {
+				t.Fatalf("ParseMultipart() files = %d, want 1", len(form.Files))
+			}
+
+			file := form.Files[0]
+			if file.ContentType != tt.wantType {
+				t.Errorf("UploadedFile.ContentType = %v, want %v", file.ContentType, tt.wantType)
+			}
+			if file.Filename != "avatar.png" {
+				t.Errorf("UploadedFile.Filename = %v, want avatar.png", file.Filename)
+			}
+			if file.Size != int64(len(tt.content)) {
+				t.Errorf("UploadedFile.Size = %v, want %v", file.Size, len(tt.content))
+			}
+		})
+	}
+}
+
+func TestParseMultipart_MaxFiles(t *testing.T) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		part, err := writer.CreateFormFile("files", name)
+		if err != nil {
+			t.Fatalf("Failed to create form file: %v", err)
+		}
+		_, _ = part.Write([]byte("content of " + name))
+	}
+	_ = writer.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+
+	tempDir := t.TempDir()
+	_, err := ParseMultipart(r, MultipartOptions{MaxFiles: 2, TempDir: tempDir})
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("ParseMultipart() error = %v, want ErrValidation", err)
+	}
+	entries, _ := os.ReadDir(tempDir)
+	if len(entries) != 0 {
+		t.Errorf("ParseMultipart() left %d temporary files behind", len(entries))
+	}
+}
+
+func TestParseMultipart_DefaultLimits(t *testing.T) {
+	r := newMultipartRequest(t, map[string]string{"title": string(bytes.Repeat([]byte("x"), 2<<20))}, "", "", nil)
+
+	_, err := ParseMultipart(r, MultipartOptions{TempDir: t.TempDir()})
+	if !errors.Is(err, ErrPayloadTooLarge) {
+		t.Fatalf("ParseMultipart() error = %v, want ErrPayloadTooLarge from the default MaxMemory", err)
+	}
+}
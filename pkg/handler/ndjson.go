@@ -0,0 +1,121 @@
+package handlerutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"time"
+)
+
+const (
+	// ndjsonFlushItems and ndjsonFlushInterval bound how long encoded lines wait in the
+	// response buffer, whichever is reached first triggers a flush
+	ndjsonFlushItems    = 100
+	ndjsonFlushInterval = 200 * time.Millisecond
+)
+
+// WriteNDJSON streams the items received from the channel as newline-delimited JSON until it
+// is closed. The response is flushed periodically and whenever the producer falls behind, so
+// clients receive items as they are produced instead of at the end.
+//
+// The producer should stop sending once the request context is done, WriteNDJSON returns on
+// the first write error and does not drain the channel.
+func WriteNDJSON[T any](w http.ResponseWriter, items <-chan T) error {
+	writer := newNDJSONWriter(w)
+
+	for {
+		var (
+			item T
+			ok   bool
+		)
+
+		select {
+		case item, ok = <-items:
+		default:
+			// Nothing is ready, send what has been encoded so far before waiting
+			writer.flush()
+			item, ok = <-items
+		}
+
+		if !ok {
+			writer.flush()
+			return nil
+		}
+
+		err := writer.write(item)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// WriteNDJSONSeq streams the items of seq as newline-delimited JSON, flushing periodically.
+// An error yielded by seq stops the stream and is returned, since the status has already been
+// sent the client only sees a truncated body.
+func WriteNDJSONSeq[T any](w http.ResponseWriter, seq iter.Seq2[T, error]) error {
+	writer := newNDJSONWriter(w)
+
+	for item, err := range seq {
+		if err != nil {
+			writer.flush()
+			return err
+		}
+
+		err = writer.write(item)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.flush()
+	return nil
+}
+
+type ndjsonWriter struct {
+	w          http.ResponseWriter
+	encoder    *json.Encoder
+	controller *http.ResponseController
+	pending    int
+	lastFlush  time.Time
+}
+
+func newNDJSONWriter(w http.ResponseWriter) *ndjsonWriter {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	return &ndjsonWriter{
+		w:          w,
+		encoder:    json.NewEncoder(w),
+		controller: http.NewResponseController(w),
+		lastFlush:  time.Now(),
+	}
+}
+
+// write encodes one item on its own line, json.Encoder already terminates each value with a newline
+func (n *ndjsonWriter) write(item any) error {
+	err := n.encoder.Encode(item)
+	if err != nil {
+		return fmt.Errorf("failed to write NDJSON item: %w", err)
+	}
+
+	n.pending++
+	if n.pending >= ndjsonFlushItems || time.Since(n.lastFlush) >= ndjsonFlushInterval {
+		n.flush()
+	}
+	return nil
+}
+
+func (n *ndjsonWriter) flush() {
+	if n.pending == 0 {
+		return
+	}
+
+	n.pending = 0
+	n.lastFlush = time.Now()
+
+	// Writers without flush support return http.ErrNotSupported and a failed flush means the
+	// client is gone, which the next write reports, so the error is not needed here
+	_ = n.controller.Flush()
+}
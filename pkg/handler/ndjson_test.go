@@ -0,0 +1,87 @@
+package handlerutil
+
+import (
+	"errors"
+	"iter"
+	"net/http/httptest"
+	"testing"
+)
+
+type ndjsonItem struct {
+	ID int `json:"id"`
+}
+
+func TestWriteNDJSON(t *testing.T) {
+	items := make(chan ndjsonItem)
+	go func() {
+		defer close(items)
+		for i := 1; i <= 3; i++ {
+			items <- ndjsonItem{ID: i}
+		}
+	}()
+
+	w := httptest.NewRecorder()
+	err := WriteNDJSON(w, items)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := w.Header().Get("Content-Type"); got != "application/x-ndjson" {
+		t.Errorf("expected Content-Type application/x-ndjson, got %q", got)
+	}
+	want := "{\"id\":1}\n{\"id\":2}\n{\"id\":3}\n"
+	if w.Body.String() != want {
+		t.Errorf("expected body %q, got %q", want, w.Body.String())
+	}
+	if !w.Flushed {
+		t.Errorf("expected response to be flushed")
+	}
+}
+
+func TestWriteNDJSONSeq(t *testing.T) {
+	errBroken := errors.New("broken cursor")
+
+	tests := []struct {
+		name     string
+		seq      iter.Seq2[ndjsonItem, error]
+		wantBody string
+		wantErr  error
+	}{
+		{
+			name: "Should write all items",
+			seq: func(yield func(ndjsonItem, error) bool) {
+				for i := 1; i <= 2; i++ {
+					if !yield(ndjsonItem{ID: i}, nil) {
+						return
+					}
+				}
+			},
+			wantBody: "{\"id\":1}\n{\"id\":2}\n",
+		},
+		{
+			name: "Should stop on error",
+			seq: func(yield func(ndjsonItem, error) bool) {
+				if !yield(ndjsonItem{ID: 1}, nil) {
+					return
+				}
+				yield(ndjsonItem{}, errBroken)
+			},
+			wantBody: "{\"id\":1}\n",
+			wantErr:  errBroken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			err := WriteNDJSONSeq(w, tt.seq)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
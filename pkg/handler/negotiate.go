@@ -0,0 +1,165 @@
+package handlerutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// ResponseCodec encodes response bodies for the media types it serves, the first entry of
+// MediaTypes identifies the codec and the matched entry is sent as the Content-Type
+type ResponseCodec struct {
+	MediaTypes []string
+	Marshal    func(v any) ([]byte, error)
+}
+
+var (
+	responseCodecsMu sync.RWMutex
+	responseCodecs   = []ResponseCodec{
+		{
+			MediaTypes: []string{"application/json"},
+			Marshal:    json.Marshal,
+		},
+		{
+			MediaTypes: []string{"application/xml", "text/xml"},
+			Marshal:    xml.Marshal,
+		},
+		{
+			MediaTypes: []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"},
+			Marshal:    marshalMsgpack,
+		},
+	}
+)
+
+// RegisterResponseCodec adds a codec used by Negotiate, or replaces the codec already serving its
+// first media type. It is meant to be called once at startup.
+func RegisterResponseCodec(codec ResponseCodec) {
+	responseCodecsMu.Lock()
+	defer responseCodecsMu.Unlock()
+
+	for i, existing := range responseCodecs {
+		if existing.MediaTypes[0] == codec.MediaTypes[0] {
+			responseCodecs[i] = codec
+			return
+		}
+	}
+	responseCodecs = append(responseCodecs, codec)
+}
+
+// Negotiate writes data in the format the client prefers according to the Accept header, JSON,
+// XML and MessagePack are available out of the box. JSON is used when the header is missing or
+// asks for nothing the server can produce, as most clients handle it anyway.
+//
+// XML is produced by encoding/xml, so data must be a struct or a slice of structs, maps are not supported.
+func Negotiate(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	codec, mediaType := negotiateCodec(r.Header.Get("Accept"))
+
+	body, err := codec.Marshal(data)
+	if err != nil {
+		http.Error(w, "Failed to marshal response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("Content-Type", mediaType)
+	w.WriteHeader(status)
+
+	_, err = w.Write(body)
+	if err != nil {
+		http.Error(w, "Failed to write response", http.StatusInternalServerError)
+		return
+	}
+}
+
+type acceptRange struct {
+	mediaType string
+	q         float64
+}
+
+// negotiateCodec returns the registered codec with the highest q-value in the Accept header, each
+// media type is weighed by the most specific range matching it and an explicitly listed type wins
+// over a wildcard with the same q-value, remaining ties go to the codec registered first
+func negotiateCodec(accept string) (ResponseCodec, string) {
+	responseCodecsMu.RLock()
+	defer responseCodecsMu.RUnlock()
+
+	ranges := parseAccept(accept)
+
+	best, bestMediaType := responseCodecs[0], responseCodecs[0].MediaTypes[0]
+	bestQ, bestSpecificity := 0.0, -1
+	for _, codec := range responseCodecs {
+		for _, mediaType := range codec.MediaTypes {
+			q, specificity := acceptQuality(ranges, mediaType)
+			if q > bestQ || (q > 0 && q == bestQ && specificity > bestSpecificity) {
+				best, bestMediaType = codec, mediaType
+				bestQ, bestSpecificity = q, specificity
+			}
+		}
+	}
+	return best, bestMediaType
+}
+
+func parseAccept(accept string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err == nil {
+				q = parsed
+			}
+		}
+		ranges = append(ranges, acceptRange{mediaType: mediaType, q: q})
+	}
+	return ranges
+}
+
+// acceptQuality returns the q-value of the most specific range matching mediaType and how specific
+// that range is, from 0 for */* to 2 for the exact type, the q-value is zero when no range matches
+func acceptQuality(ranges []acceptRange, mediaType string) (float64, int) {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+
+	q, specificity := 0.0, -1
+	for _, ar := range ranges {
+		var s int
+		switch {
+		case ar.mediaType == mediaType:
+			s = 2
+		case ar.mediaType == mainType+"/*":
+			s = 1
+		case ar.mediaType == "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			q, specificity = ar.q, s
+		}
+	}
+	return q, specificity
+}
+
+// marshalMsgpack reuses the json tags, so the field names match the JSON representation
+func marshalMsgpack(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+
+	err := enc.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,92 @@
+package handlerutil
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type negotiateTestResponse struct {
+	XMLName xml.Name `json:"-" xml:"user"`
+	ID      int      `json:"id" xml:"id"`
+	Name    string   `json:"name" xml:"name"`
+}
+
+func TestNegotiate(t *testing.T) {
+	data := negotiateTestResponse{ID: 7, Name: "alice"}
+
+	tests := []struct {
+		name            string
+		accept          string
+		wantContentType string
+	}{
+		{name: "Should default to JSON without Accept", wantContentType: "application/json"},
+		{name: "Should use JSON for wildcard", accept: "*/*", wantContentType: "application/json"},
+		{name: "Should use XML", accept: "application/xml", wantContentType: "application/xml"},
+		{name: "Should keep the requested XML alias", accept: "text/xml", wantContentType: "text/xml"},
+		{name: "Should prefer explicit type over wildcard", accept: "*/*, application/msgpack", wantContentType: "application/msgpack"},
+		{name: "Should respect q-values", accept: "application/json;q=0.5, application/xml;q=0.9", wantContentType: "application/xml"},
+		{name: "Should fall back to JSON when nothing matches", accept: "image/png", wantContentType: "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+
+			Negotiate(w, r, http.StatusOK, data)
+
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Fatalf("expected Content-Type %q, got %q", tt.wantContentType, got)
+			}
+
+			var got negotiateTestResponse
+			var err error
+			switch tt.wantContentType {
+			case "application/json":
+				err = json.Unmarshal(w.Body.Bytes(), &got)
+			case "application/xml", "text/xml":
+				err = xml.Unmarshal(w.Body.Bytes(), &got)
+			case "application/msgpack":
+				dec := msgpack.NewDecoder(w.Body)
+				dec.SetCustomStructTag("json")
+				err = dec.Decode(&got)
+			}
+			if err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if got.ID != data.ID || got.Name != data.Name {
+				t.Errorf("expected %+v, got %+v", data, got)
+			}
+		})
+	}
+}
+
+func TestRegisterResponseCodec(t *testing.T) {
+	RegisterResponseCodec(ResponseCodec{
+		MediaTypes: []string{"text/csv"},
+		Marshal: func(v any) ([]byte, error) {
+			return []byte("id,name\n7,alice\n"), nil
+		},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/csv")
+	w := httptest.NewRecorder()
+
+	Negotiate(w, r, http.StatusOK, negotiateTestResponse{ID: 7, Name: "alice"})
+
+	if got := w.Header().Get("Content-Type"); got != "text/csv" {
+		t.Errorf("expected Content-Type text/csv, got %q", got)
+	}
+	if w.Body.String() != "id,name\n7,alice\n" {
+		t.Errorf("unexpected body %q", w.Body.String())
+	}
+}
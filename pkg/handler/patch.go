@@ -0,0 +1,371 @@
+package handlerutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"go.opentelemetry.io/otel"
+)
+
+// ApplyJSONMergePatch applies an RFC 7386 merge patch to existing and validates the result.
+// existing is not modified. Malformed patches and invalid results are returned as ValidationError.
+func ApplyJSONMergePatch[T any](ctx context.Context, v *validator.Validate, existing T, patch []byte) (T, error) {
+	_, span := otel.Tracer("internal/handler").Start(ctx, "ApplyJSONMergePatch")
+	defer span.End()
+
+	var result T
+
+	var patchDoc any
+	err := json.Unmarshal(patch, &patchDoc)
+	if err != nil {
+		span.RecordError(err)
+		return result, NewValidationErrorWithErrors("invalid merge patch", []string{err.Error()})
+	}
+
+	doc, err := toJSONDocument(existing)
+	if err != nil {
+		span.RecordError(err)
+		return result, err
+	}
+
+	return fromJSONDocument[T](v, mergePatch(doc, patchDoc))
+}
+
+// mergePatch implements the MergePatch function of RFC 7386 section 2
+func mergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = map[string]any{}
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// PatchOperation is a single operation of an RFC 6902 JSON patch
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// ApplyJSONPatch applies an RFC 6902 JSON patch to existing and validates the result.
+// existing is not modified. Malformed patches, failed operations, including a failed "test",
+// and invalid results are returned as ValidationError.
+func ApplyJSONPatch[T any](ctx context.Context, v *validator.Validate, existing T, patch []byte) (T, error) {
+	_, span := otel.Tracer("internal/handler").Start(ctx, "ApplyJSONPatch")
+	defer span.End()
+
+	var result T
+
+	var operations []PatchOperation
+	err := json.Unmarshal(patch, &operations)
+	if err != nil {
+		span.RecordError(err)
+		return result, NewValidationErrorWithErrors("invalid JSON patch", []string{err.Error()})
+	}
+
+	doc, err := toJSONDocument(existing)
+	if err != nil {
+		span.RecordError(err)
+		return result, err
+	}
+
+	for i, operation := range operations {
+		doc, err = applyPatchOperation(doc, operation)
+		if err != nil {
+			span.RecordError(err)
+			return result, NewValidationErrorWithErrors("failed to apply JSON patch", []string{fmt.Sprintf("operation %d (%s %s): %v", i, operation.Op, operation.Path, err)})
+		}
+	}
+
+	return fromJSONDocument[T](v, doc)
+}
+
+func applyPatchOperation(doc any, operation PatchOperation) (any, error) {
+	var value any
+	if operation.Op == "add" || operation.Op == "replace" || operation.Op == "test" {
+		if operation.Value == nil {
+			return nil, fmt.Errorf("missing value")
+		}
+		err := json.Unmarshal(operation.Value, &value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value: %w", err)
+		}
+	}
+
+	switch operation.Op {
+	case "add":
+		return pointerAdd(doc, operation.Path, value)
+	case "remove":
+		doc, _, err := pointerRemove(doc, operation.Path)
+		return doc, err
+	case "replace":
+		doc, _, err := pointerRemove(doc, operation.Path)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, operation.Path, value)
+	case "move":
+		if strings.HasPrefix(operation.Path, operation.From+"/") {
+			return nil, fmt.Errorf("cannot move a value into one of its children")
+		}
+		doc, moved, err := pointerRemove(doc, operation.From)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, operation.Path, moved)
+	case "copy":
+		copied, err := pointerGet(doc, operation.From)
+		if err != nil {
+			return nil, err
+		}
+		return pointerAdd(doc, operation.Path, deepCopyJSON(copied))
+	case "test":
+		current, err := pointerGet(doc, operation.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, fmt.Errorf("test failed")
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("unknown operation %q", operation.Op)
+	}
+}
+
+// parsePointer splits an RFC 6901 JSON pointer into its unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if index > length || (!allowEnd && index == length) {
+		return 0, fmt.Errorf("array index %d out of range", index)
+	}
+	return index, nil
+}
+
+func pointerGet(doc any, pointer string) (any, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	current := doc
+	for _, token := range tokens {
+		switch node := current.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path %q does not exist", pointer)
+			}
+			current = value
+		case []any:
+			index, err := arrayIndex(token, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("path %q does not exist", pointer)
+		}
+	}
+	return current, nil
+}
+
+// pointerAdd returns doc with value added at pointer, following the "add" semantics of RFC 6902
+func pointerAdd(doc any, pointer string, value any) (any, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := pointerGet(doc, parentPointer)
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+
+	switch node := parent.(type) {
+	case map[string]any:
+		node[last] = value
+		return doc, nil
+	case []any:
+		index, err := arrayIndex(last, len(node), true)
+		if err != nil {
+			return nil, err
+		}
+		node = append(node[:index], append([]any{value}, node[index:]...)...)
+		return pointerSet(doc, parentPointer, node)
+	default:
+		return nil, fmt.Errorf("path %q does not exist", parentPointer)
+	}
+}
+
+// pointerRemove returns doc with the value at pointer removed, together with the removed value
+func pointerRemove(doc any, pointer string) (any, any, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := pointerGet(doc, parentPointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	last := tokens[len(tokens)-1]
+
+	switch node := parent.(type) {
+	case map[string]any:
+		removed, ok := node[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("path %q does not exist", pointer)
+		}
+		delete(node, last)
+		return doc, removed, nil
+	case []any:
+		index, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, nil, err
+		}
+		removed := node[index]
+		node = append(node[:index:index], node[index+1:]...)
+		doc, err = pointerSet(doc, parentPointer, node)
+		return doc, removed, err
+	default:
+		return nil, nil, fmt.Errorf("path %q does not exist", pointer)
+	}
+}
+
+// pointerSet replaces the value at pointer, it is used to store arrays after they were resized
+func pointerSet(doc any, pointer string, value any) (any, error) {
+	tokens, err := parsePointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := pointerGet(doc, parentPointer)
+	if err != nil {
+		return nil, err
+	}
+	last := tokens[len(tokens)-1]
+
+	switch node := parent.(type) {
+	case map[string]any:
+		node[last] = value
+	case []any:
+		index, err := arrayIndex(last, len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = value
+	}
+	return doc, nil
+}
+
+func deepCopyJSON(value any) any {
+	switch node := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(node))
+		for k, v := range node {
+			copied[k] = deepCopyJSON(v)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(node))
+		for i, v := range node {
+			copied[i] = deepCopyJSON(v)
+		}
+		return copied
+	default:
+		return value
+	}
+}
+
+// toJSONDocument converts value into its generic JSON representation
+func toJSONDocument(value any) (any, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal patch target: %w", err)
+	}
+
+	var doc any
+	err = json.Unmarshal(raw, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal patch target: %w", err)
+	}
+	return doc, nil
+}
+
+// fromJSONDocument decodes the patched document into a new T and validates it
+func fromJSONDocument[T any](v *validator.Validate, doc any) (T, error) {
+	var result T
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return result, fmt.Errorf("failed to marshal patched document: %w", err)
+	}
+
+	err = json.Unmarshal(raw, &result)
+	if err != nil {
+		return result, NewValidationErrorWithErrors("patched document does not match the resource", []string{err.Error()})
+	}
+
+	if v != nil {
+		err = v.Struct(result)
+		if err != nil {
+			return result, NewValidationErrorWithErrors("patched resource is invalid", []string{err.Error()})
+		}
+	}
+
+	return result, nil
+}
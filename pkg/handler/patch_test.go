@@ -0,0 +1,130 @@
+package handlerutil
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type patchTestResource struct {
+	Name  string   `json:"name" validate:"required"`
+	Email string   `json:"email,omitempty"`
+	Tags  []string `json:"tags"`
+}
+
+func TestApplyJSONMergePatch(t *testing.T) {
+	existing := patchTestResource{Name: "alice", Email: "alice@example.com", Tags: []string{"a"}}
+
+	tests := []struct {
+		name    string
+		patch   string
+		want    patchTestResource
+		wantErr bool
+	}{
+		{
+			name:  "Should replace given fields only",
+			patch: `{"name":"bob"}`,
+			want:  patchTestResource{Name: "bob", Email: "alice@example.com", Tags: []string{"a"}},
+		},
+		{
+			name:  "Should remove fields set to null",
+			patch: `{"email":null,"tags":["x","y"]}`,
+			want:  patchTestResource{Name: "alice", Tags: []string{"x", "y"}},
+		},
+		{
+			name:    "Should reject malformed patch",
+			patch:   `{"name":`,
+			wantErr: true,
+		},
+		{
+			name:    "Should reject invalid result",
+			patch:   `{"name":null}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyJSONMergePatch(context.Background(), validator.New(), existing, []byte(tt.patch))
+			if tt.wantErr {
+				if !errors.Is(err, ErrValidation) {
+					t.Fatalf("ApplyJSONMergePatch() error = %v, want ErrValidation", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyJSONMergePatch() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplyJSONMergePatch() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if existing.Name != "alice" || len(existing.Tags) != 1 {
+		t.Errorf("ApplyJSONMergePatch() modified existing value: %+v", existing)
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	existing := patchTestResource{Name: "alice", Email: "alice@example.com", Tags: []string{"a", "b"}}
+
+	tests := []struct {
+		name    string
+		patch   string
+		want    patchTestResource
+		wantErr bool
+	}{
+		{
+			name:  "Should replace and append",
+			patch: `[{"op":"replace","path":"/name","value":"bob"},{"op":"add","path":"/tags/-","value":"c"}]`,
+			want:  patchTestResource{Name: "bob", Email: "alice@example.com", Tags: []string{"a", "b", "c"}},
+		},
+		{
+			name:  "Should insert, remove and move",
+			patch: `[{"op":"add","path":"/tags/0","value":"z"},{"op":"remove","path":"/tags/2"},{"op":"move","from":"/email","path":"/name"}]`,
+			want:  patchTestResource{Name: "alice@example.com", Tags: []string{"z", "a"}},
+		},
+		{
+			name:  "Should pass test operation and copy",
+			patch: `[{"op":"test","path":"/name","value":"alice"},{"op":"copy","from":"/name","path":"/email"}]`,
+			want:  patchTestResource{Name: "alice", Email: "alice", Tags: []string{"a", "b"}},
+		},
+		{
+			name:    "Should fail on failed test operation",
+			patch:   `[{"op":"test","path":"/name","value":"bob"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "Should fail on missing path",
+			patch:   `[{"op":"remove","path":"/missing"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "Should fail on unknown operation",
+			patch:   `[{"op":"merge","path":"/name"}]`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyJSONPatch(context.Background(), validator.New(), existing, []byte(tt.patch))
+			if tt.wantErr {
+				if !errors.Is(err, ErrValidation) {
+					t.Fatalf("ApplyJSONPatch() error = %v, want ErrValidation", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyJSONPatch() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplyJSONPatch() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,18 +1,27 @@
 package handlerutil
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
 
+	"github.com/NYCU-SDC/summer/pkg/log"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
 )
 
-func ParseAndValidateRequestBody(ctx context.Context, v *validator.Validate, r *http.Request, s interface{}) error {
+// ParseAndValidateRequestBody decodes the request body into s and validates it, opts configure the
+// decoding the same way as for BindJSON
+func ParseAndValidateRequestBody(ctx context.Context, v *validator.Validate, r *http.Request, s interface{}, opts ...BindOption) error {
 	_, span := otel.Tracer("internal/handler").Start(ctx, "ParseAndValidateRequestBody")
 	defer span.End()
 
@@ -24,20 +33,290 @@ func ParseAndValidateRequestBody(ctx context.Context, v *validator.Validate, r *
 	defer func() {
 		err := r.Body.Close()
 		if err != nil {
-			fmt.Println("Error closing request body:", err)
+			logutil.FromContext(ctx).Warn("Failed to close request body", zap.Error(err))
 		}
 	}()
 
-	err = json.Unmarshal(bodyBytes, s)
+	err = decodeJSON(bodyBytes, s, opts)
 	if err != nil {
 		span.RecordError(err)
-		return NewValidationErrorWithErrors("invalid JSON payload", []string{err.Error()})
+		return err
 	}
 
 	err = v.Struct(s)
 	if err != nil {
 		span.RecordError(err)
-		return err
+		return TranslateValidationErrors(err)
+	}
+
+	return nil
+}
+
+// BindOption customizes how BindJSON, ParseAndValidateRequestBody and Bind decode the request body
+type BindOption func(*bindConfig)
+
+type bindConfig struct {
+	disallowUnknownFields bool
+	useNumber             bool
+	limits                JSONLimits
+
+	// body and sources select the parts of the request Bind reads
+	body    BodyMode
+	sources []string
+}
+
+// BodyMode is how Bind handles the JSON body, see WithBody
+type BodyMode int
+
+const (
+	// BodyOptional decodes the body when there is one
+	BodyOptional BodyMode = iota
+	// BodyRequired reports a missing or empty body as a validation failure
+	BodyRequired
+	// BodyIgnored never reads the body, e.g. for GET and DELETE handlers
+	BodyIgnored
+)
+
+// WithBody sets how Bind handles the JSON body, BodyOptional by default
+func WithBody(mode BodyMode) BindOption {
+	return func(c *bindConfig) {
+		c.body = mode
+	}
+}
+
+// WithSources limits the tags Bind reads besides the body to sources, among "path", "query" and
+// "header", which are all read by default
+func WithSources(sources ...string) BindOption {
+	return func(c *bindConfig) {
+		c.sources = sources
+	}
+}
+
+// JSONLimits bounds the shape of a request body, so that pathological payloads are rejected
+// before they are decoded. A zero or negative value disables that limit. Bodies are not limited
+// unless WithJSONLimits or WithMaxDepth is given.
+type JSONLimits struct {
+	// MaxDepth is the deepest objects and arrays may nest
+	MaxDepth int
+
+	// MaxArrayLength is the number of elements a single array may have
+	MaxArrayLength int
+
+	// MaxStringLength is the length in bytes of a single string or object key, as sent
+	MaxStringLength int
+}
+
+// DefaultJSONLimits returns limits suited to most API bodies, to pass to WithJSONLimits
+func DefaultJSONLimits() JSONLimits {
+	return JSONLimits{
+		MaxDepth:        32,
+		MaxArrayLength:  10000,
+		MaxStringLength: 1 << 20,
+	}
+}
+
+// WithDisallowUnknownFields rejects bodies containing fields the target type does not declare, the
+// returned ValidationError names the unknown field
+func WithDisallowUnknownFields() BindOption {
+	return func(c *bindConfig) {
+		c.disallowUnknownFields = true
+	}
+}
+
+// WithUseNumber decodes numbers into interface{} values as json.Number instead of float64
+func WithUseNumber() BindOption {
+	return func(c *bindConfig) {
+		c.useNumber = true
+	}
+}
+
+// WithMaxDepth rejects bodies whose objects and arrays are nested deeper than depth
+func WithMaxDepth(depth int) BindOption {
+	return func(c *bindConfig) {
+		c.limits.MaxDepth = depth
+	}
+}
+
+// WithJSONLimits rejects bodies exceeding limits, e.g. WithJSONLimits(DefaultJSONLimits())
+func WithJSONLimits(limits JSONLimits) BindOption {
+	return func(c *bindConfig) {
+		c.limits = limits
+	}
+}
+
+// BindJSON decodes and validates the request body into a new T. Decoding and validation failures
+// are returned as ValidationError, validation failures list every failed field in Errors.
+func BindJSON[T any](ctx context.Context, v *validator.Validate, r *http.Request, opts ...BindOption) (T, error) {
+	_, span := otel.Tracer("internal/handler").Start(ctx, "BindJSON")
+	defer span.End()
+
+	var value T
+
+	defer func() {
+		err := r.Body.Close()
+		if err != nil {
+			logutil.FromContext(ctx).Warn("Failed to close request body", zap.Error(err))
+		}
+	}()
+
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		span.RecordError(err)
+		return value, err
+	}
+
+	err = decodeJSON(bodyBytes, &value, opts)
+	if err != nil {
+		span.RecordError(err)
+		return value, err
+	}
+
+	err = v.Struct(value)
+	if err != nil {
+		span.RecordError(err)
+		return value, TranslateValidationErrors(err)
+	}
+
+	return value, nil
+}
+
+// decodeJSON decodes data into dst following opts, every failure is returned as ValidationError.
+// Unknown fields and type mismatches name the offending JSON field in Field and Violations.
+func decodeJSON(data []byte, dst any, opts []BindOption) error {
+	var config bindConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	err := checkJSONLimits(data, config.limits)
+	if err != nil {
+		return NewValidationErrorWithErrors("invalid JSON payload", []string{err.Error()})
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if config.disallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if config.useNumber {
+		decoder.UseNumber()
+	}
+
+	err = decoder.Decode(dst)
+	if err == nil {
+		// More() stops at a closing bracket, so only a second Decode reaching EOF proves the body ends here
+		var trailing json.RawMessage
+		if decoder.Decode(&trailing) != io.EOF {
+			err = errors.New("unexpected data after the JSON value")
+		}
+	}
+	if err == nil {
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return newJSONFieldError(typeErr.Field, typeErr.Value, fmt.Sprintf("'%s' must be %s", typeErr.Field, jsonTypeName(typeErr.Type)))
+	}
+
+	// encoding/json has no typed error for unknown fields, only this message
+	if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		field, _ = strconv.Unquote(field)
+		return newJSONFieldError(field, nil, fmt.Sprintf("unknown field '%s'", field))
+	}
+
+	return NewValidationErrorWithErrors("invalid JSON payload", []string{err.Error()})
+}
+
+// newJSONFieldError builds the ValidationError of a decoding failure on field, a dotted path such as "items.0.name"
+func newJSONFieldError(field string, value interface{}, message string) ValidationError {
+	var pointer strings.Builder
+	for _, token := range strings.Split(field, ".") {
+		writePointerToken(&pointer, token)
+	}
+
+	validationErr := NewValidationError(field, value, "invalid JSON payload")
+	validationErr.Errors = []string{message}
+	validationErr.Violations = []FieldViolation{{Pointer: pointer.String(), Message: message}}
+	return validationErr
+}
+
+// jsonTypeName describes t with the JSON type a client has to send
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	default:
+		return "of type " + t.String()
+	}
+}
+
+// checkJSONLimits reports the first limit data exceeds, it only tracks brackets, commas and
+// strings, syntax errors are left to the decoder
+func checkJSONLimits(data []byte, limits JSONLimits) error {
+	// lengths holds the number of elements of every open array, -1 for open objects
+	var lengths []int
+	inString := false
+	escaped := false
+	stringStart := 0
+
+	for i, c := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+				if limits.MaxStringLength > 0 && i-stringStart-1 > limits.MaxStringLength {
+					return fmt.Errorf("JSON string exceeds the maximum length of %d bytes", limits.MaxStringLength)
+				}
+			}
+			continue
+		}
+
+		// The first value in an array is its first element, the following ones are counted by the commas
+		top := len(lengths) - 1
+		if top >= 0 && lengths[top] == 0 && c != ']' && c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			lengths[top] = 1
+		}
+
+		switch c {
+		case '"':
+			inString = true
+			stringStart = i
+		case '{', '[':
+			if limits.MaxDepth > 0 && len(lengths) >= limits.MaxDepth {
+				return fmt.Errorf("JSON nesting exceeds the maximum depth of %d", limits.MaxDepth)
+			}
+			if c == '[' {
+				lengths = append(lengths, 0)
+			} else {
+				lengths = append(lengths, -1)
+			}
+		case '}', ']':
+			if top >= 0 {
+				lengths = lengths[:top]
+			}
+		case ',':
+			if top >= 0 && lengths[top] > 0 {
+				lengths[top]++
+				if limits.MaxArrayLength > 0 && lengths[top] > limits.MaxArrayLength {
+					return fmt.Errorf("JSON array exceeds the maximum length of %d elements", limits.MaxArrayLength)
+				}
+			}
+		}
 	}
 
 	return nil
@@ -66,3 +345,33 @@ func ParseUUID(value string) (uuid.UUID, error) {
 	}
 	return parsedUUID, nil
 }
+
+// ParsePathUUID parses the named path value of r, errors wrap ErrInvalidUUID like ParseUUID
+func ParsePathUUID(r *http.Request, name string) (uuid.UUID, error) {
+	parsedUUID, err := ParseUUID(r.PathValue(name))
+	if err != nil {
+		return parsedUUID, fmt.Errorf("path parameter %s: %w", name, err)
+	}
+	return parsedUUID, nil
+}
+
+// ParseUUIDs parses every value and reports all malformed ones in a single ValidationError,
+// the returned slice is in the same order as values
+func ParseUUIDs(values []string) ([]uuid.UUID, error) {
+	parsed := make([]uuid.UUID, len(values))
+
+	var invalid []string
+	for i, value := range values {
+		parsedUUID, err := uuid.Parse(value)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("'%s' is not a valid UUID", value))
+			continue
+		}
+		parsed[i] = parsedUUID
+	}
+
+	if len(invalid) > 0 {
+		return nil, NewValidationErrorWithErrors("invalid UUID format", invalid)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,325 @@
+package handlerutil
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type bindTestRequest struct {
+	Name  string `json:"name" validate:"required"`
+	Email string `json:"email" validate:"required,email"`
+}
+
+func TestBindJSON(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		opts          []BindOption
+		want          bindTestRequest
+		wantErr       bool
+		wantErrorsLen int
+	}{
+		{
+			name: "Should bind valid body",
+			body: `{"name":"alice","email":"alice@example.com"}`,
+			want: bindTestRequest{Name: "alice", Email: "alice@example.com"},
+		},
+		{
+			name: "Should ignore unknown fields by default",
+			body: `{"name":"alice","email":"alice@example.com","role":"admin"}`,
+			want: bindTestRequest{Name: "alice", Email: "alice@example.com"},
+		},
+		{
+			name:          "Should reject unknown fields when disallowed",
+			body:          `{"name":"alice","email":"alice@example.com","role":"admin"}`,
+			opts:          []BindOption{WithDisallowUnknownFields()},
+			wantErr:       true,
+			wantErrorsLen: 1,
+		},
+		{
+			name:          "Should return validation error for invalid JSON",
+			body:          `{"name":`,
+			wantErr:       true,
+			wantErrorsLen: 1,
+		},
+		{
+			name:          "Should list every failed field",
+			body:          `{"email":"not-an-email"}`,
+			wantErr:       true,
+			wantErrorsLen: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.body))
+
+			got, err := BindJSON[bindTestRequest](context.Background(), validator.New(), r, tt.opts...)
+			if tt.wantErr {
+				var validationErr ValidationError
+				if !errors.As(err, &validationErr) {
+					t.Fatalf("BindJSON() error = %v, want ValidationError", err)
+				}
+				if len(validationErr.Errors) != tt.wantErrorsLen {
+					t.Errorf("BindJSON() errors = %v, want %d entries", validationErr.Errors, tt.wantErrorsLen)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("BindJSON() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("BindJSON() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+type bindPolicyTestRequest struct {
+	Name  string         `json:"name"`
+	Age   int            `json:"age"`
+	Extra map[string]any `json:"extra"`
+	Items []struct {
+		Count int `json:"count"`
+	} `json:"items"`
+}
+
+func TestBindJSONDecodingPolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		opts        []BindOption
+		wantErr     bool
+		wantField   string
+		wantPointer string
+	}{
+		{
+			name:        "Should name the unknown field",
+			body:        `{"name":"alice","nmae":"typo"}`,
+			opts:        []BindOption{WithDisallowUnknownFields()},
+			wantErr:     true,
+			wantField:   "nmae",
+			wantPointer: "/nmae",
+		},
+		{
+			name:        "Should name the field with a mismatched type",
+			body:        `{"name":"alice","age":"twenty"}`,
+			wantErr:     true,
+			wantField:   "age",
+			wantPointer: "/age",
+		},
+		{
+			name:        "Should name the nested field with a mismatched type",
+			body:        `{"items":[{"count":"one"}]}`,
+			wantErr:     true,
+			wantField:   "items.0.count",
+			wantPointer: "/items/0/count",
+		},
+		{
+			name:    "Should reject bodies nested deeper than the maximum depth",
+			body:    `{"extra":{"a":{"b":{}}}}`,
+			opts:    []BindOption{WithMaxDepth(3)},
+			wantErr: true,
+		},
+		{
+			name: "Should accept bodies within the maximum depth",
+			body: `{"extra":{"a":{"b":"{{{{"}}}`,
+			opts: []BindOption{WithMaxDepth(3)},
+		},
+		{
+			name:    "Should reject arrays longer than the maximum length",
+			body:    `{"items":[{"count":1},{"count":2},{"count":3}]}`,
+			opts:    []BindOption{WithJSONLimits(JSONLimits{MaxArrayLength: 2})},
+			wantErr: true,
+		},
+		{
+			name: "Should accept arrays within the maximum length",
+			body: `{"items":[{"count":1},{"count":2}],"extra":{"a":[],"b":["x,y"]}}`,
+			opts: []BindOption{WithJSONLimits(JSONLimits{MaxArrayLength: 2})},
+		},
+		{
+			name:    "Should reject strings longer than the maximum length",
+			body:    `{"name":"abcdef"}`,
+			opts:    []BindOption{WithJSONLimits(JSONLimits{MaxStringLength: 5})},
+			wantErr: true,
+		},
+		{
+			name:    "Should reject bodies nested deeper than DefaultJSONLimits",
+			body:    strings.Repeat(`{"extra":`, 40) + `{}` + strings.Repeat(`}`, 40),
+			opts:    []BindOption{WithJSONLimits(DefaultJSONLimits())},
+			wantErr: true,
+		},
+		{
+			name: "Should accept deeply nested bodies without limits",
+			body: strings.Repeat(`{"extra":`, 40) + `{}` + strings.Repeat(`}`, 40),
+		},
+		{
+			name: "Should accept long arrays and strings without limits",
+			body: `{"items":[` + strings.Repeat(`{"count":1},`, 20000) + `{"count":1}],"name":"` + strings.Repeat("a", 2<<20) + `"}`,
+		},
+		{
+			name:    "Should reject data after the JSON value",
+			body:    `{"name":"alice"} {"name":"bob"}`,
+			wantErr: true,
+		},
+		{
+			name:    "Should reject a closing bracket after the JSON value",
+			body:    `{"name":"alice"}]`,
+			wantErr: true,
+		},
+		{
+			name:    "Should reject a closing brace after the JSON value",
+			body:    `{"name":"alice"}}`,
+			wantErr: true,
+		},
+		{
+			name: "Should accept whitespace after the JSON value",
+			body: "{\"name\":\"alice\"}\n\t ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(tt.body))
+
+			_, err := BindJSON[bindPolicyTestRequest](context.Background(), validator.New(), r, tt.opts...)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("BindJSON() unexpected error: %v", err)
+				}
+				return
+			}
+
+			var validationErr ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("BindJSON() error = %v, want ValidationError", err)
+			}
+			if validationErr.Field != tt.wantField {
+				t.Errorf("BindJSON() field = %q, want %q", validationErr.Field, tt.wantField)
+			}
+			if tt.wantPointer != "" && (len(validationErr.Violations) != 1 || validationErr.Violations[0].Pointer != tt.wantPointer) {
+				t.Errorf("BindJSON() violations = %+v, want pointer %q", validationErr.Violations, tt.wantPointer)
+			}
+		})
+	}
+}
+
+func TestBindJSONUseNumber(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"extra":{"id":12345678901234567890}}`))
+
+	got, err := BindJSON[bindPolicyTestRequest](context.Background(), validator.New(), r, WithUseNumber())
+	if err != nil {
+		t.Fatalf("BindJSON() unexpected error: %v", err)
+	}
+	if n, ok := got.Extra["id"].(json.Number); !ok || n.String() != "12345678901234567890" {
+		t.Errorf("BindJSON() extra id = %#v, want json.Number", got.Extra["id"])
+	}
+}
+
+func TestBindJSON_LogsBodyCloseError(t *testing.T) {
+	tests := []struct {
+		name string
+		bind func(ctx context.Context, r *http.Request) error
+	}{
+		{
+			name: "Should log the close failure of BindJSON",
+			bind: func(ctx context.Context, r *http.Request) error {
+				_, err := BindJSON[bindTestRequest](ctx, validator.New(), r)
+				return err
+			},
+		},
+		{
+			name: "Should log the close failure of ParseAndValidateRequestBody",
+			bind: func(ctx context.Context, r *http.Request) error {
+				var request bindTestRequest
+				return ParseAndValidateRequestBody(ctx, validator.New(), r, &request)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			ctx := logutil.IntoContext(context.Background(), zap.New(core))
+
+			r := httptest.NewRequest(http.MethodPost, "/users", nil)
+			r.Body = closeErrorBody{Reader: strings.NewReader(`{"name":"alice","email":"alice@example.com"}`)}
+
+			err := tt.bind(ctx, r)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			entries := logs.FilterMessage("Failed to close request body").All()
+			if len(entries) != 1 {
+				t.Fatalf("got %d close failure entries, want 1", len(entries))
+			}
+			if got := entries[0].ContextMap()["error"]; got != "connection reset" {
+				t.Errorf("error = %v, want %q", got, "connection reset")
+			}
+		})
+	}
+}
+
+func TestParsePathUUID(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{name: "Should parse valid path UUID", path: "/users/0b9c3a34-8d1f-4a52-9d6e-2f1f4c8e7a10"},
+		{name: "Should reject malformed path UUID", path: "/users/not-a-uuid", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var err error
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+				_, err = ParsePathUUID(r, "id")
+			})
+			mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("ParsePathUUID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !errors.Is(err, ErrInvalidUUID) {
+				t.Errorf("ParsePathUUID() error = %v, want ErrInvalidUUID", err)
+			}
+		})
+	}
+}
+
+func TestParseUUIDs(t *testing.T) {
+	valid := "0b9c3a34-8d1f-4a52-9d6e-2f1f4c8e7a10"
+
+	ids, err := ParseUUIDs([]string{valid, valid})
+	if err != nil {
+		t.Fatalf("ParseUUIDs() unexpected error: %v", err)
+	}
+	if len(ids) != 2 || ids[0].String() != valid {
+		t.Errorf("ParseUUIDs() = %v, want two parsed UUIDs", ids)
+	}
+
+	_, err = ParseUUIDs([]string{"bad-1", valid, "bad-2"})
+	var validationErr ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("ParseUUIDs() error = %v, want ValidationError", err)
+	}
+	want := []string{"'bad-1' is not a valid UUID", "'bad-2' is not a valid UUID"}
+	if strings.Join(validationErr.Errors, "|") != strings.Join(want, "|") {
+		t.Errorf("ParseUUIDs() errors = %v, want %v", validationErr.Errors, want)
+	}
+}
@@ -0,0 +1,227 @@
+package handlerutil
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/NYCU-SDC/summer/pkg/pagination"
+)
+
+// ListQuerySpec is what a list endpoint accepts in its search, filter and sort query parameters
+type ListQuerySpec struct {
+	// Filters maps the filterable fields to their allowed operators, all of them when nil
+	Filters map[string][]pagination.FilterOp
+	// Sortable are the fields sort accepts
+	Sortable []string
+	// Searchable enables the search parameter
+	Searchable bool
+	// MaxFilters caps the number of filters, 10 when zero
+	MaxFilters int
+}
+
+// ParseListQuery parses the list query parameters shared by all SDC APIs:
+//
+//	search=alice                       full text search, when spec.Searchable
+//	filter[status]=active              equality, the operator defaults to eq
+//	filter[age]=gte:18&filter[age]=lt:65
+//	filter[role]=in:admin,editor       one of the comma separated values
+//	filter[name]=contains:ali          case-insensitive substring
+//	sort=createdAt:desc,name           fields in order, the direction defaults to asc
+//
+// The legacy sort=asc|desc&sortBy=field form is accepted too. Unknown fields and operators are
+// rejected with a ValidationError pointing at the parameter, so they map to a 400 problem.
+func ParseListQuery(r *http.Request, spec ListQuerySpec) (pagination.ListQuery, error) {
+	values := r.URL.Query()
+
+	var query pagination.ListQuery
+	if spec.Searchable {
+		query.Search = strings.TrimSpace(values.Get("search"))
+	}
+
+	filters, err := parseFilters(values, spec)
+	if err != nil {
+		return pagination.ListQuery{}, err
+	}
+	query.Filters = filters
+
+	sort, err := parseSort(values, spec.Sortable)
+	if err != nil {
+		return pagination.ListQuery{}, err
+	}
+	query.Sort = sort
+
+	return query, nil
+}
+
+// GetListRequest parses the pagination parameters with factory and the list query with spec into
+// one request. spec.Sortable defaults to the sortable columns of factory, and the first sort field
+// is also set as Sort and SortBy, so the request works with factory.Keyset.
+func GetListRequest[T any](r *http.Request, factory pagination.Factory[T], spec ListQuerySpec) (pagination.Request, error) {
+	if spec.Sortable == nil {
+		spec.Sortable = factory.SortableColumns
+	}
+
+	query, err := ParseListQuery(r, spec)
+	if err != nil {
+		return pagination.Request{}, err
+	}
+
+	// The sort parameters are parsed above, the factory only sees the paging ones
+	values := r.URL.Query()
+	values.Del("sort")
+	values.Del("sortBy")
+	pageURL := *r.URL
+	pageURL.RawQuery = values.Encode()
+	pageRequest := r.WithContext(r.Context())
+	pageRequest.URL = &pageURL
+
+	request, err := factory.GetRequest(pageRequest)
+	if err != nil {
+		return pagination.Request{}, err
+	}
+
+	request.Query = query
+	if len(query.Sort) > 0 {
+		request.SortBy = query.Sort[0].Field
+		request.Sort = "asc"
+		if query.Sort[0].Descending {
+			request.Sort = "desc"
+		}
+	}
+	return request, nil
+}
+
+func parseFilters(values url.Values, spec ListQuerySpec) ([]pagination.Filter, error) {
+	maxFilters := spec.MaxFilters
+	if maxFilters == 0 {
+		maxFilters = 10
+	}
+
+	// Sorted so the filters, and the placeholders built from them, do not depend on map order
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var filters []pagination.Filter
+	for _, key := range keys {
+		field, ok := strings.CutPrefix(key, "filter[")
+		if !ok {
+			continue
+		}
+		field, ok = strings.CutSuffix(field, "]")
+		if !ok || field == "" {
+			return nil, queryParameterError(key, "", "must be written filter[field]")
+		}
+
+		allowed, filterable := spec.Filters[field]
+		if spec.Filters != nil && !filterable {
+			return nil, queryParameterError(key, "", fmt.Sprintf("%s is not filterable", field))
+		}
+
+		for _, value := range values[key] {
+			filter := parseFilter(field, value)
+			if !slices.Contains(pagination.FilterOps, filter.Op) || (allowed != nil && !slices.Contains(allowed, filter.Op)) {
+				return nil, queryParameterError(key, value, fmt.Sprintf("operator %s is not allowed on %s", filter.Op, field))
+			}
+			if len(filter.Values) == 0 {
+				return nil, queryParameterError(key, value, "must have a value")
+			}
+			filters = append(filters, filter)
+		}
+	}
+
+	if len(filters) > maxFilters {
+		return nil, queryParameterError("filter", "", fmt.Sprintf("at most %d filters are allowed", maxFilters))
+	}
+	return filters, nil
+}
+
+// parseFilter splits op:value, a value without a known operator prefix is an equality on the whole
+// value, so values containing a colon such as times still work with the default operator
+func parseFilter(field, value string) pagination.Filter {
+	filter := pagination.Filter{Field: field, Op: pagination.OpEq}
+
+	if op, operand, ok := strings.Cut(value, ":"); ok && isFilterOpName(op) {
+		filter.Op = pagination.FilterOp(op)
+		value = operand
+	}
+
+	if filter.Op == pagination.OpIn {
+		for _, item := range strings.Split(value, ",") {
+			if item != "" {
+				filter.Values = append(filter.Values, item)
+			}
+		}
+		return filter
+	}
+	if value != "" {
+		filter.Values = []string{value}
+	}
+	return filter
+}
+
+// isFilterOpName reports whether op looks like an operator, so that an unknown one such as
+// "gtt:5" is rejected instead of being compared as a value
+func isFilterOpName(op string) bool {
+	if op == "" {
+		return false
+	}
+	for _, c := range op {
+		if c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func parseSort(values url.Values, sortable []string) ([]pagination.SortField, error) {
+	sort := values.Get("sort")
+	if sort == "" {
+		return nil, nil
+	}
+
+	// Legacy form, sort is only the direction of sortBy
+	if strings.EqualFold(sort, "asc") || strings.EqualFold(sort, "desc") {
+		sortBy := values.Get("sortBy")
+		if !slices.Contains(sortable, sortBy) {
+			return nil, queryParameterError("sortBy", sortBy, fmt.Sprintf("must be one of %s", strings.Join(sortable, ", ")))
+		}
+		return []pagination.SortField{{Field: sortBy, Descending: strings.EqualFold(sort, "desc")}}, nil
+	}
+
+	var fields []pagination.SortField
+	for _, term := range strings.Split(sort, ",") {
+		name, direction, _ := strings.Cut(strings.TrimSpace(term), ":")
+
+		if !slices.Contains(sortable, name) {
+			return nil, queryParameterError("sort", sort, fmt.Sprintf("%q is not sortable, must be one of %s", name, strings.Join(sortable, ", ")))
+		}
+		if slices.ContainsFunc(fields, func(field pagination.SortField) bool { return field.Field == name }) {
+			return nil, queryParameterError("sort", sort, fmt.Sprintf("%q is sorted twice", name))
+		}
+
+		switch strings.ToLower(direction) {
+		case "", "asc":
+			fields = append(fields, pagination.SortField{Field: name})
+		case "desc":
+			fields = append(fields, pagination.SortField{Field: name, Descending: true})
+		default:
+			return nil, queryParameterError("sort", sort, fmt.Sprintf("direction of %q must be asc or desc", name))
+		}
+	}
+	return fields, nil
+}
+
+func queryParameterError(parameter, value, message string) ValidationError {
+	return ValidationError{
+		Field:      parameter,
+		Value:      value,
+		Message:    "invalid " + parameter + ": " + message,
+		Violations: []FieldViolation{{Parameter: parameter, Message: message}},
+	}
+}
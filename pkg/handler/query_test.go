@@ -0,0 +1,131 @@
+package handlerutil
+
+import (
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/NYCU-SDC/summer/pkg/pagination"
+)
+
+func TestParseListQuery(t *testing.T) {
+	spec := ListQuerySpec{
+		Filters: map[string][]pagination.FilterOp{
+			"status":    {pagination.OpEq, pagination.OpIn},
+			"createdAt": nil,
+		},
+		Sortable:   []string{"name", "createdAt"},
+		Searchable: true,
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		want      pagination.ListQuery
+		wantError string
+	}{
+		{
+			name:  "Should parse search, filters and sort",
+			query: "search=+alice+&filter[status]=in:active,pending&filter[createdAt]=gte:2024-01-01T00:00:00Z&sort=createdAt:desc,name",
+			want: pagination.ListQuery{
+				Search: "alice",
+				Filters: []pagination.Filter{
+					{Field: "createdAt", Op: pagination.OpGte, Values: []string{"2024-01-01T00:00:00Z"}},
+					{Field: "status", Op: pagination.OpIn, Values: []string{"active", "pending"}},
+				},
+				Sort: []pagination.SortField{{Field: "createdAt", Descending: true}, {Field: "name"}},
+			},
+		},
+		{
+			name:  "Should default to equality",
+			query: "filter[status]=active",
+			want: pagination.ListQuery{
+				Filters: []pagination.Filter{{Field: "status", Op: pagination.OpEq, Values: []string{"active"}}},
+			},
+		},
+		{
+			name:  "Should accept legacy sort",
+			query: "sort=desc&sortBy=name",
+			want:  pagination.ListQuery{Sort: []pagination.SortField{{Field: "name", Descending: true}}},
+		},
+		{
+			name:      "Should reject unknown filter field",
+			query:     "filter[password]=x",
+			wantError: "filter[password]",
+		},
+		{
+			name:      "Should reject operator not allowed on field",
+			query:     "filter[status]=contains:act",
+			wantError: "filter[status]",
+		},
+		{
+			name:      "Should reject unknown operator",
+			query:     "filter[createdAt]=gtt:5",
+			wantError: "filter[createdAt]",
+		},
+		{
+			name:      "Should reject unsortable field",
+			query:     "sort=email:asc",
+			wantError: "sort",
+		},
+		{
+			name:      "Should reject invalid direction",
+			query:     "sort=name:up",
+			wantError: "sort",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/users?"+tt.query, nil)
+
+			got, err := ParseListQuery(r, spec)
+			if tt.wantError != "" {
+				var validationError ValidationError
+				if !errors.As(err, &validationError) {
+					t.Fatalf("ParseListQuery() error = %v, want ValidationError", err)
+				}
+				if len(validationError.Violations) != 1 || validationError.Violations[0].Parameter != tt.wantError {
+					t.Errorf("ParseListQuery() violations = %+v, want parameter %q", validationError.Violations, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseListQuery() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseListQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetListRequest(t *testing.T) {
+	factory := pagination.NewFactory[string](50, []string{"name", "createdAt"})
+	r := httptest.NewRequest("GET", "/users?page=2&size=20&sort=createdAt:desc&filter[name]=contains:al", nil)
+
+	got, err := GetListRequest(r, factory, ListQuerySpec{})
+	if err != nil {
+		t.Fatalf("GetListRequest() unexpected error: %v", err)
+	}
+	if got.Page != 2 || got.Size != 20 || got.SortBy != "createdAt" || got.Sort != "desc" {
+		t.Errorf("GetListRequest() = %+v, want page 2, size 20, sorted by createdAt desc", got)
+	}
+
+	where, err := got.Query.Where(map[string]string{"name": "u.name"}, nil, 1)
+	if err != nil {
+		t.Fatalf("Where() unexpected error: %v", err)
+	}
+	if where.Predicate != "u.name ILIKE $2" || !reflect.DeepEqual(where.Args, []any{"%al%"}) {
+		t.Errorf("Where() = %+v, want u.name ILIKE $2 with %%al%%", where)
+	}
+
+	orderBy, err := got.Query.OrderBy(map[string]string{"createdAt": "u.created_at"}, "u.id")
+	if err != nil {
+		t.Fatalf("OrderBy() unexpected error: %v", err)
+	}
+	if orderBy != "u.created_at DESC, u.id DESC" {
+		t.Errorf("OrderBy() = %q, want u.created_at DESC, u.id DESC", orderBy)
+	}
+}
@@ -0,0 +1,125 @@
+package handlerutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultSSEHeartbeat keeps idle connections from being closed by proxies, most of which time out after 30 to 60 seconds
+const DefaultSSEHeartbeat = 15 * time.Second
+
+// SSEEvent is a single Server-Sent Event. Data is written as is when it is a string or []byte
+// and encoded as JSON otherwise, multi-line data is split into several data fields.
+type SSEEvent struct {
+	ID    string
+	Event string
+	Data  any
+
+	// Retry tells the browser how long to wait before reconnecting, zero leaves it unchanged
+	Retry time.Duration
+}
+
+// LastEventID returns the ID of the last event a reconnecting client received, so the
+// stream can resume from there. It is empty on the first connection.
+func LastEventID(r *http.Request) string {
+	return r.Header.Get("Last-Event-ID")
+}
+
+// StreamSSE writes the events received from the channel as a text/event-stream until the channel
+// is closed or the client disconnects. A comment is sent every heartbeat interval while no event
+// is ready, a zero interval uses DefaultSSEHeartbeat.
+//
+// It returns nil when the channel is closed and the request context error when the client went
+// away, producers should watch the same context to stop sending.
+func StreamSSE(w http.ResponseWriter, r *http.Request, events <-chan SSEEvent, heartbeat time.Duration) error {
+	if heartbeat <= 0 {
+		heartbeat = DefaultSSEHeartbeat
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// Stops nginx from buffering the stream
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	controller := http.NewResponseController(w)
+	err := controller.Flush()
+	if err != nil {
+		return fmt.Errorf("server-sent events need a flushable response writer: %w", err)
+	}
+
+	ticker := time.NewTicker(heartbeat)
+	defer ticker.Stop()
+
+	ctx := r.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			err = writeSSEEvent(w, event)
+			ticker.Reset(heartbeat)
+		}
+		if err != nil {
+			return err
+		}
+
+		err = controller.Flush()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func writeSSEEvent(w http.ResponseWriter, event SSEEvent) error {
+	var data string
+	switch d := event.Data.(type) {
+	case nil:
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		encoded, err := json.Marshal(d)
+		if err != nil {
+			return fmt.Errorf("failed to marshal event data: %w", err)
+		}
+		data = string(encoded)
+	}
+
+	var b strings.Builder
+	if event.ID != "" {
+		b.WriteString("id: " + sseFieldValue(event.ID) + "\n")
+	}
+	if event.Event != "" {
+		b.WriteString("event: " + sseFieldValue(event.Event) + "\n")
+	}
+	if event.Retry > 0 {
+		b.WriteString(fmt.Sprintf("retry: %d\n", event.Retry.Milliseconds()))
+	}
+	// Every line break the spec recognizes starts a new data line, a bare \r would otherwise start a new field
+	for _, line := range strings.Split(sseLineBreaks.Replace(data), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+
+	_, err := w.Write([]byte(b.String()))
+	return err
+}
+
+var sseLineBreaks = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// sseFieldValue strips line breaks, which would otherwise end the field and let the value inject other fields
+func sseFieldValue(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
@@ -0,0 +1,85 @@
+package handlerutil
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamSSE(t *testing.T) {
+	events := make(chan SSEEvent, 3)
+	events <- SSEEvent{ID: "1", Event: "created", Data: map[string]int{"id": 7}}
+	events <- SSEEvent{Data: "line one\nline two", Retry: 3 * time.Second}
+	events <- SSEEvent{ID: "2\nevent: spoofed", Data: "x"}
+	close(events)
+
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	w := httptest.NewRecorder()
+
+	err := StreamSSE(w, r, events, time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := w.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Errorf("expected Content-Type text/event-stream, got %q", got)
+	}
+
+	want := "id: 1\nevent: created\ndata: {\"id\":7}\n\n" +
+		"retry: 3000\ndata: line one\ndata: line two\n\n" +
+		"id: 2event: spoofed\ndata: x\n\n"
+	if w.Body.String() != want {
+		t.Errorf("expected body %q, got %q", want, w.Body.String())
+	}
+}
+
+func TestStreamSSE_Heartbeat(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	r := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	err := StreamSSE(w, r, make(chan SSEEvent), 10*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if !strings.Contains(w.Body.String(), ": heartbeat\n\n") {
+		t.Errorf("expected a heartbeat comment, got %q", w.Body.String())
+	}
+}
+
+func TestStreamSSE_DataLineBreaks(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "Should split data on LF", data: "x\nevent: admin", want: "data: x\ndata: event: admin\n\n"},
+		{name: "Should split data on CRLF", data: "x\r\nevent: admin", want: "data: x\ndata: event: admin\n\n"},
+		{name: "Should split data on a bare CR", data: "x\revent: admin", want: "data: x\ndata: event: admin\n\n"},
+		{name: "Should split data on mixed line breaks", data: "a\rb\r\nc\nd", want: "data: a\ndata: b\ndata: c\ndata: d\n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := make(chan SSEEvent, 1)
+			events <- SSEEvent{Data: tt.data}
+			close(events)
+
+			w := httptest.NewRecorder()
+			err := StreamSSE(w, httptest.NewRequest(http.MethodGet, "/events", nil), events, time.Minute)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if w.Body.String() != tt.want {
+				t.Errorf("expected body %q, got %q", tt.want, w.Body.String())
+			}
+		})
+	}
+}
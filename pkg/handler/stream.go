@@ -0,0 +1,190 @@
+package handlerutil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultCompressionThreshold is the response size below which compression is skipped,
+// compressing small payloads costs more CPU than it saves bandwidth
+const DefaultCompressionThreshold = 1024
+
+type streamConfig struct {
+	threshold int
+	compress  bool
+}
+
+// StreamOption configures WriteJSONStream
+type StreamOption func(*streamConfig)
+
+// WithCompressionThreshold sets the size in bytes a response must reach before it is compressed
+func WithCompressionThreshold(threshold int) StreamOption {
+	return func(c *streamConfig) {
+		c.threshold = threshold
+	}
+}
+
+// WithoutCompression always writes the response uncompressed
+func WithoutCompression() StreamOption {
+	return func(c *streamConfig) {
+		c.compress = false
+	}
+}
+
+// WriteJSONStream encodes data directly into the response with a json.Encoder instead of
+// marshaling it into memory first. When the client accepts gzip or deflate and the response
+// reaches the compression threshold it is compressed on the fly, smaller responses are sent
+// as is with a Content-Length.
+//
+// An encoding error before anything was sent results in a 500 response, once the response
+// has started it can only be cut short, in both cases the error is returned for logging.
+func WriteJSONStream(w http.ResponseWriter, r *http.Request, status int, data interface{}, opts ...StreamOption) error {
+	config := streamConfig{threshold: DefaultCompressionThreshold, compress: true}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	encoding := ""
+	if config.compress {
+		encoding = negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		w.Header().Add("Vary", "Accept-Encoding")
+	}
+
+	writer := &thresholdWriter{
+		w:         w,
+		status:    status,
+		encoding:  encoding,
+		threshold: config.threshold,
+	}
+
+	err := json.NewEncoder(writer).Encode(data)
+	if err != nil {
+		if !writer.started {
+			w.Header().Del("Vary")
+			http.Error(w, "Failed to marshal response", http.StatusInternalServerError)
+		}
+		return err
+	}
+
+	return writer.Close()
+}
+
+// thresholdWriter buffers the response until it either completes or grows past the threshold,
+// and only then decides whether to compress it
+type thresholdWriter struct {
+	w         http.ResponseWriter
+	status    int
+	encoding  string
+	threshold int
+
+	buf        bytes.Buffer
+	started    bool
+	compressor io.WriteCloser
+}
+
+func (t *thresholdWriter) Write(p []byte) (int, error) {
+	if t.started {
+		return t.out().Write(p)
+	}
+
+	t.buf.Write(p)
+	if t.encoding == "" || t.buf.Len() < t.threshold {
+		return len(p), nil
+	}
+
+	err := t.start()
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// start sends the headers with compression enabled and flushes the buffered bytes through the compressor
+func (t *thresholdWriter) start() error {
+	t.started = true
+
+	header := t.w.Header()
+	header.Set("Content-Type", "application/json")
+	header.Set("Content-Encoding", t.encoding)
+	header.Del("Content-Length")
+	t.w.WriteHeader(t.status)
+
+	switch t.encoding {
+	case "gzip":
+		t.compressor = gzip.NewWriter(t.w)
+	case "deflate":
+		t.compressor = zlib.NewWriter(t.w)
+	}
+
+	_, err := t.compressor.Write(t.buf.Bytes())
+	t.buf.Reset()
+	return err
+}
+
+func (t *thresholdWriter) out() io.Writer {
+	if t.compressor != nil {
+		return t.compressor
+	}
+	return t.w
+}
+
+// Close writes a response that stayed below the threshold, or finishes the compressed stream
+func (t *thresholdWriter) Close() error {
+	if t.started {
+		return t.compressor.Close()
+	}
+
+	t.started = true
+	header := t.w.Header()
+	header.Set("Content-Type", "application/json")
+	header.Set("Content-Length", strconv.Itoa(t.buf.Len()))
+	t.w.WriteHeader(t.status)
+
+	_, err := t.w.Write(t.buf.Bytes())
+	return err
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header, preferring the highest
+// q-value and gzip on ties, and returns an empty string when neither is acceptable
+func negotiateEncoding(acceptEncoding string) string {
+	best, bestQ := "", 0.0
+	wildcardQ := -1.0
+	explicit := map[string]bool{}
+
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(strings.TrimSpace(key), "q") {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err == nil {
+					q = parsed
+				}
+			}
+		}
+
+		switch name {
+		case "*":
+			wildcardQ = q
+		case "gzip", "deflate":
+			explicit[name] = true
+			if q > 0 && (q > bestQ || (q == bestQ && name == "gzip")) {
+				best, bestQ = name, q
+			}
+		}
+	}
+
+	if best == "" && wildcardQ > 0 && !explicit["gzip"] {
+		return "gzip"
+	}
+	return best
+}
@@ -0,0 +1,123 @@
+package handlerutil
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteJSONStream(t *testing.T) {
+	large := map[string]string{"data": strings.Repeat("a", 4096)}
+	small := map[string]string{"data": "a"}
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		data           map[string]string
+		opts           []StreamOption
+		wantEncoding   string
+	}{
+		{
+			name:           "Should gzip large response",
+			acceptEncoding: "gzip, deflate",
+			data:           large,
+			wantEncoding:   "gzip",
+		},
+		{
+			name:           "Should deflate when preferred",
+			acceptEncoding: "gzip;q=0.5, deflate",
+			data:           large,
+			wantEncoding:   "deflate",
+		},
+		{
+			name:           "Should skip compression below threshold",
+			acceptEncoding: "gzip",
+			data:           small,
+		},
+		{
+			name: "Should skip compression when not accepted",
+			data: large,
+		},
+		{
+			name:           "Should skip compression when disabled",
+			acceptEncoding: "gzip",
+			data:           large,
+			opts:           []StreamOption{WithoutCompression()},
+		},
+		{
+			name:           "Should respect custom threshold",
+			acceptEncoding: "gzip",
+			data:           small,
+			opts:           []StreamOption{WithCompressionThreshold(1)},
+			wantEncoding:   "gzip",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+
+			err := WriteJSONStream(w, r, http.StatusCreated, tt.data, tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if w.Code != http.StatusCreated {
+				t.Errorf("expected status %d, got %d", http.StatusCreated, w.Code)
+			}
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("expected Content-Encoding %q, got %q", tt.wantEncoding, got)
+			}
+
+			var body io.Reader = w.Body
+			switch tt.wantEncoding {
+			case "gzip":
+				body, err = gzip.NewReader(w.Body)
+			case "deflate":
+				body, err = zlib.NewReader(w.Body)
+			}
+			if err != nil {
+				t.Fatalf("failed to open compressed body: %v", err)
+			}
+
+			var got map[string]string
+			err = json.NewDecoder(body).Decode(&got)
+			if err != nil {
+				t.Fatalf("failed to decode body: %v", err)
+			}
+			if got["data"] != tt.data["data"] {
+				t.Errorf("body does not round trip")
+			}
+		})
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{header: "", want: ""},
+		{header: "br", want: ""},
+		{header: "deflate, gzip", want: "gzip"},
+		{header: "gzip;q=0, deflate", want: "deflate"},
+		{header: "gzip;q=0", want: ""},
+		{header: "*", want: "gzip"},
+		{header: "gzip;q=0, *", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package handlerutil
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ValidationMessageFunc builds the message for a failed validation rule, field is the display name of the field
+type ValidationMessageFunc func(field string, fe validator.FieldError) string
+
+var (
+	validationMessagesMu sync.RWMutex
+	validationMessages   = map[string]ValidationMessageFunc{
+		"required": func(field string, fe validator.FieldError) string {
+			return fmt.Sprintf("%s is required", field)
+		},
+		"email": func(field string, fe validator.FieldError) string {
+			return fmt.Sprintf("%s must be a valid email address", field)
+		},
+		"url": func(field string, fe validator.FieldError) string {
+			return fmt.Sprintf("%s must be a valid URL", field)
+		},
+		"uuid": func(field string, fe validator.FieldError) string {
+			return fmt.Sprintf("%s must be a valid UUID", field)
+		},
+		"min": func(field string, fe validator.FieldError) string {
+			return fmt.Sprintf("%s must be at least %s%s", field, fe.Param(), lengthUnit(fe))
+		},
+		"max": func(field string, fe validator.FieldError) string {
+			return fmt.Sprintf("%s must be at most %s%s", field, fe.Param(), lengthUnit(fe))
+		},
+		"len": func(field string, fe validator.FieldError) string {
+			return fmt.Sprintf("%s must be exactly %s%s", field, fe.Param(), lengthUnit(fe))
+		},
+		"gte": func(field string, fe validator.FieldError) string {
+			return fmt.Sprintf("%s must be greater than or equal to %s", field, fe.Param())
+		},
+		"gt": func(field string, fe validator.FieldError) string {
+			return fmt.Sprintf("%s must be greater than %s", field, fe.Param())
+		},
+		"lte": func(field string, fe validator.FieldError) string {
+			return fmt.Sprintf("%s must be less than or equal to %s", field, fe.Param())
+		},
+		"lt": func(field string, fe validator.FieldError) string {
+			return fmt.Sprintf("%s must be less than %s", field, fe.Param())
+		},
+		"oneof": func(field string, fe validator.FieldError) string {
+			return fmt.Sprintf("%s must be one of: %s", field, strings.Join(strings.Fields(fe.Param()), ", "))
+		},
+		"numeric": func(field string, fe validator.FieldError) string {
+			return fmt.Sprintf("%s must be numeric", field)
+		},
+		"alphanum": func(field string, fe validator.FieldError) string {
+			return fmt.Sprintf("%s must contain only letters and digits", field)
+		},
+		"datetime": func(field string, fe validator.FieldError) string {
+			return fmt.Sprintf("%s must be a date time in the format %s", field, fe.Param())
+		},
+	}
+)
+
+// RegisterValidationMessage sets the message used for a validation tag, including custom tags
+// registered on the validator. It is meant to be called once at startup.
+func RegisterValidationMessage(tag string, fn ValidationMessageFunc) {
+	validationMessagesMu.Lock()
+	defer validationMessagesMu.Unlock()
+
+	validationMessages[tag] = fn
+}
+
+// TranslateValidationErrors converts validator.ValidationErrors into a ValidationError listing one
+// human-readable message per failed field, other errors are returned unchanged
+func TranslateValidationErrors(err error) error {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	validationMessagesMu.RLock()
+	defer validationMessagesMu.RUnlock()
+
+	messages := make([]string, 0, len(validationErrors))
+	violations := make([]FieldViolation, 0, len(validationErrors))
+	for _, fe := range validationErrors {
+		message := validationMessage(displayFieldName(fe.Field()), fe)
+		messages = append(messages, message)
+		violations = append(violations, FieldViolation{
+			Pointer: fieldPointer(fe.Namespace()),
+			Message: message,
+		})
+	}
+
+	validationErr := NewValidationErrorWithErrors("validation failed", messages)
+	validationErr.Violations = violations
+	return validationErr
+}
+
+// validationMessage builds the message of fe for the display name field, validationMessagesMu must be held
+func validationMessage(field string, fe validator.FieldError) string {
+	fn, ok := validationMessages[fe.Tag()]
+	if ok {
+		return fn(field, fe)
+	}
+	return fmt.Sprintf("%s failed on the '%s' rule", field, fe.Tag())
+}
+
+// fieldPointer converts a validator namespace such as "Request.Items[2].Email" into the JSON Pointer
+// "/items/2/email". The root struct name is dropped and field names get the same first letter
+// lowercasing as the messages, so json names registered with RegisterTagNameFunc are kept as is.
+func fieldPointer(namespace string) string {
+	_, path, ok := strings.Cut(namespace, ".")
+	if !ok {
+		return ""
+	}
+
+	var pointer strings.Builder
+	for _, segment := range strings.Split(path, ".") {
+		name, rest, _ := strings.Cut(segment, "[")
+		writePointerToken(&pointer, displayFieldName(name))
+
+		// Slice indexes and map keys, possibly nested as in Matrix[1][2]
+		for rest != "" {
+			key, after, found := strings.Cut(rest, "]")
+			writePointerToken(&pointer, key)
+			if !found {
+				break
+			}
+			rest = strings.TrimPrefix(after, "[")
+		}
+	}
+	return pointer.String()
+}
+
+func writePointerToken(pointer *strings.Builder, token string) {
+	pointer.WriteByte('/')
+	pointer.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+}
+
+// displayFieldName lowercases the first letter of a Go field name, json names registered
+// with RegisterTagNameFunc are usually lowercase already and are left untouched
+func displayFieldName(field string) string {
+	r, size := utf8.DecodeRuneInString(field)
+	if r == utf8.RuneError {
+		return field
+	}
+	return string(unicode.ToLower(r)) + field[size:]
+}
+
+// lengthUnit returns the unit of min, max and len, which compare the length of strings and collections
+func lengthUnit(fe validator.FieldError) string {
+	switch fe.Kind() {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " items"
+	default:
+		return ""
+	}
+}
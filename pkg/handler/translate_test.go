@@ -0,0 +1,112 @@
+package handlerutil
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type translateTestRequest struct {
+	Email    string   `validate:"required,email"`
+	Password string   `validate:"min=8"`
+	Role     string   `validate:"oneof=admin member"`
+	Tags     []string `validate:"max=2"`
+	Nickname string   `validate:"nickname"`
+}
+
+func TestTranslateValidationErrors(t *testing.T) {
+	v := validator.New()
+	_ = v.RegisterValidation("nickname", func(fl validator.FieldLevel) bool {
+		return fl.Field().String() != "admin"
+	})
+	RegisterValidationMessage("nickname", func(field string, fe validator.FieldError) string {
+		return field + " is reserved"
+	})
+
+	err := v.Struct(translateTestRequest{
+		Email:    "not-an-email",
+		Password: "short",
+		Role:     "owner",
+		Tags:     []string{"a", "b", "c"},
+		Nickname: "admin",
+	})
+
+	translated := TranslateValidationErrors(err)
+
+	var validationErr ValidationError
+	if !errors.As(translated, &validationErr) {
+		t.Fatalf("TranslateValidationErrors() = %v, want ValidationError", translated)
+	}
+
+	want := []string{
+		"email must be a valid email address",
+		"password must be at least 8 characters",
+		"role must be one of: admin, member",
+		"tags must be at most 2 items",
+		"nickname is reserved",
+	}
+	if !reflect.DeepEqual(validationErr.Errors, want) {
+		t.Errorf("TranslateValidationErrors().Errors = %v, want %v", validationErr.Errors, want)
+	}
+}
+
+func TestTranslateValidationErrors_OtherError(t *testing.T) {
+	err := errors.New("boom")
+	if got := TranslateValidationErrors(err); got != err {
+		t.Errorf("TranslateValidationErrors() = %v, want the original error", got)
+	}
+}
+
+type pointerTestItem struct {
+	Email string `validate:"required,email"`
+}
+
+type pointerTestRequest struct {
+	Name  string                     `validate:"required"`
+	Items []pointerTestItem          `validate:"dive"`
+	Attrs map[string]pointerTestItem `validate:"dive"`
+}
+
+func TestTranslateValidationErrors_Violations(t *testing.T) {
+	v := validator.New()
+
+	err := v.Struct(pointerTestRequest{
+		Items: []pointerTestItem{{Email: "a@example.com"}, {Email: "a@example.com"}, {Email: "broken"}},
+		Attrs: map[string]pointerTestItem{"a/b": {}},
+	})
+
+	var validationErr ValidationError
+	if !errors.As(TranslateValidationErrors(err), &validationErr) {
+		t.Fatalf("TranslateValidationErrors() did not return a ValidationError")
+	}
+
+	want := []FieldViolation{
+		{Pointer: "/name", Message: "name is required"},
+		{Pointer: "/items/2/email", Message: "email must be a valid email address"},
+		{Pointer: "/attrs/a~1b/email", Message: "email is required"},
+	}
+	if !reflect.DeepEqual(validationErr.Violations, want) {
+		t.Errorf("TranslateValidationErrors().Violations = %v, want %v", validationErr.Violations, want)
+	}
+}
+
+func TestFieldPointer(t *testing.T) {
+	tests := []struct {
+		namespace string
+		want      string
+	}{
+		{namespace: "Request.Email", want: "/email"},
+		{namespace: "Request.Items[2].Email", want: "/items/2/email"},
+		{namespace: "Request.Matrix[1][0]", want: "/matrix/1/0"},
+		{namespace: "Request.user.first_name", want: "/user/first_name"},
+		{namespace: "Request", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := fieldPointer(tt.namespace); got != tt.want {
+			t.Errorf("fieldPointer(%q) = %q, want %q", tt.namespace, got, tt.want)
+		}
+	}
+}
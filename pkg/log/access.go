@@ -0,0 +1,106 @@
+package logutil
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// AccessLogMiddleware logs one "HTTP access" entry per request with its method, route, status,
+// response bytes, duration, remote IP, user agent and trace ID. The route is the ServeMux pattern,
+// e.g. "GET /api/users/{id}", or the path when the request did not go through a pattern.
+//
+// Append it after traceutil.TraceMiddleware so that the request span is in the context:
+//
+//	basicMiddleware := middleware.NewSet(traceMiddleware.RecoverMiddleware)
+//	basicMiddleware = basicMiddleware.Append(traceMiddleware.TraceMiddleware)
+//	basicMiddleware = basicMiddleware.Append(func(next http.HandlerFunc) http.HandlerFunc {
+//		return logutil.AccessLogMiddleware(next, logger)
+//	})
+func AccessLogMiddleware(next http.HandlerFunc, logger *zap.Logger) http.HandlerFunc {
+	logger = logger.Named("access")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		writer := &accessLogWriter{ResponseWriter: w}
+
+		next(writer, r)
+
+		status := writer.status
+		if status == 0 {
+			// Nothing written, net/http answers 200 with an empty body
+			status = http.StatusOK
+		}
+
+		route := r.Pattern
+		if route == "" {
+			route = r.URL.Path
+		}
+
+		fields := []zap.Field{
+			zap.String("method", r.Method),
+			zap.String("route", route),
+			zap.Int("status", status),
+			zap.Int64("bytes", writer.bytes),
+			zap.Duration("duration", time.Since(start)),
+			zap.String("remote_ip", remoteIP(r)),
+			zap.String("user_agent", r.UserAgent()),
+		}
+		if spanCtx := trace.SpanContextFromContext(r.Context()); spanCtx.HasTraceID() {
+			fields = append(fields, zap.String("trace_id", spanCtx.TraceID().String()))
+		}
+
+		logger.Info("HTTP access", fields...)
+	}
+}
+
+// accessLogWriter records the status and the size of the response body
+type accessLogWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *accessLogWriter) WriteHeader(code int) {
+	// Informational responses are followed by the final one
+	if w.status == 0 && code >= 200 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *accessLogWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+func (w *accessLogWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *accessLogWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// remoteIP is the host of the connection peer, forwarded headers are not trusted since they can
+// be set by any client
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
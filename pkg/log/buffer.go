@@ -0,0 +1,131 @@
+package logutil
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type entryBufferContextKey struct{}
+
+// BufferedEntry is a log entry kept by an EntryBuffer, Fields holds the encoded fields of the entry
+// and of the logger that wrote it
+type BufferedEntry struct {
+	Time    time.Time
+	Level   zapcore.Level
+	Message string
+	Fields  map[string]any
+}
+
+// EntryBuffer keeps the last entries written at or above its level, it is safe for concurrent use
+type EntryBuffer struct {
+	level zapcore.Level
+
+	mu      sync.Mutex
+	entries []BufferedEntry
+	next    int
+	full    bool
+}
+
+// NewEntryBuffer creates a buffer keeping the last size entries at or above level
+func NewEntryBuffer(size int, level zapcore.Level) *EntryBuffer {
+	if size < 1 {
+		size = 1
+	}
+	return &EntryBuffer{level: level, entries: make([]BufferedEntry, size)}
+}
+
+// WithEntryBuffer returns a context carrying buffer, the loggers returned by WithContext for it
+// write a copy of their entries to buffer
+func WithEntryBuffer(ctx context.Context, buffer *EntryBuffer) context.Context {
+	return context.WithValue(ctx, entryBufferContextKey{}, buffer)
+}
+
+// EntryBufferFromContext returns the buffer of ctx, or nil when there is none
+func EntryBufferFromContext(ctx context.Context) *EntryBuffer {
+	buffer, _ := ctx.Value(entryBufferContextKey{}).(*EntryBuffer)
+	return buffer
+}
+
+// Core returns a core writing to the buffer, to be teed with the core of a logger
+func (b *EntryBuffer) Core() zapcore.Core {
+	return &bufferCore{buffer: b}
+}
+
+// Entries returns the kept entries, oldest first
+func (b *EntryBuffer) Entries() []BufferedEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.full {
+		return append([]BufferedEntry(nil), b.entries[:b.next]...)
+	}
+
+	entries := make([]BufferedEntry, 0, len(b.entries))
+	entries = append(entries, b.entries[b.next:]...)
+	return append(entries, b.entries[:b.next]...)
+}
+
+func (b *EntryBuffer) add(entry BufferedEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries[b.next] = entry
+	b.next++
+	if b.next == len(b.entries) {
+		b.next = 0
+		b.full = true
+	}
+}
+
+// teeEntryBuffer returns logger also writing to buffer
+func teeEntryBuffer(logger *zap.Logger, buffer *EntryBuffer) *zap.Logger {
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, buffer.Core())
+	}))
+}
+
+type bufferCore struct {
+	buffer *EntryBuffer
+	fields []zapcore.Field
+}
+
+func (c *bufferCore) Enabled(level zapcore.Level) bool {
+	return level >= c.buffer.level
+}
+
+func (c *bufferCore) With(fields []zapcore.Field) zapcore.Core {
+	return &bufferCore{buffer: c.buffer, fields: append(append([]zapcore.Field(nil), c.fields...), fields...)}
+}
+
+func (c *bufferCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *bufferCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, field := range c.fields {
+		field.AddTo(encoder)
+	}
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+
+	c.buffer.add(BufferedEntry{
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Message: entry.Message,
+		Fields:  encoder.Fields,
+	})
+	return nil
+}
+
+func (c *bufferCore) Sync() error {
+	return nil
+}
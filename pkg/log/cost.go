@@ -0,0 +1,31 @@
+package logutil
+
+import (
+	"context"
+)
+
+// CostCounter accumulates the resources used by a request, implemented by traceutil.CostCounter.
+// It lives here so that the packages traceutil depends on, such as databaseutil, can report costs.
+type CostCounter interface {
+	Add(key string, n int64)
+}
+
+type costCounterContextKey struct{}
+
+// WithCostCounter returns a context carrying counter, see traceutil.WithCostCounter
+func WithCostCounter(ctx context.Context, counter CostCounter) context.Context {
+	return context.WithValue(ctx, costCounterContextKey{}, counter)
+}
+
+// CostCounterFromContext returns the counter of ctx, or nil when there is none
+func CostCounterFromContext(ctx context.Context) CostCounter {
+	counter, _ := ctx.Value(costCounterContextKey{}).(CostCounter)
+	return counter
+}
+
+// AddCost adds n to key on the counter of ctx, it does nothing without a counter
+func AddCost(ctx context.Context, key string, n int64) {
+	if counter := CostCounterFromContext(ctx); counter != nil {
+		counter.Add(key, n)
+	}
+}
@@ -0,0 +1,65 @@
+package logutil
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/baggage"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DebugBaggageKey is the baggage member that clients could previously use to mark a request for
+// debugging. Baggage is set by any caller, so it is never trusted: TraceMiddleware drops inbound
+// members with this key, and DebugEnabled only reads the mark set by WithDebug.
+const DebugBaggageKey = "debug"
+
+type debugContextKey struct{}
+
+// WithDebug marks ctx for per-request debugging, see DebugEnabled. It must only be called by a
+// trusted source such as middleware.DebugMiddleware, after it verified a signed token.
+func WithDebug(ctx context.Context) context.Context {
+	return context.WithValue(ctx, debugContextKey{}, true)
+}
+
+// DebugEnabled reports whether ctx was marked for per-request debugging with WithDebug
+func DebugEnabled(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	enabled, _ := ctx.Value(debugContextKey{}).(bool)
+	return enabled
+}
+
+// WithoutDebugBaggage removes the DebugBaggageKey member from the baggage of ctx, so that an
+// inbound mark is neither trusted by older services nor propagated downstream
+func WithoutDebugBaggage(ctx context.Context) context.Context {
+	bag := baggage.FromContext(ctx)
+	if bag.Member(DebugBaggageKey).Key() == "" {
+		return ctx
+	}
+	return baggage.ContextWithBaggage(ctx, bag.DeleteMember(DebugBaggageKey))
+}
+
+// debugCore writes debug entries even when the wrapped core is configured at a higher level
+type debugCore struct {
+	zapcore.Core
+}
+
+func (c debugCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c debugCore) With(fields []zapcore.Field) zapcore.Core {
+	return debugCore{Core: c.Core.With(fields)}
+}
+
+func (c debugCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checked.AddCore(entry, c)
+}
+
+// forceDebug lowers the level of logger to debug
+func forceDebug(logger *zap.Logger) *zap.Logger {
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return debugCore{Core: core}
+	}))
+}
@@ -0,0 +1,114 @@
+package logutil
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fieldsTag is the struct tag read by Fields, e.g. `logfield:"user_id"`. The options
+// "omitempty" skips zero values and "secret" never logs the field, "-" also skips it.
+const fieldsTag = "logfield"
+
+type taggedField struct {
+	index     int
+	name      string
+	omitEmpty bool
+}
+
+var taggedFieldsCache sync.Map // reflect.Type -> []taggedField
+
+// Fields converts a struct, or a pointer to one, into zap fields following its logfield tags.
+// Untagged and secret fields are never logged, so adding a field to a domain type does not
+// leak it into the logs by accident. Tagged struct fields with tags of their own are logged
+// as nested objects, anything else is handled by zap.Any.
+func Fields(v any) []zap.Field {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	tagged := taggedFieldsOf(rv.Type())
+	fields := make([]zap.Field, 0, len(tagged))
+	for _, tf := range tagged {
+		value := rv.Field(tf.index)
+		if tf.omitEmpty && value.IsZero() {
+			continue
+		}
+		fields = append(fields, fieldOf(tf.name, value))
+	}
+	return fields
+}
+
+func fieldOf(name string, value reflect.Value) zap.Field {
+	nested := value
+	for nested.Kind() == reflect.Pointer && !nested.IsNil() {
+		nested = nested.Elem()
+	}
+	if nested.Kind() == reflect.Struct && len(taggedFieldsOf(nested.Type())) > 0 {
+		return zap.Object(name, taggedObject{value: nested})
+	}
+	return zap.Any(name, value.Interface())
+}
+
+// taggedObject logs a nested struct with the same rules as Fields
+type taggedObject struct {
+	value reflect.Value
+}
+
+func (o taggedObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, field := range Fields(o.value.Interface()) {
+		field.AddTo(enc)
+	}
+	return nil
+}
+
+func taggedFieldsOf(t reflect.Type) []taggedField {
+	if cached, ok := taggedFieldsCache.Load(t); ok {
+		return cached.([]taggedField)
+	}
+
+	var tagged []taggedField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup(fieldsTag)
+		if !ok || tag == "-" {
+			continue
+		}
+
+		name, options, _ := strings.Cut(tag, ",")
+		tf := taggedField{index: i, name: name}
+		secret := false
+		for _, option := range strings.Split(options, ",") {
+			switch strings.TrimSpace(option) {
+			case "omitempty":
+				tf.omitEmpty = true
+			case "secret":
+				secret = true
+			}
+		}
+		if secret {
+			continue
+		}
+		if tf.name == "" {
+			tf.name = field.Name
+		}
+		tagged = append(tagged, tf)
+	}
+
+	taggedFieldsCache.Store(t, tagged)
+	return tagged
+}
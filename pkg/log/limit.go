@@ -0,0 +1,320 @@
+package logutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	truncatedFieldsKey = "truncated_fields"
+	maxDepthMarker     = "<max depth reached>"
+	maxValuesMarker    = "<max values reached>"
+	cycleMarker        = "<cycle>"
+	truncatedValuesKey = "<truncated>"
+)
+
+// FieldLimits bounds the size of the fields written with a single log entry.
+// A zero value in any of the limits disables that limit. Cyclic values are always cut,
+// the reference closing the cycle is written as "<cycle>".
+type FieldLimits struct {
+	MaxDepth        int
+	MaxStringLength int
+	MaxFields       int
+
+	// MaxValues caps the number of values written for a single reflected field, counting
+	// every map entry, slice element and struct field at any depth
+	MaxValues int
+
+	// OmitEmpty drops the zero struct fields tagged omitempty, like the JSON payloads do
+	OmitEmpty bool
+}
+
+// DefaultFieldLimits returns limits that keep a single entry well below the size most log pipelines accept
+func DefaultFieldLimits() FieldLimits {
+	return FieldLimits{
+		MaxDepth:        5,
+		MaxStringLength: 4096,
+		MaxFields:       64,
+		MaxValues:       1000,
+	}
+}
+
+// WithFieldLimits returns a zap.Option that applies the given limits to every entry written by the logger
+func WithFieldLimits(limits FieldLimits) zap.Option {
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return NewLimitCore(core, limits)
+	})
+}
+
+// limitCore is a zapcore.Core wrapper that truncates oversized fields before passing them to the wrapped core
+type limitCore struct {
+	zapcore.Core
+	limits FieldLimits
+	// fields is the number of fields added with With, they count against MaxFields like the entry's own
+	fields int
+}
+
+func NewLimitCore(core zapcore.Core, limits FieldLimits) zapcore.Core {
+	return &limitCore{Core: core, limits: limits}
+}
+
+func (c *limitCore) With(fields []zapcore.Field) zapcore.Core {
+	limited, kept := c.limitFields(fields)
+	return &limitCore{Core: c.Core.With(limited), limits: c.limits, fields: c.fields + kept}
+}
+
+func (c *limitCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *limitCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	limited, _ := c.limitFields(fields)
+	return c.Core.Write(entry, limited)
+}
+
+// limitFields drops the fields exceeding what is left of MaxFields after the fields added with With
+// and truncates the remaining ones, a marker field with the number of dropped fields is appended when
+// anything is dropped. It returns the limited fields and the number of fields kept.
+func (c *limitCore) limitFields(fields []zapcore.Field) ([]zapcore.Field, int) {
+	dropped := 0
+	if c.limits.MaxFields > 0 {
+		remaining := max(c.limits.MaxFields-c.fields, 0)
+		if len(fields) > remaining {
+			dropped = len(fields) - remaining
+			fields = fields[:remaining]
+		}
+	}
+
+	limited := make([]zapcore.Field, 0, len(fields)+1)
+	for _, field := range fields {
+		limited = append(limited, c.limitField(field))
+	}
+
+	if dropped > 0 {
+		limited = append(limited, zap.Int(truncatedFieldsKey, dropped))
+	}
+
+	return limited, len(fields)
+}
+
+func (c *limitCore) limitField(field zapcore.Field) zapcore.Field {
+	switch field.Type {
+	case zapcore.StringType:
+		field.String = truncateString(field.String, c.limits.MaxStringLength)
+	case zapcore.ByteStringType:
+		if b, ok := field.Interface.([]byte); ok {
+			return zap.String(field.Key, truncateString(string(b), c.limits.MaxStringLength))
+		}
+	case zapcore.ErrorType:
+		if err, ok := field.Interface.(error); ok && err != nil {
+			return zap.String(field.Key, truncateString(err.Error(), c.limits.MaxStringLength))
+		}
+	case zapcore.StringerType:
+		if s, ok := field.Interface.(fmt.Stringer); ok && s != nil {
+			return zap.String(field.Key, truncateString(s.String(), c.limits.MaxStringLength))
+		}
+	case zapcore.ReflectType:
+		return zap.Any(field.Key, limitValue(reflect.ValueOf(field.Interface), c.limits, 0))
+	}
+	return field
+}
+
+// limitValue walks a reflected value and rebuilds it with the limits applied
+func limitValue(v reflect.Value, limits FieldLimits, depth int) any {
+	limiter := valueLimiter{limits: limits, visiting: map[visit]bool{}}
+	return limiter.limit(v, depth)
+}
+
+// visit identifies a pointer, map or slice on the current path, the type is part of it because
+// a struct and its first field share their address
+type visit struct {
+	pointer uintptr
+	typ     reflect.Type
+}
+
+// valueLimiter holds the state of one limitValue walk
+type valueLimiter struct {
+	limits FieldLimits
+	// visiting are the references being walked, finding one again means the value is cyclic
+	visiting map[visit]bool
+	// values counts the values rebuilt so far, against MaxValues
+	values int
+}
+
+func (l *valueLimiter) limit(v reflect.Value, depth int) any {
+	if !v.IsValid() {
+		return nil
+	}
+
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Pointer {
+			done, cyclic := l.enter(v)
+			if cyclic {
+				return cycleMarker
+			}
+			defer done()
+		}
+		v = v.Elem()
+	}
+
+	if l.limits.MaxDepth > 0 && depth >= l.limits.MaxDepth {
+		switch v.Kind() {
+		case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+			return maxDepthMarker
+		}
+	}
+
+	l.values++
+
+	switch v.Kind() {
+	case reflect.String:
+		return truncateString(v.String(), l.limits.MaxStringLength)
+	case reflect.Map:
+		done, cyclic := l.enter(v)
+		if cyclic {
+			return cycleMarker
+		}
+		defer done()
+
+		result := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			if l.exhausted() {
+				result[truncatedValuesKey] = maxValuesMarker
+				break
+			}
+			result[fmt.Sprintf("%v", iter.Key().Interface())] = l.limit(iter.Value(), depth+1)
+		}
+		return result
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return truncateString(string(v.Bytes()), l.limits.MaxStringLength)
+		}
+		if v.Kind() == reflect.Slice {
+			done, cyclic := l.enter(v)
+			if cyclic {
+				return cycleMarker
+			}
+			defer done()
+		}
+		result := make([]any, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if l.exhausted() {
+				result = append(result, maxValuesMarker)
+				break
+			}
+			result = append(result, l.limit(v.Index(i), depth+1))
+		}
+		return result
+	case reflect.Struct:
+		// Types such as time.Time know how to encode themselves
+		if v.CanInterface() {
+			if _, ok := v.Interface().(json.Marshaler); ok {
+				return v.Interface()
+			}
+		}
+		result := make(map[string]any, v.NumField())
+		l.limitStructFields(v, depth, false, result)
+		return result
+	default:
+		if v.CanInterface() {
+			return v.Interface()
+		}
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// exhausted reports whether MaxValues values were written, the walk then stops adding any
+func (l *valueLimiter) exhausted() bool {
+	return l.limits.MaxValues > 0 && l.values >= l.limits.MaxValues
+}
+
+// enter marks the reference v as being walked and returns the function unmarking it, or reports
+// that v is already being walked. Only the current path is tracked, so a value referenced twice
+// without a cycle is still written twice.
+func (l *valueLimiter) enter(v reflect.Value) (done func(), cyclic bool) {
+	key := visit{pointer: v.Pointer(), typ: v.Type()}
+	// Empty slices and nil references may share an address without referencing each other
+	if key.pointer == 0 || (v.Kind() == reflect.Slice && v.Len() == 0) {
+		return func() {}, false
+	}
+	if l.visiting[key] {
+		return nil, true
+	}
+	l.visiting[key] = true
+	return func() { delete(l.visiting, key) }, false
+}
+
+// limitStructFields adds the fields of the struct v to result named like encoding/json would, so
+// logged values match the API payloads: the json tag names the field, "-" and unexported fields are
+// skipped, and the fields of untagged embedded structs are promoted. Zero fields tagged omitempty
+// are only skipped when OmitEmpty is set.
+func (l *valueLimiter) limitStructFields(v reflect.Value, depth int, promoted bool, result map[string]any) {
+	for i := 0; i < v.NumField(); i++ {
+		fieldType := v.Type().Field(i)
+		name, options, tagged := strings.Cut(fieldType.Tag.Get("json"), ",")
+		if name == "-" && !tagged {
+			continue
+		}
+
+		field := v.Field(i)
+		if fieldType.Anonymous && name == "" {
+			embedded := field
+			if embedded.Kind() == reflect.Pointer {
+				if embedded.IsNil() || !fieldType.IsExported() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				l.limitStructFields(embedded, depth, true, result)
+				continue
+			}
+		}
+		if !fieldType.IsExported() {
+			continue
+		}
+
+		if l.limits.OmitEmpty && slices.Contains(strings.Split(options, ","), "omitempty") && field.IsZero() {
+			continue
+		}
+		if name == "" {
+			name = fieldType.Name
+		}
+		if l.exhausted() {
+			result[truncatedValuesKey] = maxValuesMarker
+			return
+		}
+		// Like encoding/json, a field of the outer struct wins over a promoted one
+		if _, ok := result[name]; ok && promoted {
+			continue
+		}
+		result[name] = l.limit(field, depth+1)
+	}
+}
+
+// truncateString cuts s to at most maxLength bytes and appends a marker with the number of bytes
+// removed, the cut backs up to a rune boundary so that no multi-byte character is split
+func truncateString(s string, maxLength int) string {
+	if maxLength <= 0 || len(s) <= maxLength {
+		return s
+	}
+	cut := maxLength
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s...(truncated %d bytes)", s[:cut], len(s)-cut)
+}
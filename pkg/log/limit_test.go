@@ -0,0 +1,251 @@
+package logutil
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type limitTestEmbedded struct {
+	Region string `json:"region"`
+}
+
+type limitTestUser struct {
+	limitTestEmbedded
+	ID       string `json:"id"`
+	Name     string
+	Password string `json:"-"`
+	Nickname string `json:"nickname,omitempty"`
+	secret   string
+	mu       chan struct{}
+}
+
+func newLimitTestLogger(limits FieldLimits) (*zap.Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	return zap.New(NewLimitCore(core, limits)), logs
+}
+
+func TestLimitCore_StructFields(t *testing.T) {
+	user := limitTestUser{
+		limitTestEmbedded: limitTestEmbedded{Region: "tw"},
+		ID:                "u1",
+		Name:              "Alice",
+		Password:          "hunter2",
+		secret:            "s3cr3t",
+		mu:                make(chan struct{}),
+	}
+
+	tests := []struct {
+		name   string
+		limits FieldLimits
+		value  any
+		want   any
+	}{
+		{
+			name:   "Should skip unexported fields without panicking",
+			limits: DefaultFieldLimits(),
+			value:  user,
+			want:   map[string]any{"region": "tw", "id": "u1", "Name": "Alice", "nickname": ""},
+		},
+		{
+			name:   "Should skip unexported fields behind a pointer",
+			limits: DefaultFieldLimits(),
+			value:  &user,
+			want:   map[string]any{"region": "tw", "id": "u1", "Name": "Alice", "nickname": ""},
+		},
+		{
+			name:   "Should drop empty omitempty fields when OmitEmpty is set",
+			limits: FieldLimits{OmitEmpty: true},
+			value:  user,
+			want:   map[string]any{"region": "tw", "id": "u1", "Name": "Alice"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, logs := newLimitTestLogger(tt.limits)
+
+			logger.Info("test", zap.Any("user", tt.value))
+
+			got := logs.All()[0].ContextMap()["user"]
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("user = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitCore_MaxFields(t *testing.T) {
+	tests := []struct {
+		name      string
+		with      []zap.Field
+		fields    []zap.Field
+		wantKeys  []string
+		wantDrops int
+	}{
+		{
+			name:     "Should keep fields within the limit",
+			fields:   []zap.Field{zap.Int("a", 1), zap.Int("b", 2)},
+			wantKeys: []string{"a", "b"},
+		},
+		{
+			name:      "Should drop entry fields beyond the limit",
+			fields:    []zap.Field{zap.Int("a", 1), zap.Int("b", 2), zap.Int("c", 3), zap.Int("d", 4)},
+			wantKeys:  []string{"a", "b", "c"},
+			wantDrops: 1,
+		},
+		{
+			name:      "Should count fields added with With",
+			with:      []zap.Field{zap.Int("a", 1), zap.Int("b", 2)},
+			fields:    []zap.Field{zap.Int("c", 3), zap.Int("d", 4)},
+			wantKeys:  []string{"a", "b", "c"},
+			wantDrops: 1,
+		},
+		{
+			name:      "Should drop every entry field when With used up the limit",
+			with:      []zap.Field{zap.Int("a", 1), zap.Int("b", 2), zap.Int("c", 3), zap.Int("d", 4)},
+			fields:    []zap.Field{zap.Int("e", 5)},
+			wantKeys:  []string{"a", "b", "c"},
+			wantDrops: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, logs := newLimitTestLogger(FieldLimits{MaxFields: 3})
+
+			logger.With(tt.with...).Info("test", tt.fields...)
+
+			context := logs.All()[0].ContextMap()
+			for _, key := range tt.wantKeys {
+				if _, ok := context[key]; !ok {
+					t.Errorf("field %q missing from %v", key, context)
+				}
+			}
+
+			gotDrops, _ := context[truncatedFieldsKey].(int64)
+			if int(gotDrops) != tt.wantDrops {
+				t.Errorf("%s = %d, want %d", truncatedFieldsKey, gotDrops, tt.wantDrops)
+			}
+			wantLen := len(tt.wantKeys)
+			if tt.wantDrops > 0 {
+				wantLen++
+			}
+			if len(context) != wantLen {
+				t.Errorf("got %d fields %v, want %d", len(context), context, wantLen)
+			}
+		})
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		name      string
+		s         string
+		maxLength int
+		want      string
+	}{
+		{name: "Should keep short strings", s: "hello", maxLength: 10, want: "hello"},
+		{name: "Should keep strings when the limit is disabled", s: "hello", maxLength: 0, want: "hello"},
+		{name: "Should cut ASCII strings at the limit", s: "hello world", maxLength: 5, want: "hello...(truncated 6 bytes)"},
+		{name: "Should cut on a rune boundary", s: "ab陽明交大", maxLength: 4, want: "ab...(truncated 12 bytes)"},
+		{name: "Should keep whole runes within the limit", s: "陽明交大", maxLength: 7, want: "陽明...(truncated 6 bytes)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateString(tt.s, tt.maxLength)
+			if got != tt.want {
+				t.Errorf("truncateString(%q, %d) = %q, want %q", tt.s, tt.maxLength, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncateString(%q, %d) = %q is not valid UTF-8", tt.s, tt.maxLength, got)
+			}
+		})
+	}
+}
+
+type limitTestNode struct {
+	Name string         `json:"name"`
+	Next *limitTestNode `json:"next"`
+}
+
+func TestLimitCore_CyclicValues(t *testing.T) {
+	self := &limitTestNode{Name: "self"}
+	self.Next = self
+
+	first := &limitTestNode{Name: "first"}
+	first.Next = &limitTestNode{Name: "second", Next: first}
+
+	cyclicMap := map[string]any{"name": "map"}
+	cyclicMap["self"] = cyclicMap
+
+	shared := &limitTestNode{Name: "shared"}
+
+	tests := []struct {
+		name  string
+		value any
+		want  any
+	}{
+		{
+			name:  "Should cut a self-referencing pointer",
+			value: self,
+			want:  map[string]any{"name": "self", "next": cycleMarker},
+		},
+		{
+			name:  "Should cut a cycle through two pointers",
+			value: first,
+			want:  map[string]any{"name": "first", "next": map[string]any{"name": "second", "next": cycleMarker}},
+		},
+		{
+			name:  "Should cut a self-referencing map",
+			value: cyclicMap,
+			want:  map[string]any{"name": "map", "self": cycleMarker},
+		},
+		{
+			name:  "Should write a value referenced twice without a cycle",
+			value: []*limitTestNode{shared, shared},
+			want:  []any{map[string]any{"name": "shared", "next": nil}, map[string]any{"name": "shared", "next": nil}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// No depth limit, so only the cycle detection stops the walk
+			logger, logs := newLimitTestLogger(FieldLimits{})
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				logger.Info("test", zap.Any("value", tt.value))
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("logging a cyclic value did not return")
+			}
+
+			got := logs.All()[0].ContextMap()["value"]
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("value = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitCore_MaxValues(t *testing.T) {
+	logger, logs := newLimitTestLogger(FieldLimits{MaxValues: 3})
+
+	logger.Info("test", zap.Any("values", []any{1, 2, 3, 4, 5}))
+
+	got := logs.All()[0].ContextMap()["values"]
+	want := []any{1, 2, maxValuesMarker}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("values = %#v, want %#v", got, want)
+	}
+}
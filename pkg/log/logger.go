@@ -12,9 +12,26 @@ import (
 	"go.uber.org/zap/zapcore"
 )
 
-// ZapProductionConfig returns a zap.Config same as zap.NewProduction() but without sampling
-func ZapProductionConfig() zap.Config {
-	return zap.Config{
+// ConfigOption adjusts the zap.Config returned by ZapProductionConfig and ZapDevelopmentConfig
+type ConfigOption func(*zap.Config)
+
+// WithSampling enables the zap sampler: every second, the first initial entries with the same
+// level and message are logged, then one in thereafter. hook, when not nil, is told about every
+// sampling decision, e.g. to count the dropped entries.
+func WithSampling(initial, thereafter int, hook func(zapcore.Entry, zapcore.SamplingDecision)) ConfigOption {
+	return func(config *zap.Config) {
+		config.Sampling = &zap.SamplingConfig{
+			Initial:    initial,
+			Thereafter: thereafter,
+			Hook:       hook,
+		}
+	}
+}
+
+// ZapProductionConfig returns a zap.Config same as zap.NewProduction() but without sampling unless
+// WithSampling is given, see also WithLevelRateLimits
+func ZapProductionConfig(opts ...ConfigOption) zap.Config {
+	config := zap.Config{
 		Level:             zap.NewAtomicLevelAt(zap.InfoLevel),
 		Development:       false,
 		DisableStacktrace: true,
@@ -23,10 +40,15 @@ func ZapProductionConfig() zap.Config {
 		OutputPaths:       []string{"stdout"},
 		ErrorOutputPaths:  []string{"stdout"},
 	}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+	return config
 }
 
 // ZapDevelopmentConfig returns a zap.Config same as zap.NewProduction() but with more pretty output
-func ZapDevelopmentConfig() zap.Config {
+func ZapDevelopmentConfig(opts ...ConfigOption) zap.Config {
 	rootDir, _ := os.Getwd()
 
 	config := zap.Config{
@@ -42,15 +64,51 @@ func ZapDevelopmentConfig() zap.Config {
 	config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
 	config.EncoderConfig.EncodeCaller = relativePrettyCallerEncoder(rootDir)
 
+	for _, opt := range opts {
+		opt(&config)
+	}
 	return config
 }
 
-// WithContext parses the context and adds the trace ID to the logger if available
+type loggerContextKey struct{}
+
+var nopLogger = zap.NewNop()
+
+// IntoContext returns a context carrying logger, see FromContext
+func IntoContext(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerContextKey{}, logger)
+}
+
+// FromContext returns the logger stored with IntoContext, or a nop logger when there is none.
+// TraceMiddleware stores the request logger, with the trace fields of WithContext, so deep layers
+// can log without a logger parameter. Its span_id is the one of the request span, call WithContext
+// with the logger to get the fields of a span started later.
+func FromContext(ctx context.Context) *zap.Logger {
+	if ctx == nil {
+		return nopLogger
+	}
+	if logger, ok := ctx.Value(loggerContextKey{}).(*zap.Logger); ok && logger != nil {
+		return logger
+	}
+	return nopLogger
+}
+
+// WithContext parses the context and adds the trace ID to the logger if available. Requests marked
+// with WithDebug get a logger writing debug entries regardless of its configured level, and contexts
+// carrying an EntryBuffer get a logger also writing to the buffer.
 func WithContext(ctx context.Context, logger *zap.Logger) *zap.Logger {
 	if ctx == nil {
 		return logger
 	}
 
+	if DebugEnabled(ctx) {
+		logger = forceDebug(logger).With(zap.Bool("debug_request", true))
+	}
+
+	if buffer := EntryBufferFromContext(ctx); buffer != nil {
+		logger = teeEntryBuffer(logger, buffer)
+	}
+
 	spanCtx := trace.SpanFromContext(ctx).SpanContext()
 	if spanCtx.HasTraceID() {
 		logger = logger.With(zap.String("trace_id", spanCtx.TraceID().String()))
@@ -68,6 +126,10 @@ func WithContext(ctx context.Context, logger *zap.Logger) *zap.Logger {
 		logger = logger.With(zap.Any("username", ctx.Value("username")))
 	}
 
+	if ctx.Value("tenant_id") != nil {
+		logger = logger.With(zap.Any("tenant_id", ctx.Value("tenant_id")))
+	}
+
 	if ctx.Value("name") != nil {
 		logger = logger.With(zap.Any("display-name", ctx.Value("name")))
 	}
@@ -0,0 +1,218 @@
+package logutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ModuleLevels holds per-module level overrides applied to named loggers (logger.Named(module)).
+// Entries of loggers without an override use the default level.
+type ModuleLevels struct {
+	mu           sync.RWMutex
+	defaultLevel zap.AtomicLevel
+	levels       map[string]zapcore.Level
+}
+
+func NewModuleLevels(defaultLevel zap.AtomicLevel) *ModuleLevels {
+	return &ModuleLevels{
+		defaultLevel: defaultLevel,
+		levels:       map[string]zapcore.Level{},
+	}
+}
+
+// ParseModuleLevels parses a level map such as "pkg/database=debug,pkg/trace=warn"
+func ParseModuleLevels(spec string) (map[string]zapcore.Level, error) {
+	levels := map[string]zapcore.Level{}
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		module, levelText, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(module) == "" {
+			return nil, fmt.Errorf("invalid module level %q, expected module=level", pair)
+		}
+
+		level, err := zapcore.ParseLevel(strings.TrimSpace(levelText))
+		if err != nil {
+			return nil, fmt.Errorf("invalid level for module %q: %w", module, err)
+		}
+		levels[strings.TrimSpace(module)] = level
+	}
+	return levels, nil
+}
+
+// Set overrides the level of a module
+func (m *ModuleLevels) Set(module string, level zapcore.Level) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.levels[module] = level
+}
+
+// Unset removes the override of a module
+func (m *ModuleLevels) Unset(module string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.levels, module)
+}
+
+// SetFromString replaces all overrides with the ones parsed from spec
+func (m *ModuleLevels) SetFromString(spec string) error {
+	levels, err := ParseModuleLevels(spec)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.levels = levels
+	return nil
+}
+
+// Levels returns a copy of the current overrides
+func (m *ModuleLevels) Levels() map[string]zapcore.Level {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	levels := make(map[string]zapcore.Level, len(m.levels))
+	for k, v := range m.levels {
+		levels[k] = v
+	}
+	return levels
+}
+
+// LevelFor returns the level applied to the given logger name, the longest matching module wins
+func (m *ModuleLevels) LevelFor(loggerName string) zapcore.Level {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	level := m.defaultLevel.Level()
+	matched := -1
+	for module, moduleLevel := range m.levels {
+		if loggerName == module || strings.HasPrefix(loggerName, module+".") {
+			if len(module) > matched {
+				level = moduleLevel
+				matched = len(module)
+			}
+		}
+	}
+	return level
+}
+
+// minLevel returns the lowest level among the default and the overrides
+func (m *ModuleLevels) minLevel() zapcore.Level {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	level := m.defaultLevel.Level()
+	for _, moduleLevel := range m.levels {
+		if moduleLevel < level {
+			level = moduleLevel
+		}
+	}
+	return level
+}
+
+// Build creates a logger from config with the module overrides applied. The config level
+// becomes the default level, it keeps being changeable through the AtomicLevel.
+func (m *ModuleLevels) Build(config zap.Config, opts ...zap.Option) (*zap.Logger, error) {
+	m.mu.Lock()
+	m.defaultLevel = config.Level
+	m.mu.Unlock()
+
+	// The wrapped core filters the entries itself, so the inner core must accept everything
+	config.Level = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+
+	opts = append(opts, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &moduleLevelCore{Core: core, levels: m}
+	}))
+	return config.Build(opts...)
+}
+
+type moduleLevelsPayload struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules"`
+}
+
+// ServeHTTP is the runtime level endpoint. GET returns the default level and the overrides,
+// PUT replaces them with the ones in the JSON body, e.g. {"level":"info","modules":{"pkg/database":"debug"}}
+func (m *ModuleLevels) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var payload moduleLevelsPayload
+		err := json.NewDecoder(r.Body).Decode(&payload)
+		if err != nil {
+			http.Error(w, "invalid JSON payload: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		levels := make(map[string]zapcore.Level, len(payload.Modules))
+		for module, levelText := range payload.Modules {
+			level, err := zapcore.ParseLevel(levelText)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("invalid level for module %q: %v", module, err), http.StatusBadRequest)
+				return
+			}
+			levels[module] = level
+		}
+
+		if payload.Level != "" {
+			level, err := zapcore.ParseLevel(payload.Level)
+			if err != nil {
+				http.Error(w, "invalid level: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			m.defaultLevel.SetLevel(level)
+		}
+
+		m.mu.Lock()
+		m.levels = levels
+		m.mu.Unlock()
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload := moduleLevelsPayload{
+		Level:   m.defaultLevel.Level().String(),
+		Modules: map[string]string{},
+	}
+	for module, level := range m.Levels() {
+		payload.Modules[module] = level.String()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+// moduleLevelCore drops the entries below the level of the module that wrote them
+type moduleLevelCore struct {
+	zapcore.Core
+	levels *ModuleLevels
+}
+
+func (c *moduleLevelCore) Enabled(level zapcore.Level) bool {
+	return level >= c.levels.minLevel()
+}
+
+func (c *moduleLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &moduleLevelCore{Core: c.Core.With(fields), levels: c.levels}
+}
+
+func (c *moduleLevelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.Level < c.levels.LevelFor(entry.LoggerName) {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
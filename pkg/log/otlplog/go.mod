@@ -0,0 +1,17 @@
+module github.com/NYCU-SDC/summer/pkg/log/otlplog
+
+go 1.26.2
+
+require (
+	github.com/NYCU-SDC/summer v0.0.0-00010101000000-000000000000
+	go.opentelemetry.io/contrib/bridges/otelzap v0.10.0
+	go.opentelemetry.io/otel v1.35.0
+	go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp v0.11.0
+	go.opentelemetry.io/otel/log v0.11.0
+	go.opentelemetry.io/otel/sdk v1.35.0
+	go.opentelemetry.io/otel/sdk/log v0.11.0
+	go.opentelemetry.io/otel/trace v1.35.0
+	go.uber.org/zap v1.27.0
+)
+
+replace github.com/NYCU-SDC/summer => ../../..
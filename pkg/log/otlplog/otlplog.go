@@ -0,0 +1,197 @@
+// Package otlplog ships zap entries to an OpenTelemetry collector with the OTel Logs SDK and the
+// otelzap bridge. It is a separate module so that services not exporting logs over OTLP do not
+// depend on the SDK.
+package otlplog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"go.opentelemetry.io/contrib/bridges/otelzap"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	scopeName    = "github.com/NYCU-SDC/summer/pkg/log"
+	flushTimeout = 10 * time.Second
+)
+
+// Config configures NewCore, the zero values fall back to the SDK defaults
+type Config struct {
+	// Endpoint is the OTLP/HTTP logs URL of the collector, e.g. http://otel-collector:4318/v1/logs
+	Endpoint string
+	// Headers are added to every export request, e.g. for authentication
+	Headers map[string]string
+	// Resource describes the process, service.name is the one backends group logs by
+	Resource map[string]string
+
+	// BatchSize is the number of records sent per request
+	BatchSize int
+	// QueueSize is the number of records waiting to be sent, records written while the queue is
+	// full are dropped instead of blocking the caller
+	QueueSize int
+	// ExportInterval is the longest a record waits in the queue
+	ExportInterval time.Duration
+}
+
+// Core is a zapcore.Core writing entries to an OTel LoggerProvider through the otelzap bridge.
+// The trace_id and span_id fields added by logutil.WithContext become the trace context of the
+// log record rather than attributes, so the backend correlates logs with the spans exported by
+// the trace pipeline. Tee it with the stdout core:
+//
+//	otlpCore, err := otlplog.NewCore(ctx, otlplog.Config{
+//		Endpoint: "http://otel-collector:4318/v1/logs",
+//		Resource: map[string]string{"service.name": "core-system"},
+//	}, zapcore.InfoLevel)
+//	defer otlpCore.Shutdown(context.Background())
+//	logger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+//		return zapcore.NewTee(core, otlpCore)
+//	}))
+type Core struct {
+	core     zapcore.Core
+	provider *sdklog.LoggerProvider
+
+	// traceID and spanID are the trace fields added with With, they are passed to the bridge
+	// as the context of every record
+	traceID string
+	spanID  string
+}
+
+// NewCore creates an OTLP/HTTP exporter for cfg and the batching LoggerProvider exporting to it,
+// Shutdown flushes and stops them
+func NewCore(ctx context.Context, cfg Config, level zapcore.LevelEnabler) (*Core, error) {
+	if cfg.Endpoint == "" {
+		return nil, errors.New("otlp endpoint is required")
+	}
+
+	exporter, err := otlploghttp.New(ctx, otlploghttp.WithEndpointURL(cfg.Endpoint), otlploghttp.WithHeaders(cfg.Headers))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp log exporter: %w", err)
+	}
+
+	var batchOpts []sdklog.BatchProcessorOption
+	if cfg.BatchSize > 0 {
+		batchOpts = append(batchOpts, sdklog.WithExportMaxBatchSize(cfg.BatchSize))
+	}
+	if cfg.QueueSize > 0 {
+		batchOpts = append(batchOpts, sdklog.WithMaxQueueSize(cfg.QueueSize))
+	}
+	if cfg.ExportInterval > 0 {
+		batchOpts = append(batchOpts, sdklog.WithExportInterval(cfg.ExportInterval))
+	}
+
+	attributes := make([]attribute.KeyValue, 0, len(cfg.Resource))
+	for key, value := range cfg.Resource {
+		attributes = append(attributes, attribute.String(key, value))
+	}
+
+	provider := sdklog.NewLoggerProvider(
+		sdklog.WithResource(resource.NewSchemaless(attributes...)),
+		sdklog.WithProcessor(sdklog.NewBatchProcessor(exporter, batchOpts...)),
+	)
+
+	return newCore(provider, level)
+}
+
+func newCore(provider *sdklog.LoggerProvider, level zapcore.LevelEnabler) (*Core, error) {
+	core, err := zapcore.NewIncreaseLevelCore(otelzap.NewCore(scopeName, otelzap.WithLoggerProvider(provider)), level)
+	if err != nil {
+		return nil, err
+	}
+	return &Core{core: core, provider: provider}, nil
+}
+
+// RegisterSink makes name usable as a logutil.TeeConfig sink output shipping entries to the
+// collector of cfg, so the export can be switched on from the service config:
+//
+//	otlplog.RegisterSink("otlp", otlplog.Config{Endpoint: cfg.OTLPLogsEndpoint})
+//	// sinks: [{output: stdout}, {output: otlp, level: info}]
+func RegisterSink(name string, cfg Config) {
+	logutil.RegisterSink(name, func(sink logutil.SinkConfig, level zapcore.Level) (zapcore.Core, error) {
+		return NewCore(context.Background(), cfg, level)
+	})
+}
+
+func (c *Core) Enabled(level zapcore.Level) bool {
+	return c.core.Enabled(level)
+}
+
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.core = c.core.With(clone.takeTraceFields(fields))
+	return &clone
+}
+
+func (c *Core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *Core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	record := *c
+	fields = record.takeTraceFields(fields)
+
+	// The bridge takes the context of the record from a context.Context field
+	if ctx, ok := record.spanContext(); ok {
+		fields = append(fields, zap.Any("context", ctx))
+	}
+	return c.core.Write(entry, fields)
+}
+
+// Sync exports the records waiting in the batch processor
+func (c *Core) Sync() error {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	return c.provider.ForceFlush(ctx)
+}
+
+// Shutdown exports the waiting records and stops the provider, records written afterwards are
+// dropped. It waits until ctx is done at most.
+func (c *Core) Shutdown(ctx context.Context) error {
+	return c.provider.Shutdown(ctx)
+}
+
+// takeTraceFields moves the trace_id and span_id string fields into c and returns the others
+func (c *Core) takeTraceFields(fields []zapcore.Field) []zapcore.Field {
+	kept := make([]zapcore.Field, 0, len(fields))
+	for _, field := range fields {
+		switch {
+		case field.Type == zapcore.StringType && field.Key == "trace_id":
+			c.traceID = field.String
+		case field.Type == zapcore.StringType && field.Key == "span_id":
+			c.spanID = field.String
+		default:
+			kept = append(kept, field)
+		}
+	}
+	return kept
+}
+
+// spanContext returns a context carrying the trace fields as a remote span context
+func (c *Core) spanContext() (context.Context, bool) {
+	traceID, err := trace.TraceIDFromHex(c.traceID)
+	if err != nil {
+		return nil, false
+	}
+	spanID, _ := trace.SpanIDFromHex(c.spanID)
+
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	return trace.ContextWithSpanContext(context.Background(), spanContext), true
+}
@@ -0,0 +1,149 @@
+package otlplog
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/otel/log"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// recordingExporter keeps the exported records in memory
+type recordingExporter struct {
+	mu      sync.Mutex
+	records []sdklog.Record
+}
+
+func (e *recordingExporter) Export(_ context.Context, records []sdklog.Record) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, record := range records {
+		e.records = append(e.records, record.Clone())
+	}
+	return nil
+}
+
+func (e *recordingExporter) Shutdown(context.Context) error   { return nil }
+func (e *recordingExporter) ForceFlush(context.Context) error { return nil }
+
+func newTestLogger(t *testing.T, level zapcore.Level) (*zap.Logger, *recordingExporter) {
+	t.Helper()
+
+	exporter := &recordingExporter{}
+	provider := sdklog.NewLoggerProvider(sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)))
+	core, err := newCore(provider, level)
+	if err != nil {
+		t.Fatalf("newCore() unexpected error: %v", err)
+	}
+	return zap.New(core), exporter
+}
+
+func recordAttributes(record sdklog.Record) map[string]string {
+	attributes := map[string]string{}
+	record.WalkAttributes(func(kv log.KeyValue) bool {
+		attributes[kv.Key] = kv.Value.String()
+		return true
+	})
+	return attributes
+}
+
+func TestCore_Levels(t *testing.T) {
+	tests := []struct {
+		name         string
+		level        zapcore.Level
+		wantExported bool
+		wantSeverity log.Severity
+	}{
+		{name: "Should drop entries below the level", level: zapcore.DebugLevel, wantExported: false},
+		{name: "Should export info entries", level: zapcore.InfoLevel, wantExported: true, wantSeverity: log.SeverityInfo},
+		{name: "Should export error entries", level: zapcore.ErrorLevel, wantExported: true, wantSeverity: log.SeverityError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, exporter := newTestLogger(t, zapcore.InfoLevel)
+
+			if ce := logger.Check(tt.level, "User created"); ce != nil {
+				ce.Write()
+			}
+
+			if got := len(exporter.records) == 1; got != tt.wantExported {
+				t.Fatalf("got %d records, want exported %t", len(exporter.records), tt.wantExported)
+			}
+			if tt.wantExported && exporter.records[0].Severity() != tt.wantSeverity {
+				t.Errorf("severity = %v, want %v", exporter.records[0].Severity(), tt.wantSeverity)
+			}
+		})
+	}
+}
+
+func TestCore_TraceContext(t *testing.T) {
+	tests := []struct {
+		name   string
+		log    func(logger *zap.Logger)
+		wantOK bool
+	}{
+		{
+			name: "Should take the trace fields added with With",
+			log: func(logger *zap.Logger) {
+				logger.With(zap.String("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736"), zap.String("span_id", "00f067aa0ba902b7")).
+					Info("User created", zap.String("user_id", "u1"))
+			},
+			wantOK: true,
+		},
+		{
+			name: "Should take the trace fields of the entry",
+			log: func(logger *zap.Logger) {
+				logger.Info("User created", zap.String("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736"), zap.String("span_id", "00f067aa0ba902b7"), zap.String("user_id", "u1"))
+			},
+			wantOK: true,
+		},
+		{
+			name: "Should keep records without trace fields",
+			log: func(logger *zap.Logger) {
+				logger.Info("User created", zap.String("user_id", "u1"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, exporter := newTestLogger(t, zapcore.InfoLevel)
+
+			tt.log(logger)
+
+			if len(exporter.records) != 1 {
+				t.Fatalf("got %d records, want 1", len(exporter.records))
+			}
+			record := exporter.records[0]
+
+			if record.Body().AsString() != "User created" {
+				t.Errorf("body = %q, want %q", record.Body().AsString(), "User created")
+			}
+			attributes := recordAttributes(record)
+			if attributes["user_id"] != "u1" {
+				t.Errorf("attributes = %v, want user_id", attributes)
+			}
+			if _, ok := attributes["trace_id"]; ok {
+				t.Errorf("attributes = %v, want trace_id moved to the trace context", attributes)
+			}
+
+			if !tt.wantOK {
+				if record.TraceID().IsValid() {
+					t.Errorf("trace id = %s, want none", record.TraceID())
+				}
+				return
+			}
+			if got := record.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Errorf("trace id = %s, want 4bf92f3577b34da6a3ce929d0e0e4736", got)
+			}
+			if got := record.SpanID().String(); got != "00f067aa0ba902b7" {
+				t.Errorf("span id = %s, want 00f067aa0ba902b7", got)
+			}
+		})
+	}
+}
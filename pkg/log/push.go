@@ -0,0 +1,374 @@
+package logutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+// PushFormat is the body of the requests of a PushSink
+type PushFormat int
+
+const (
+	// PushLoki sends the entries as one Grafana Loki stream, see the Loki push API
+	PushLoki PushFormat = iota
+	// PushJSONLines sends the encoded entries one per line, for generic HTTP collectors
+	PushJSONLines
+)
+
+// PushConfig configures NewPushSink, the zero values fall back to defaults
+type PushConfig struct {
+	// URL receives the batches, e.g. http://loki:3100/loki/api/v1/push
+	URL    string
+	Format PushFormat
+	// Labels identify the stream in Loki, usually service and environment, keep them few and static
+	Labels  map[string]string
+	Headers map[string]string
+
+	// BatchSize is the number of entries sent per request, 1000 when zero
+	BatchSize int
+	// BatchWait is the longest an entry waits in the queue, 1 second when zero
+	BatchWait time.Duration
+	// QueueSize is the number of entries waiting to be sent, 10000 when zero
+	QueueSize int
+	// BlockTimeout is how long a write waits for room in a full queue before the entry is dropped,
+	// slowing the logging goroutines down rather than losing entries. Zero drops at once.
+	BlockTimeout time.Duration
+
+	// Client sends the requests, one with a 10 seconds timeout when nil
+	Client *http.Client
+	// OnError receives the failed requests, they are written to stderr when nil
+	OnError func(err error)
+}
+
+// PushSink is a zap.Sink batching the encoded entries and pushing them over HTTP from a
+// background goroutine. The log.push.* metrics count the sent, dropped and failed entries, so a
+// collector outage shows on the dashboards instead of silently losing logs.
+//
+// Importing the package registers the loki+http, loki+https, push+http and push+https schemes,
+// so a sink is configured like any other output of a zap.Config. The query parameters are the
+// stream labels, except batch_size, batch_wait, queue_size and block_timeout:
+//
+//	config := logutil.ZapProductionConfig()
+//	config.OutputPaths = append(config.OutputPaths,
+//		"loki+http://loki:3100/loki/api/v1/push?service=core-system&environment=prod&block_timeout=50ms")
+type PushSink struct {
+	cfg PushConfig
+
+	queue   chan pushEntry
+	flushes chan chan error
+
+	closeOnce sync.Once
+	done      chan struct{}
+	stopped   chan struct{}
+
+	sent    metric.Int64Counter
+	dropped metric.Int64Counter
+	failed  metric.Int64Counter
+	attrs   metric.MeasurementOption
+}
+
+type pushEntry struct {
+	time time.Time
+	line []byte
+}
+
+var pushSchemes = map[string]PushFormat{
+	"loki+http":  PushLoki,
+	"loki+https": PushLoki,
+	"push+http":  PushJSONLines,
+	"push+https": PushJSONLines,
+}
+
+func init() {
+	for scheme := range pushSchemes {
+		_ = zap.RegisterSink(scheme, func(u *url.URL) (zap.Sink, error) {
+			cfg, err := pushConfigFromURL(u)
+			if err != nil {
+				return nil, err
+			}
+			return NewPushSink(cfg)
+		})
+	}
+}
+
+// NewPushSink creates the sink and starts its push goroutine, Close stops it
+func NewPushSink(cfg PushConfig) (*PushSink, error) {
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("push sink url is required")
+	}
+
+	cfg.BatchSize = valueOr(cfg.BatchSize, 1000)
+	cfg.BatchWait = valueOr(cfg.BatchWait, time.Second)
+	cfg.QueueSize = valueOr(cfg.QueueSize, 10000)
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.OnError == nil {
+		cfg.OnError = func(err error) {
+			fmt.Fprintf(os.Stderr, "log push: %v\n", err)
+		}
+	}
+
+	s := &PushSink{
+		cfg:     cfg,
+		queue:   make(chan pushEntry, cfg.QueueSize),
+		flushes: make(chan chan error),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		attrs:   metric.WithAttributes(attribute.String("url", redactedURL(cfg.URL))),
+	}
+
+	meter := otel.Meter("internal/log")
+	s.sent, _ = meter.Int64Counter("log.push.sent_entries", metric.WithDescription("Number of log entries pushed"))
+	s.dropped, _ = meter.Int64Counter("log.push.dropped_entries", metric.WithDescription("Number of log entries dropped because the push queue was full"))
+	s.failed, _ = meter.Int64Counter("log.push.failed_entries", metric.WithDescription("Number of log entries whose push request failed"))
+
+	go s.run()
+	return s, nil
+}
+
+// Write queues one encoded entry, zap calls it once per entry. It never fails, entries that do not
+// fit in the queue within BlockTimeout are dropped and counted.
+func (s *PushSink) Write(p []byte) (int, error) {
+	entry := pushEntry{time: time.Now(), line: bytes.TrimRight(bytes.Clone(p), "\n")}
+
+	select {
+	case <-s.done:
+		s.drop()
+		return len(p), nil
+	case s.queue <- entry:
+		return len(p), nil
+	default:
+	}
+
+	if s.cfg.BlockTimeout > 0 {
+		timer := time.NewTimer(s.cfg.BlockTimeout)
+		defer timer.Stop()
+
+		select {
+		case s.queue <- entry:
+			return len(p), nil
+		case <-timer.C:
+		case <-s.done:
+		}
+	}
+
+	s.drop()
+	return len(p), nil
+}
+
+// Sync pushes the queued entries and waits for the requests to complete
+func (s *PushSink) Sync() error {
+	result := make(chan error, 1)
+	select {
+	case s.flushes <- result:
+		return <-result
+	case <-s.stopped:
+		return nil
+	}
+}
+
+// Close pushes the queued entries and stops the push goroutine, entries written afterwards are dropped
+func (s *PushSink) Close() error {
+	s.closeOnce.Do(func() { close(s.done) })
+	<-s.stopped
+	return nil
+}
+
+func (s *PushSink) drop() {
+	if s.dropped != nil {
+		s.dropped.Add(context.Background(), 1, s.attrs)
+	}
+}
+
+func (s *PushSink) run() {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(s.cfg.BatchWait)
+	defer ticker.Stop()
+
+	batch := make([]pushEntry, 0, s.cfg.BatchSize)
+	send := func() error {
+		var err error
+		for {
+			for len(batch) < s.cfg.BatchSize && len(s.queue) > 0 {
+				batch = append(batch, <-s.queue)
+			}
+			if len(batch) == 0 {
+				return err
+			}
+
+			if pushErr := s.push(batch); pushErr != nil {
+				err = pushErr
+			}
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case entry := <-s.queue:
+			batch = append(batch, entry)
+			if len(batch) >= s.cfg.BatchSize {
+				_ = send()
+			}
+		case <-ticker.C:
+			_ = send()
+		case result := <-s.flushes:
+			result <- send()
+		case <-s.done:
+			_ = send()
+			return
+		}
+	}
+}
+
+func (s *PushSink) push(batch []pushEntry) error {
+	ctx := context.Background()
+
+	body, contentType, err := s.encode(batch)
+	if err == nil {
+		err = s.post(ctx, body, contentType)
+	}
+	if err != nil {
+		if s.failed != nil {
+			s.failed.Add(ctx, int64(len(batch)), s.attrs)
+		}
+		err = fmt.Errorf("failed to push %d entries: %w", len(batch), err)
+		s.cfg.OnError(err)
+		return err
+	}
+
+	if s.sent != nil {
+		s.sent.Add(ctx, int64(len(batch)), s.attrs)
+	}
+	return nil
+}
+
+func (s *PushSink) encode(batch []pushEntry) ([]byte, string, error) {
+	if s.cfg.Format == PushJSONLines {
+		var body bytes.Buffer
+		for _, entry := range batch {
+			body.Write(entry.line)
+			body.WriteByte('\n')
+		}
+		return body.Bytes(), "application/x-ndjson", nil
+	}
+
+	values := make([][2]string, 0, len(batch))
+	for _, entry := range batch {
+		values = append(values, [2]string{strconv.FormatInt(entry.time.UnixNano(), 10), string(entry.line)})
+	}
+
+	labels := s.cfg.Labels
+	if len(labels) == 0 {
+		// Loki rejects streams without labels
+		labels = map[string]string{"job": "summer"}
+	}
+	body, err := json.Marshal(map[string]any{
+		"streams": []map[string]any{{"stream": labels, "values": values}},
+	})
+	return body, "application/json", err
+}
+
+func (s *PushSink) post(ctx context.Context, body []byte, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for name, value := range s.cfg.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := s.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return nil
+}
+
+// pushConfigFromURL reads a sink URL of a zap.Config output path, see PushSink
+func pushConfigFromURL(u *url.URL) (PushConfig, error) {
+	format, ok := pushSchemes[u.Scheme]
+	if !ok {
+		return PushConfig{}, fmt.Errorf("unknown push sink scheme %q", u.Scheme)
+	}
+
+	target := *u
+	_, target.Scheme, _ = strings.Cut(u.Scheme, "+")
+	target.RawQuery = ""
+
+	cfg := PushConfig{Format: format, Labels: map[string]string{}}
+	if target.User != nil {
+		// Credentials in the URL become a basic authorization header
+		password, _ := target.User.Password()
+		request := http.Request{Header: http.Header{}}
+		request.SetBasicAuth(target.User.Username(), password)
+		cfg.Headers = map[string]string{"Authorization": request.Header.Get("Authorization")}
+		target.User = nil
+	}
+	cfg.URL = target.String()
+
+	for name, values := range u.Query() {
+		value := values[len(values)-1]
+
+		var err error
+		switch name {
+		case "batch_size":
+			cfg.BatchSize, err = strconv.Atoi(value)
+		case "queue_size":
+			cfg.QueueSize, err = strconv.Atoi(value)
+		case "batch_wait":
+			cfg.BatchWait, err = time.ParseDuration(value)
+		case "block_timeout":
+			cfg.BlockTimeout, err = time.ParseDuration(value)
+		default:
+			cfg.Labels[name] = value
+		}
+		if err != nil {
+			return PushConfig{}, fmt.Errorf("invalid push sink parameter %s=%q: %w", name, value, err)
+		}
+	}
+	return cfg, nil
+}
+
+// redactedURL drops the credentials and query of rawURL, for metric attributes
+func redactedURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	u.RawQuery = ""
+	return u.String()
+}
+
+func valueOr[T comparable](value, fallback T) T {
+	var zero T
+	if value == zero {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,102 @@
+package logutil
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const rateLimitedKey = "rate_limited_entries"
+
+// WithLevelRateLimits returns a zap.Option capping the entries written per second for each level
+// of perSecond, levels missing from it are not limited. Unlike sampling, the cap covers all the
+// messages of a level together, so a flood of distinct debug entries cannot starve the pipeline.
+// The first entry written after a limited second carries the number of entries dropped in the
+// rate_limited_entries field.
+//
+//	logger, err := logutil.ZapProductionConfig(logutil.WithSampling(100, 100, nil)).Build(
+//		logutil.WithLevelRateLimits(map[zapcore.Level]int{zapcore.InfoLevel: 1000, zapcore.WarnLevel: 200}))
+func WithLevelRateLimits(perSecond map[zapcore.Level]int) zap.Option {
+	return zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return NewRateLimitCore(core, perSecond)
+	})
+}
+
+// NewRateLimitCore wraps core with the per-level limits of WithLevelRateLimits
+func NewRateLimitCore(core zapcore.Core, perSecond map[zapcore.Level]int) zapcore.Core {
+	limiter := &levelRateLimiter{windows: map[zapcore.Level]*rateWindow{}}
+	for level, limit := range perSecond {
+		if limit > 0 {
+			limiter.windows[level] = &rateWindow{limit: limit}
+		}
+	}
+	return &rateLimitCore{Core: core, limiter: limiter}
+}
+
+type rateLimitCore struct {
+	zapcore.Core
+	limiter *levelRateLimiter
+}
+
+func (c *rateLimitCore) With(fields []zapcore.Field) zapcore.Core {
+	return &rateLimitCore{Core: c.Core.With(fields), limiter: c.limiter}
+}
+
+func (c *rateLimitCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *rateLimitCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	allowed, dropped := c.limiter.allow(entry.Level, entry.Time)
+	if !allowed {
+		return nil
+	}
+	if dropped > 0 {
+		fields = append(fields[:len(fields):len(fields)], zap.Int(rateLimitedKey, dropped))
+	}
+	return c.Core.Write(entry, fields)
+}
+
+// levelRateLimiter counts the entries of each limited level in one second windows
+type levelRateLimiter struct {
+	windows map[zapcore.Level]*rateWindow
+}
+
+type rateWindow struct {
+	mu      sync.Mutex
+	limit   int
+	start   time.Time
+	count   int
+	dropped int
+}
+
+// allow reports whether an entry of level at now fits in its window, and the number of entries
+// dropped since the last allowed one, so that it can be reported
+func (l *levelRateLimiter) allow(level zapcore.Level, now time.Time) (bool, int) {
+	window, ok := l.windows[level]
+	if !ok {
+		return true, 0
+	}
+
+	window.mu.Lock()
+	defer window.mu.Unlock()
+
+	if now.Sub(window.start) >= time.Second || now.Before(window.start) {
+		window.start = now
+		window.count = 0
+	}
+	if window.count >= window.limit {
+		window.dropped++
+		return false, 0
+	}
+
+	window.count++
+	dropped := window.dropped
+	window.dropped = 0
+	return true, dropped
+}
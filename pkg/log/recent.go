@@ -0,0 +1,157 @@
+package logutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// RecentLogs keeps the last entries of every level in memory, so operators can look at the recent
+// activity of a pod without going through the log system. Each level has its own ring of size
+// entries, a burst of debug entries does not push the last errors out. It is also an http.Handler
+// answering GET with the kept entries, filtered with the level, trace_id and limit query parameters:
+//
+//	recent := logutil.NewRecentLogs(200, zapcore.InfoLevel, func(r *http.Request) bool {
+//		return subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Debug-Token")), debugToken) == 1
+//	})
+//	logger = logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+//		return zapcore.NewTee(core, recent.Core())
+//	}))
+//	mux.Handle("/debug/logs", recent) // GET /debug/logs?level=warn&trace_id=4bf92f35...
+type RecentLogs struct {
+	buffers   map[zapcore.Level]*EntryBuffer
+	minLevel  zapcore.Level
+	authorize func(r *http.Request) bool
+}
+
+// NewRecentLogs creates a store keeping the last size entries of every level at or above minLevel.
+// authorize guards the endpoint, the entries can hold personal data, a nil authorize refuses every
+// request.
+func NewRecentLogs(size int, minLevel zapcore.Level, authorize func(r *http.Request) bool) *RecentLogs {
+	minLevel = max(minLevel, zapcore.DebugLevel)
+
+	buffers := map[zapcore.Level]*EntryBuffer{}
+	for level := minLevel; level <= zapcore.FatalLevel; level++ {
+		buffers[level] = NewEntryBuffer(size, level)
+	}
+
+	return &RecentLogs{
+		buffers:   buffers,
+		minLevel:  minLevel,
+		authorize: authorize,
+	}
+}
+
+// Core returns a core keeping entries, to be teed with the core of a logger
+func (l *RecentLogs) Core() zapcore.Core {
+	cores := make([]zapcore.Core, 0, len(l.buffers))
+	for level, buffer := range l.buffers {
+		cores = append(cores, exactLevelCore{Core: buffer.Core(), level: level})
+	}
+	return zapcore.NewTee(cores...)
+}
+
+// Entries returns the kept entries at or above level, written with traceID when it is not empty,
+// oldest first. A positive limit only returns the newest limit entries.
+func (l *RecentLogs) Entries(level zapcore.Level, traceID string, limit int) []BufferedEntry {
+	var entries []BufferedEntry
+	for bufferLevel, buffer := range l.buffers {
+		if bufferLevel < level {
+			continue
+		}
+		for _, entry := range buffer.Entries() {
+			if traceID != "" && entry.Fields["trace_id"] != traceID {
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	slices.SortStableFunc(entries, func(a, b BufferedEntry) int {
+		return a.Time.Compare(b.Time)
+	})
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries
+}
+
+type recentLogEntry struct {
+	Time    time.Time      `json:"time"`
+	Level   string         `json:"level"`
+	Message string         `json:"message"`
+	Fields  map[string]any `json:"fields,omitempty"`
+}
+
+func (l *RecentLogs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if l.authorize == nil || !l.authorize(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+
+	level := l.minLevel
+	if levelText := query.Get("level"); levelText != "" {
+		var err error
+		level, err = zapcore.ParseLevel(levelText)
+		if err != nil {
+			http.Error(w, "invalid level: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := 0
+	if limitText := query.Get("limit"); limitText != "" {
+		var err error
+		limit, err = strconv.Atoi(limitText)
+		if err != nil || limit < 0 {
+			http.Error(w, "invalid limit, expected a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	entries := l.Entries(level, query.Get("trace_id"), limit)
+	payload := make([]recentLogEntry, 0, len(entries))
+	for _, entry := range entries {
+		payload = append(payload, recentLogEntry{
+			Time:    entry.Time,
+			Level:   entry.Level.String(),
+			Message: entry.Message,
+			Fields:  entry.Fields,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(map[string]any{"entries": payload})
+}
+
+// exactLevelCore only lets the entries of one level through, so each level fills its own buffer
+type exactLevelCore struct {
+	zapcore.Core
+	level zapcore.Level
+}
+
+func (c exactLevelCore) Enabled(level zapcore.Level) bool {
+	return level == c.level
+}
+
+func (c exactLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return exactLevelCore{Core: c.Core.With(fields), level: c.level}
+}
+
+func (c exactLevelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.Level != c.level {
+		return checked
+	}
+	return c.Core.Check(entry, checked)
+}
@@ -0,0 +1,316 @@
+package logutil
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// Rotation configures a RotatingFile, a zero MaxAge or MaxBackups keeps the backups forever
+type Rotation struct {
+	// MaxSize is the size in megabytes a file reaches before it is rotated, 100 when zero
+	MaxSize int
+	// MaxAge removes the backups rotated longer ago
+	MaxAge time.Duration
+	// MaxBackups is the number of backups kept
+	MaxBackups int
+	// Compress gzips the backups
+	Compress bool
+}
+
+// WithFileOutput adds a rotating file at path to the outputs of the config, next to stdout, for
+// deployments without a log collector. The directory is created when missing.
+//
+//	config := logutil.ZapProductionConfig(logutil.WithFileOutput("/var/log/core/app.log", logutil.Rotation{
+//		MaxSize: 100, MaxAge: 7 * 24 * time.Hour, MaxBackups: 10, Compress: true,
+//	}))
+func WithFileOutput(path string, rotation Rotation) ConfigOption {
+	return func(config *zap.Config) {
+		config.OutputPaths = append(config.OutputPaths, rotationURL(path, rotation))
+	}
+}
+
+// rotationURL is the zap sink URL of a rotating file, e.g.
+// rotate:///var/log/app.log?max_size=100&max_age=168h&max_backups=10&compress=true
+func rotationURL(path string, rotation Rotation) string {
+	absolute, err := filepath.Abs(path)
+	if err == nil {
+		path = absolute
+	}
+
+	query := url.Values{}
+	if rotation.MaxSize > 0 {
+		query.Set("max_size", strconv.Itoa(rotation.MaxSize))
+	}
+	if rotation.MaxAge > 0 {
+		query.Set("max_age", rotation.MaxAge.String())
+	}
+	if rotation.MaxBackups > 0 {
+		query.Set("max_backups", strconv.Itoa(rotation.MaxBackups))
+	}
+	if rotation.Compress {
+		query.Set("compress", "true")
+	}
+
+	u := url.URL{Scheme: "rotate", Path: filepath.ToSlash(path), RawQuery: query.Encode()}
+	return u.String()
+}
+
+func init() {
+	_ = zap.RegisterSink("rotate", func(u *url.URL) (zap.Sink, error) {
+		var rotation Rotation
+		var err error
+		query := u.Query()
+		if value := query.Get("max_size"); value != "" && err == nil {
+			rotation.MaxSize, err = strconv.Atoi(value)
+		}
+		if value := query.Get("max_age"); value != "" && err == nil {
+			rotation.MaxAge, err = time.ParseDuration(value)
+		}
+		if value := query.Get("max_backups"); value != "" && err == nil {
+			rotation.MaxBackups, err = strconv.Atoi(value)
+		}
+		if value := query.Get("compress"); value != "" && err == nil {
+			rotation.Compress, err = strconv.ParseBool(value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid rotation parameter in %s: %w", u, err)
+		}
+
+		return NewRotatingFile(filepath.FromSlash(u.Path), rotation)
+	})
+}
+
+// RotatingFile is a zap.Sink appending to a file, which is renamed with its rotation time, e.g.
+// app-2024-05-01T10-00-00.000.log, and replaced by a new one once it reaches MaxSize. Old backups
+// are compressed and removed in the background.
+type RotatingFile struct {
+	path     string
+	rotation Rotation
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+
+	cleanupMu sync.Mutex
+}
+
+// NewRotatingFile opens path for appending, creating it and its directory when missing
+func NewRotatingFile(path string, rotation Rotation) (*RotatingFile, error) {
+	if rotation.MaxSize <= 0 {
+		rotation.MaxSize = 100
+	}
+
+	f := &RotatingFile{path: path, rotation: rotation}
+	err := f.open()
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, errors.New("rotating file is closed")
+	}
+
+	if f.size > 0 && f.size+int64(len(p)) > f.maxBytes() {
+		err := f.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *RotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Sync()
+}
+
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// Rotate rotates the file now, e.g. on SIGHUP
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.rotate()
+}
+
+func (f *RotatingFile) maxBytes() int64 {
+	return int64(f.rotation.MaxSize) * 1024 * 1024
+}
+
+func (f *RotatingFile) open() error {
+	err := os.MkdirAll(filepath.Dir(f.path), 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *RotatingFile) rotate() error {
+	if f.file != nil {
+		err := f.file.Close()
+		f.file = nil
+		if err != nil {
+			return fmt.Errorf("failed to close log file: %w", err)
+		}
+	}
+
+	err := os.Rename(f.path, f.backupName(time.Now()))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to rename log file: %w", err)
+	}
+
+	err = f.open()
+	if err != nil {
+		return err
+	}
+
+	go f.cleanup()
+	return nil
+}
+
+func (f *RotatingFile) backupName(t time.Time) string {
+	dir := filepath.Dir(f.path)
+	ext := filepath.Ext(f.path)
+	base := strings.TrimSuffix(filepath.Base(f.path), ext)
+	return filepath.Join(dir, base+"-"+t.Format(backupTimeFormat)+ext)
+}
+
+type logBackup struct {
+	path string
+	time time.Time
+}
+
+// cleanup compresses the backups and removes the ones beyond MaxBackups or older than MaxAge,
+// errors are ignored so that the next rotation retries
+func (f *RotatingFile) cleanup() {
+	f.cleanupMu.Lock()
+	defer f.cleanupMu.Unlock()
+
+	backups := f.backups()
+
+	var cutoff time.Time
+	if f.rotation.MaxAge > 0 {
+		cutoff = time.Now().Add(-f.rotation.MaxAge)
+	}
+
+	for i, backup := range backups {
+		if (f.rotation.MaxBackups > 0 && i >= f.rotation.MaxBackups) || (!cutoff.IsZero() && backup.time.Before(cutoff)) {
+			_ = os.Remove(backup.path)
+			continue
+		}
+		if f.rotation.Compress && !strings.HasSuffix(backup.path, ".gz") {
+			_ = compressFile(backup.path)
+		}
+	}
+}
+
+// backups lists the backups of the file, newest first
+func (f *RotatingFile) backups() []logBackup {
+	dir := filepath.Dir(f.path)
+	ext := filepath.Ext(f.path)
+	prefix := strings.TrimSuffix(filepath.Base(f.path), ext) + "-"
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var backups []logBackup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+		t, err := time.ParseInLocation(backupTimeFormat, stamp, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, logBackup{path: filepath.Join(dir, name), time: t})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].time.After(backups[j].time)
+	})
+	return backups
+}
+
+func compressFile(path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+
+	writer := gzip.NewWriter(target)
+	_, err = io.Copy(writer, source)
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := target.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path + ".gz")
+		return err
+	}
+
+	return os.Remove(path)
+}
@@ -0,0 +1,8 @@
+module github.com/NYCU-SDC/summer/pkg/log/sentrylog
+
+go 1.26.2
+
+require (
+	github.com/getsentry/sentry-go v0.32.0
+	go.uber.org/zap v1.27.0
+)
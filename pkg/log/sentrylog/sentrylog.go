@@ -0,0 +1,168 @@
+// Package sentrylog forwards zap entries to Sentry through sentry-go. It is a separate module so
+// that services not reporting to Sentry do not depend on the SDK.
+package sentrylog
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const flushTimeout = 5 * time.Second
+
+// Options configures WithSentry and NewCore
+type Options struct {
+	Environment string
+	Release     string
+	ServerName  string
+	// Tags are added to every event, trace_id is added from the entry fields
+	Tags map[string]string
+
+	// Level is the lowest level forwarded, error when lower
+	Level zapcore.Level
+}
+
+// WithSentry returns logger also forwarding Error, DPanic, Panic and Fatal entries to the Sentry
+// project of dsn, with the environment, release and tags of opts. The trace_id and span_id fields
+// added by logutil.WithContext become the trace context of the event, so an issue links to its
+// trace, and the panics logged by traceutil.RecoverMiddleware are reported as unhandled
+// exceptions with their stack. sentry-go sends the events in the background, logger.Sync waits
+// for them:
+//
+//	logger, err = sentrylog.WithSentry(logger, os.Getenv("SENTRY_DSN"), sentrylog.Options{
+//		Environment: "production",
+//		Release:     version,
+//	})
+//	defer logger.Sync()
+//
+// An empty dsn returns logger unchanged, so local runs need no Sentry project.
+func WithSentry(logger *zap.Logger, dsn string, opts Options) (*zap.Logger, error) {
+	if dsn == "" {
+		return logger, nil
+	}
+
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:         dsn,
+		Environment: opts.Environment,
+		Release:     opts.Release,
+		ServerName:  opts.ServerName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sentry client: %w", err)
+	}
+
+	core := NewCore(client, opts)
+	return logger.WithOptions(zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(c, core)
+	})), nil
+}
+
+// NewCore returns a core capturing the entries at opts.Level and above as events of client, for
+// a client shared with the rest of the service
+func NewCore(client *sentry.Client, opts Options) zapcore.Core {
+	return &core{
+		LevelEnabler: max(opts.Level, zapcore.ErrorLevel),
+		client:       client,
+		tags:         opts.Tags,
+	}
+}
+
+type core struct {
+	zapcore.LevelEnabler
+	client *sentry.Client
+	tags   map[string]string
+	fields []zapcore.Field
+}
+
+func (c *core) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+	return &clone
+}
+
+func (c *core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	encoder := zapcore.NewMapObjectEncoder()
+	var err error
+	for _, field := range append(c.fields[:len(c.fields):len(c.fields)], fields...) {
+		field.AddTo(encoder)
+		if field.Type == zapcore.ErrorType && field.Key == "error" {
+			err, _ = field.Interface.(error)
+		}
+	}
+
+	c.client.CaptureEvent(newEvent(entry, encoder.Fields, err, c.tags), nil, sentry.NewScope())
+
+	// Fatal and Panic entries end the process or the goroutine right after, send them now
+	if entry.Level > zapcore.DPanicLevel {
+		return c.Sync()
+	}
+	return nil
+}
+
+// Sync waits for the captured events to be sent
+func (c *core) Sync() error {
+	if !c.client.Flush(flushTimeout) {
+		return errors.New("sentry: timed out sending events")
+	}
+	return nil
+}
+
+func newEvent(entry zapcore.Entry, fields map[string]any, err error, tags map[string]string) *sentry.Event {
+	event := sentry.NewEvent()
+	event.Level = sentry.LevelError
+	if entry.Level >= zapcore.PanicLevel {
+		event.Level = sentry.LevelFatal
+	}
+	event.Message = entry.Message
+	event.Logger = entry.LoggerName
+	event.Timestamp = entry.Time
+	for name, value := range tags {
+		event.Tags[name] = value
+	}
+
+	if traceID, _ := fields["trace_id"].(string); traceID != "" {
+		spanID, _ := fields["span_id"].(string)
+		event.Tags["trace_id"] = traceID
+		event.Contexts["trace"] = sentry.Context{"trace_id": traceID, "span_id": spanID}
+		delete(fields, "trace_id")
+		delete(fields, "span_id")
+	}
+
+	// traceutil.RecoverMiddleware logs the panic value as error and the callers as trace, the
+	// stack of the recovering goroutine still holds the panicking frames
+	if recovered, _ := fields["panic"].(bool); recovered {
+		handled := false
+		event.Exception = []sentry.Exception{{
+			Type:       "panic",
+			Value:      fmt.Sprint(fields["error"]),
+			Mechanism:  &sentry.Mechanism{Type: "panic", Handled: &handled},
+			Stacktrace: sentry.NewStacktrace(),
+		}}
+		delete(fields, "trace")
+	} else if err != nil {
+		stacktrace := sentry.ExtractStacktrace(err)
+		if stacktrace == nil {
+			stacktrace = sentry.NewStacktrace()
+		}
+		event.Exception = []sentry.Exception{{
+			Type:       reflect.TypeOf(err).String(),
+			Value:      err.Error(),
+			Stacktrace: stacktrace,
+		}}
+	}
+
+	event.Extra = fields
+	return event
+}
@@ -0,0 +1,128 @@
+package sentrylog
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// newTestLogger returns a logger whose events are captured by BeforeSend instead of being sent
+func newTestLogger(t *testing.T, opts Options) (*zap.Logger, *[]*sentry.Event) {
+	t.Helper()
+
+	var events []*sentry.Event
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn: "https://public@sentry.example.com/1",
+		BeforeSend: func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			events = append(events, event)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("sentry.NewClient() unexpected error: %v", err)
+	}
+
+	return zap.New(NewCore(client, opts)), &events
+}
+
+func TestCore_Levels(t *testing.T) {
+	tests := []struct {
+		name      string
+		level     zapcore.Level
+		optsLevel zapcore.Level
+		wantEvent bool
+		wantLevel sentry.Level
+	}{
+		{name: "Should ignore warn entries", level: zapcore.WarnLevel, wantEvent: false},
+		{name: "Should forward error entries", level: zapcore.ErrorLevel, wantEvent: true, wantLevel: sentry.LevelError},
+		{name: "Should forward dpanic entries as errors", level: zapcore.DPanicLevel, wantEvent: true, wantLevel: sentry.LevelError},
+		{name: "Should not lower the level below error", level: zapcore.WarnLevel, optsLevel: zapcore.DebugLevel, wantEvent: false},
+		{name: "Should ignore entries below a raised level", level: zapcore.ErrorLevel, optsLevel: zapcore.DPanicLevel, wantEvent: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, events := newTestLogger(t, Options{Level: tt.optsLevel})
+
+			if ce := logger.Check(tt.level, "something failed"); ce != nil {
+				ce.Write()
+			}
+
+			if got := len(*events) == 1; got != tt.wantEvent {
+				t.Fatalf("got %d events, want event %t", len(*events), tt.wantEvent)
+			}
+			if tt.wantEvent && (*events)[0].Level != tt.wantLevel {
+				t.Errorf("level = %q, want %q", (*events)[0].Level, tt.wantLevel)
+			}
+		})
+	}
+}
+
+func TestCore_Event(t *testing.T) {
+	logger, events := newTestLogger(t, Options{Tags: map[string]string{"service": "core-system"}})
+
+	logger.Named("user").With(zap.String("trace_id", "4bf92f3577b34da6a3ce929d0e0e4736")).Error("Failed to load user",
+		zap.String("span_id", "00f067aa0ba902b7"),
+		zap.String("user_id", "u1"),
+		zap.Error(errors.New("connection refused")),
+	)
+
+	if len(*events) != 1 {
+		t.Fatalf("got %d events, want 1", len(*events))
+	}
+	event := (*events)[0]
+
+	if event.Message != "Failed to load user" || event.Logger != "user" {
+		t.Errorf("message = %q logger = %q, want %q and %q", event.Message, event.Logger, "Failed to load user", "user")
+	}
+	if event.Tags["service"] != "core-system" || event.Tags["trace_id"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("tags = %v, want service and trace_id", event.Tags)
+	}
+	if got := event.Contexts["trace"]["span_id"]; got != "00f067aa0ba902b7" {
+		t.Errorf("trace context span_id = %v, want %q", got, "00f067aa0ba902b7")
+	}
+	if event.Extra["user_id"] != "u1" {
+		t.Errorf("extra = %v, want user_id", event.Extra)
+	}
+	if _, ok := event.Extra["trace_id"]; ok {
+		t.Errorf("extra = %v, want trace_id moved to the trace context", event.Extra)
+	}
+	if len(event.Exception) != 1 || event.Exception[0].Value != "connection refused" || event.Exception[0].Stacktrace == nil {
+		t.Errorf("exception = %+v, want the logged error with a stacktrace", event.Exception)
+	}
+}
+
+func TestCore_Panic(t *testing.T) {
+	logger, events := newTestLogger(t, Options{})
+
+	logger.Error("Recovered from panic", zap.Any("error", "boom"), zap.String("trace", "[main.go:10]"), zap.Bool("panic", true))
+
+	if len(*events) != 1 || len((*events)[0].Exception) != 1 {
+		t.Fatalf("got events %+v, want one with an exception", *events)
+	}
+	exception := (*events)[0].Exception[0]
+	if exception.Type != "panic" || exception.Value != "boom" {
+		t.Errorf("exception = %q %q, want panic boom", exception.Type, exception.Value)
+	}
+	if exception.Mechanism == nil || exception.Mechanism.Handled == nil || *exception.Mechanism.Handled {
+		t.Errorf("mechanism = %+v, want unhandled", exception.Mechanism)
+	}
+	if exception.Stacktrace == nil || len(exception.Stacktrace.Frames) == 0 {
+		t.Error("exception has no stacktrace")
+	}
+}
+
+func TestWithSentry_EmptyDSN(t *testing.T) {
+	logger := zap.NewNop()
+
+	got, err := WithSentry(logger, "", Options{})
+	if err != nil {
+		t.Fatalf("WithSentry() unexpected error: %v", err)
+	}
+	if got != logger {
+		t.Error("WithSentry() with an empty dsn should return the logger unchanged")
+	}
+}
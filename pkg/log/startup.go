@@ -0,0 +1,93 @@
+package logutil
+
+import (
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sort"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// StartupInfo describes a service as it starts, see LogStartup
+type StartupInfo struct {
+	Service string
+	Version string
+
+	// Commit defaults to the VCS revision embedded by the Go toolchain, if any
+	Commit string
+
+	// ConfigSources lists where the configuration was read from, in order of precedence,
+	// e.g. "env", "config.yaml", "defaults"
+	ConfigSources []string
+
+	// Features are the optional features and whether they are enabled, e.g. "tracing": true
+	Features map[string]bool
+
+	// Addresses are the addresses the service listens on by name, e.g. "http": ":8080"
+	Addresses map[string]string
+}
+
+// LogStartup writes the first log line of a service, a single Info entry summarizing what is
+// starting and how, so every service can be identified the same way from its logs
+func LogStartup(logger *zap.Logger, info StartupInfo) {
+	commit := info.Commit
+	if commit == "" {
+		commit = vcsRevision()
+	}
+
+	var enabled, disabled []string
+	for name, on := range info.Features {
+		if on {
+			enabled = append(enabled, name)
+		} else {
+			disabled = append(disabled, name)
+		}
+	}
+	sort.Strings(enabled)
+	sort.Strings(disabled)
+
+	hostname, _ := os.Hostname()
+
+	logger.WithOptions(zap.AddCallerSkip(1)).Info("Starting "+info.Service,
+		zap.String("service", info.Service),
+		zap.String("version", info.Version),
+		zap.String("commit", commit),
+		zap.String("go_version", runtime.Version()),
+		zap.String("hostname", hostname),
+		zap.Int("pid", os.Getpid()),
+		zap.Strings("config_sources", info.ConfigSources),
+		zap.Strings("features_enabled", enabled),
+		zap.Strings("features_disabled", disabled),
+		zap.Object("addresses", addressesObject(info.Addresses)),
+	)
+}
+
+func vcsRevision() string {
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, setting := range buildInfo.Settings {
+		if setting.Key == "vcs.revision" {
+			return setting.Value
+		}
+	}
+	return ""
+}
+
+type addressesObject map[string]string
+
+func (a addressesObject) MarshalLogObject(encoder zapcore.ObjectEncoder) error {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		encoder.AddString(name, a[name])
+	}
+	return nil
+}
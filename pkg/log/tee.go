@@ -0,0 +1,130 @@
+package logutil
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// SinkConfig is one destination of a tee logger. Output is "stdout", "stderr", a file path or the
+// name of a sink registered with RegisterSink, Encoding is "console" or "json" and Level defaults to info.
+type SinkConfig struct {
+	Output   string `yaml:"output"`
+	Encoding string `yaml:"encoding"`
+	Level    string `yaml:"level"`
+}
+
+// TeeConfig writes every entry to all of its sinks, each filtering by its own level, e.g.
+// console at info on stdout, json at debug to a file and warn and above to an OTLP sink
+type TeeConfig struct {
+	Sinks []SinkConfig `yaml:"sinks"`
+}
+
+// SinkFactory builds the core of a registered sink, level is the parsed Level of sink
+type SinkFactory func(sink SinkConfig, level zapcore.Level) (zapcore.Core, error)
+
+var (
+	sinkFactoriesMu sync.RWMutex
+	sinkFactories   = map[string]SinkFactory{}
+)
+
+// RegisterSink makes name usable as a sink Output, typically to bridge to an OTLP log exporter
+// that the service already depends on
+func RegisterSink(name string, factory SinkFactory) {
+	sinkFactoriesMu.Lock()
+	defer sinkFactoriesMu.Unlock()
+
+	sinkFactories[name] = factory
+}
+
+func lookupSinkFactory(name string) (SinkFactory, bool) {
+	sinkFactoriesMu.RLock()
+	defer sinkFactoriesMu.RUnlock()
+
+	factory, ok := sinkFactories[name]
+	return factory, ok
+}
+
+// Core builds the tee of all sinks, the returned function closes the files opened for them
+func (c TeeConfig) Core() (zapcore.Core, func(), error) {
+	if len(c.Sinks) == 0 {
+		return nil, nil, errors.New("tee config has no sinks")
+	}
+
+	cores := make([]zapcore.Core, 0, len(c.Sinks))
+	var closers []func()
+	closeAll := func() {
+		for _, closeSink := range closers {
+			closeSink()
+		}
+	}
+
+	for i, sink := range c.Sinks {
+		core, closeSink, err := sink.core()
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("sink %d (%s): %w", i, sink.Output, err)
+		}
+		cores = append(cores, core)
+		if closeSink != nil {
+			closers = append(closers, closeSink)
+		}
+	}
+
+	return zapcore.NewTee(cores...), closeAll, nil
+}
+
+// Build returns a logger writing to all sinks with caller information, call the returned function
+// after the last entry was written to close the sink files
+func (c TeeConfig) Build(opts ...zap.Option) (*zap.Logger, func(), error) {
+	core, closeSinks, err := c.Core()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	logger := zap.New(core, append([]zap.Option{zap.AddCaller()}, opts...)...)
+	return logger, func() {
+		_ = logger.Sync()
+		closeSinks()
+	}, nil
+}
+
+func (s SinkConfig) core() (zapcore.Core, func(), error) {
+	level := zapcore.InfoLevel
+	if s.Level != "" {
+		parsed, err := zapcore.ParseLevel(s.Level)
+		if err != nil {
+			return nil, nil, err
+		}
+		level = parsed
+	}
+
+	if factory, ok := lookupSinkFactory(s.Output); ok {
+		core, err := factory(s, level)
+		return core, nil, err
+	}
+
+	var encoder zapcore.Encoder
+	switch s.Encoding {
+	case "", "json":
+		encoder = zapcore.NewJSONEncoder(ZapProductionConfig().EncoderConfig)
+	case "console":
+		encoder = zapcore.NewConsoleEncoder(ZapDevelopmentConfig().EncoderConfig)
+	default:
+		return nil, nil, fmt.Errorf("unknown encoding %q, expected console or json", s.Encoding)
+	}
+
+	output := s.Output
+	if output == "" {
+		output = "stdout"
+	}
+	writer, closeOutput, err := zap.Open(output)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return zapcore.NewCore(encoder, writer, level), closeOutput, nil
+}
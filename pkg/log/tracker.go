@@ -0,0 +1,147 @@
+package logutil
+
+import (
+	"context"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// Tracker records the operations of one kind, e.g. database queries or service methods:
+//
+//	ctx, done := tracker.Track(ctx, "GetUser", zap.String("user_id", id))
+//	user, err := s.queries.GetUser(ctx, id)
+//	done(user, err)
+//
+// Every operation is logged with its duration and counted on the request's cost counter under
+// "<kind>.operations", so TraceMiddleware records the per-request counts on the request span.
+// The fields passed to Track and the sampled results are bounded by DefaultFieldLimits unless
+// WithParamLimits is given.
+type Tracker interface {
+	Track(ctx context.Context, operation string, fields ...zap.Field) (context.Context, TrackDone)
+}
+
+// TrackDone ends an operation started with Tracker.Track, result is only logged when sampled
+type TrackDone func(result any, err error)
+
+// TrackerOption configures a tracker
+type TrackerOption func(*tracker)
+
+// WithSpan starts a span named after the operation for every tracked operation
+func WithSpan(tracerName string) TrackerOption {
+	return func(t *tracker) {
+		t.tracer = otel.Tracer(tracerName)
+	}
+}
+
+// WithThreshold logs the operations taking longer than threshold at Warn instead of Debug
+func WithThreshold(threshold time.Duration) TrackerOption {
+	return func(t *tracker) {
+		t.threshold = threshold
+	}
+}
+
+// WithResultSampling logs the result of a fraction (0-1) of the successful operations
+func WithResultSampling(rate float64) TrackerOption {
+	return func(t *tracker) {
+		t.resultSampleRate = rate
+	}
+}
+
+// WithParamLimits replaces the DefaultFieldLimits applied to the parameters and results of the operations
+func WithParamLimits(limits FieldLimits) TrackerOption {
+	return func(t *tracker) {
+		t.paramLimits = limits
+	}
+}
+
+type tracker struct {
+	kind   string
+	logger *zap.Logger
+
+	tracer           trace.Tracer
+	threshold        time.Duration
+	resultSampleRate float64
+	paramLimits      FieldLimits
+}
+
+// NewTracker creates a tracker for operations of kind, such as "cache" or "http"
+func NewTracker(kind string, logger *zap.Logger, opts ...TrackerOption) Tracker {
+	t := &tracker{kind: kind, logger: logger, paramLimits: DefaultFieldLimits()}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// NewDBTracker creates a tracker for database operations
+func NewDBTracker(logger *zap.Logger, opts ...TrackerOption) Tracker {
+	return NewTracker("db", logger, opts...)
+}
+
+// NewMethodTracker creates a tracker for service and repository methods
+func NewMethodTracker(logger *zap.Logger, opts ...TrackerOption) Tracker {
+	return NewTracker("method", logger, opts...)
+}
+
+func (t *tracker) Track(ctx context.Context, operation string, fields ...zap.Field) (context.Context, TrackDone) {
+	start := time.Now()
+
+	var span trace.Span
+	if t.tracer != nil {
+		ctx, span = t.tracer.Start(ctx, operation, trace.WithAttributes(attribute.String("operation.kind", t.kind)))
+	}
+
+	AddCost(ctx, t.kind+".operations", 1)
+
+	logger := WithContext(ctx, t.logger).WithOptions(zap.AddCallerSkip(1))
+
+	return ctx, func(result any, err error) {
+		duration := time.Since(start)
+
+		entryFields := []zap.Field{
+			zap.String("kind", t.kind),
+			zap.String("operation", operation),
+			zap.Duration("duration", duration),
+		}
+
+		if span != nil {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}
+
+		switch {
+		case err != nil:
+			entryFields = append(entryFields, normalizeParams(fields, t.paramLimits)...)
+			logger.Warn("Operation failed", append(entryFields, zap.Error(err))...)
+		case t.threshold > 0 && duration > t.threshold:
+			entryFields = append(entryFields, normalizeParams(fields, t.paramLimits)...)
+			logger.Warn("Slow operation", append(entryFields, zap.Duration("threshold", t.threshold))...)
+		default:
+			params := fields
+			if t.resultSampleRate > 0 && rand.Float64() < t.resultSampleRate {
+				params = append(slices.Clip(fields), zap.Any("result", result))
+			}
+			logger.Debug("Operation completed", append(entryFields, normalizeParams(params, t.paramLimits)...)...)
+		}
+	}
+}
+
+// normalizeParams applies limits to the parameters and the result of an operation, so that a large
+// request or query result cannot turn into a multi-megabyte entry even when the logger has no
+// WithFieldLimits. Depth, string length and value count are cut with the same markers as the
+// limiting core, and the fields beyond MaxFields are counted in a "truncated_fields" field.
+func normalizeParams(params []zap.Field, limits FieldLimits) []zap.Field {
+	core := limitCore{limits: limits}
+	normalized, _ := core.limitFields(params)
+	return normalized
+}
@@ -0,0 +1,92 @@
+package logutil
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type trackerTestRequest struct {
+	Name     string              `json:"name"`
+	Tags     []string            `json:"tags"`
+	Children *trackerTestRequest `json:"children"`
+}
+
+func TestNormalizeParams(t *testing.T) {
+	nested := &trackerTestRequest{Name: "root", Children: &trackerTestRequest{Name: "child", Children: &trackerTestRequest{Name: "grandchild"}}}
+
+	tests := []struct {
+		name   string
+		limits FieldLimits
+		params []zap.Field
+		want   map[string]any
+	}{
+		{
+			name:   "Should cut nested structures at MaxDepth",
+			limits: FieldLimits{MaxDepth: 1},
+			params: []zap.Field{zap.Any("request", nested)},
+			want: map[string]any{
+				"request": map[string]any{
+					"name":     "root",
+					"tags":     maxDepthMarker,
+					"children": maxDepthMarker,
+				},
+			},
+		},
+		{
+			name:   "Should cut long strings at MaxStringLength",
+			limits: FieldLimits{MaxStringLength: 4},
+			params: []zap.Field{zap.String("query", "SELECT 1")},
+			want:   map[string]any{"query": "SELE...(truncated 4 bytes)"},
+		},
+		{
+			name:   "Should count the params dropped beyond MaxFields",
+			limits: FieldLimits{MaxFields: 1},
+			params: []zap.Field{zap.String("a", "1"), zap.String("b", "2"), zap.String("c", "3")},
+			want:   map[string]any{"a": "1", truncatedFieldsKey: int64(2)},
+		},
+		{
+			name:   "Should keep params within the limits",
+			limits: DefaultFieldLimits(),
+			params: []zap.Field{zap.String("id", "u1"), zap.Int("page", 2)},
+			want:   map[string]any{"id": "u1", "page": int64(2)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder := zapcore.NewMapObjectEncoder()
+			for _, field := range normalizeParams(tt.params, tt.limits) {
+				field.AddTo(encoder)
+			}
+
+			if !reflect.DeepEqual(encoder.Fields, tt.want) {
+				t.Errorf("normalizeParams() = %#v, want %#v", encoder.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestTracker_LimitsParams(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	tracker := NewMethodTracker(zap.New(core), WithParamLimits(FieldLimits{MaxStringLength: 8}), WithResultSampling(1))
+
+	_, done := tracker.Track(context.Background(), "CreateUser", zap.String("name", strings.Repeat("a", 1<<20)))
+	done(strings.Repeat("b", 1<<20), nil)
+
+	entry := logs.All()[0].ContextMap()
+	if got, want := entry["name"], "aaaaaaaa...(truncated 1048568 bytes)"; got != want {
+		t.Errorf("name = %q, want %q", got, want)
+	}
+	if got, want := entry["result"], "bbbbbbbb...(truncated 1048568 bytes)"; got != want {
+		t.Errorf("result = %q, want %q", got, want)
+	}
+	if got, want := entry["operation"], "CreateUser"; got != want {
+		t.Errorf("operation = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"net/http"
+
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const CanaryHeader = "X-Canary"
+
+// CanaryMatcher decides whether a request goes to the canary handler, reason describes
+// the rule that matched and ends up in the span and the log entry
+type CanaryMatcher func(r *http.Request) (matched bool, reason string)
+
+// CanaryByHeader matches requests carrying the header with the given value, an empty value matches any value
+func CanaryByHeader(name, value string) CanaryMatcher {
+	return func(r *http.Request) (bool, string) {
+		got := r.Header.Get(name)
+		if got == "" || (value != "" && got != value) {
+			return false, ""
+		}
+		return true, "header " + name
+	}
+}
+
+// CanaryByCookie matches requests carrying the cookie with the given value, an empty value matches any value
+func CanaryByCookie(name, value string) CanaryMatcher {
+	return func(r *http.Request) (bool, string) {
+		cookie, err := r.Cookie(name)
+		if err != nil || (value != "" && cookie.Value != value) {
+			return false, ""
+		}
+		return true, "cookie " + name
+	}
+}
+
+// CanaryByPercent matches percent (0-100) of the traffic. Requests with a "user_id" context
+// value are assigned by hashing it with salt so a user sticks to one implementation,
+// anonymous requests are assigned at random.
+func CanaryByPercent(percent float64, salt string) CanaryMatcher {
+	return func(r *http.Request) (bool, string) {
+		if percent <= 0 {
+			return false, ""
+		}
+
+		var bucket float64
+		if userID := r.Context().Value("user_id"); userID != nil {
+			h := fnv.New32a()
+			_, _ = h.Write([]byte(fmt.Sprintf("%s:%v", salt, userID)))
+			bucket = float64(h.Sum32()%10000) / 100
+		} else {
+			bucket = rand.Float64() * 100
+		}
+
+		if bucket >= percent {
+			return false, ""
+		}
+		return true, fmt.Sprintf("%g%% rollout", percent)
+	}
+}
+
+// AnyCanary matches when one of the matchers does, checking them in order
+func AnyCanary(matchers ...CanaryMatcher) CanaryMatcher {
+	return func(r *http.Request) (bool, string) {
+		for _, matcher := range matchers {
+			if matched, reason := matcher(r); matched {
+				return true, reason
+			}
+		}
+		return false, ""
+	}
+}
+
+// Canary routes the requests accepted by matcher to canaryHandler instead of next, to roll out a
+// rewritten endpoint in-process. The assignment is exposed with the X-Canary response header,
+// span attributes and a debug log entry.
+func Canary(next http.HandlerFunc, logger *zap.Logger, matcher CanaryMatcher, canaryHandler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		span := trace.SpanFromContext(ctx)
+
+		matched, reason := matcher(r)
+		if !matched {
+			span.SetAttributes(attribute.Bool("canary", false))
+			next(w, r)
+			return
+		}
+
+		w.Header().Set(CanaryHeader, "true")
+		span.SetAttributes(attribute.Bool("canary", true), attribute.String("canary.reason", reason))
+		logutil.WithContext(ctx, logger).Debug("Routed request to canary", zap.String("reason", reason), zap.String("path", r.URL.Path))
+
+		canaryHandler(w, r)
+	}
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const DebugTokenHeader = "X-Debug-Token"
+
+var ErrInvalidDebugToken = errors.New("invalid debug token")
+
+// debugClaims is the signed payload of a debug token, Subject tells who asked for it
+type debugClaims struct {
+	Subject   string `json:"sub"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// NewDebugToken issues a token for the X-Debug-Token header, valid for ttl. subject is logged
+// with every request using it, e.g. the engineer or the ticket the token was issued for.
+func NewDebugToken(secret []byte, subject string, ttl time.Duration) (string, error) {
+	payload, err := json.Marshal(debugClaims{Subject: subject, ExpiresAt: time.Now().Add(ttl).Unix()})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal debug token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(signDebugToken(secret, payload)), nil
+}
+
+// VerifyDebugToken checks the signature and expiry of token and returns its subject
+func VerifyDebugToken(secret []byte, token string) (string, error) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return "", fmt.Errorf("%w: malformed token", ErrInvalidDebugToken)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed encoding", ErrInvalidDebugToken)
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, signDebugToken(secret, payload)) {
+		return "", fmt.Errorf("%w: invalid signature", ErrInvalidDebugToken)
+	}
+
+	var claims debugClaims
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed payload", ErrInvalidDebugToken)
+	}
+
+	if time.Now().Unix() > claims.ExpiresAt {
+		return "", fmt.Errorf("%w: expired", ErrInvalidDebugToken)
+	}
+	return claims.Subject, nil
+}
+
+func signDebugToken(secret, payload []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// DebugMiddleware marks requests carrying a valid X-Debug-Token with logutil.WithDebug, for that
+// request only the loggers from logutil.WithContext write debug entries, traceutil.DebugSampler
+// samples its spans and problem.HttpWriter includes the raw error in the response. Put it in front
+// of TraceMiddleware so the mark is present when the request span is started. Requests with an
+// invalid token are handled as if they had none, no secret disables the middleware.
+func DebugMiddleware(next http.HandlerFunc, logger *zap.Logger, secret []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get(DebugTokenHeader)
+		if token == "" || len(secret) == 0 {
+			next(w, r)
+			return
+		}
+
+		ctx := r.Context()
+
+		subject, err := VerifyDebugToken(secret, token)
+		if err != nil {
+			logutil.WithContext(ctx, logger).Warn("Ignored invalid debug token", zap.Error(err), zap.String("path", r.URL.Path))
+			next(w, r)
+			return
+		}
+
+		ctx = logutil.WithDebug(ctx)
+
+		trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("debug", true), attribute.String("debug.subject", subject))
+		logutil.WithContext(ctx, logger).Debug("Enabled debug mode for request", zap.String("subject", subject), zap.String("path", r.URL.Path))
+
+		next(w, r.WithContext(ctx))
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NYCU-SDC/summer/pkg/handler"
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"github.com/NYCU-SDC/summer/pkg/problem"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// DependencyProbe returns the failing critical dependencies of a route group by name, with the
+// error of their last check. It is called on every request, so it should read the results of a
+// background health checker rather than check the dependencies itself.
+type DependencyProbe func(ctx context.Context) map[string]error
+
+// Degrade answers requests with a 503 Problem naming the failing dependencies while probe reports
+// any, instead of letting each request time out on them. Apply it to the route groups that need
+// the probed dependencies. retryAfter is sent in the Retry-After header when positive, and the
+// check errors are only included in the detail when debug is set.
+func Degrade(next http.HandlerFunc, logger *zap.Logger, probe DependencyProbe, retryAfter time.Duration, debug bool) http.HandlerFunc {
+	problemWriter := problem.New()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		failures := probe(ctx)
+		if len(failures) == 0 {
+			next(w, r)
+			return
+		}
+
+		names := make([]string, 0, len(failures))
+		for name := range failures {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		err := handlerutil.NewDependencyUnavailableError(names, retryAfter, "")
+		if debug {
+			details := make([]string, 0, len(names))
+			for _, name := range names {
+				details = append(details, fmt.Sprintf("%s: %v", name, failures[name]))
+			}
+			err.Message = "dependencies unavailable: " + strings.Join(details, "; ")
+		}
+
+		trace.SpanFromContext(ctx).SetAttributes(attribute.StringSlice("degraded.dependencies", names))
+		logger := logutil.WithContext(ctx, logger)
+		logger.Debug("Rejected request of a degraded route", zap.Strings("dependencies", names), zap.String("path", r.URL.Path))
+
+		problemWriter.WriteError(ctx, w, err, logger)
+	}
+}
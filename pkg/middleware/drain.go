@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/NYCU-SDC/summer/pkg/handler"
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"github.com/NYCU-SDC/summer/pkg/problem"
+	"go.uber.org/zap"
+)
+
+// Drainer lets clients move away from an instance that is shutting down. Once draining, every
+// response asks the client to close its connection, and new non-idempotent requests are rejected
+// with a 503 Problem and a Retry-After header so the client sends them to another instance.
+// Requests already in flight and idempotent ones, which a client can safely retry, are still served.
+type Drainer struct {
+	logger     *zap.Logger
+	retryAfter time.Duration
+
+	draining atomic.Bool
+}
+
+// NewDrainer creates a Drainer, retryAfter is sent in the Retry-After header of rejected requests
+// when positive
+func NewDrainer(logger *zap.Logger, retryAfter time.Duration) *Drainer {
+	return &Drainer{
+		logger:     logger,
+		retryAfter: retryAfter,
+	}
+}
+
+// Start begins draining, it can be called several times, e.g. with http.Server.RegisterOnShutdown
+func (d *Drainer) Start() {
+	if d.draining.CompareAndSwap(false, true) {
+		d.logger.Info("Started draining connections")
+	}
+}
+
+// Draining reports whether Start was called, e.g. to fail the readiness probe
+func (d *Drainer) Draining() bool {
+	return d.draining.Load()
+}
+
+// Shutdown starts draining, waits for grace so load balancers and clients stop sending requests,
+// then shuts server down gracefully, waiting for the in-flight requests until ctx is done:
+//
+//	<-signalCtx.Done()
+//	err := drainer.Shutdown(shutdownCtx, server, 5*time.Second)
+func (d *Drainer) Shutdown(ctx context.Context, server *http.Server, grace time.Duration) error {
+	d.Start()
+
+	timer := time.NewTimer(grace)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+	case <-timer.C:
+	}
+
+	return server.Shutdown(ctx)
+}
+
+// Middleware applies the draining behavior to next
+func (d *Drainer) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	problemWriter := problem.New()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !d.draining.Load() {
+			next(w, r)
+			return
+		}
+
+		w.Header().Set("Connection", "close")
+
+		if isIdempotentMethod(r.Method) {
+			next(w, r)
+			return
+		}
+
+		ctx := r.Context()
+		logger := logutil.WithContext(ctx, d.logger)
+		logger.Debug("Rejected request while draining", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+		problemWriter.WriteError(ctx, w, handlerutil.NewDependencyUnavailableError(nil, d.retryAfter, "server is shutting down"), logger)
+	}
+}
+
+// isIdempotentMethod reports whether requests of method can be repeated without changing their effect,
+// see RFC 9110 section 9.2.2
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const ExperimentHeader = "X-Experiment-Variant"
+
+type experimentContextKey struct{}
+
+// Variant is one arm of an experiment, requests are spread across variants proportionally to Weight
+type Variant struct {
+	Name   string
+	Weight int
+}
+
+// Experiment describes an A/B experiment. Salt makes the assignment of the same user
+// independent between experiments, changing it reshuffles every user.
+type Experiment struct {
+	Name     string
+	Salt     string
+	Variants []Variant
+}
+
+// Assign deterministically picks the variant for the given user id
+func (e Experiment) Assign(userID string) (string, bool) {
+	total := 0
+	for _, v := range e.Variants {
+		if v.Weight > 0 {
+			total += v.Weight
+		}
+	}
+	if total == 0 {
+		return "", false
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(e.Salt + ":" + userID))
+	bucket := int(h.Sum32() % uint32(total))
+
+	for _, v := range e.Variants {
+		if v.Weight <= 0 {
+			continue
+		}
+		if bucket < v.Weight {
+			return v.Name, true
+		}
+		bucket -= v.Weight
+	}
+
+	return "", false
+}
+
+// ExperimentMiddleware assigns the request to a variant of the experiment based on the "user_id"
+// context value. The assignment is exposed with VariantFromContext, the X-Experiment-Variant
+// response header, a span attribute and a log entry. Requests without a user id are not assigned.
+func ExperimentMiddleware(next http.HandlerFunc, logger *zap.Logger, experiment Experiment) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		userID := ctx.Value("user_id")
+		if userID == nil {
+			next(w, r)
+			return
+		}
+
+		variant, ok := experiment.Assign(fmt.Sprintf("%v", userID))
+		if !ok {
+			next(w, r)
+			return
+		}
+
+		assignments := map[string]string{}
+		if existing, ok := ctx.Value(experimentContextKey{}).(map[string]string); ok {
+			for k, v := range existing {
+				assignments[k] = v
+			}
+		}
+		assignments[experiment.Name] = variant
+		ctx = context.WithValue(ctx, experimentContextKey{}, assignments)
+
+		w.Header().Add(ExperimentHeader, experiment.Name+"="+variant)
+
+		trace.SpanFromContext(ctx).SetAttributes(attribute.String("experiment."+experiment.Name, variant))
+		logutil.WithContext(ctx, logger).Debug("Assigned experiment variant", zap.String("experiment", experiment.Name), zap.String("variant", variant))
+
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// VariantFromContext returns the variant assigned to the request for the given experiment
+func VariantFromContext(ctx context.Context, experiment string) (string, bool) {
+	assignments, ok := ctx.Value(experimentContextKey{}).(map[string]string)
+	if !ok {
+		return "", false
+	}
+	variant, ok := assignments[experiment]
+	return variant, ok
+}
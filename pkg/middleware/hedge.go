@@ -0,0 +1,255 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.uber.org/zap"
+)
+
+const (
+	hedgeLatencySamples    = 100
+	hedgeMinLatencySamples = 20
+)
+
+// HedgeRoute enables hedging for the outgoing requests with Method whose path starts with PathPrefix.
+// Both attempts may reach the server, so requests with a non-idempotent method such as POST or
+// PATCH are never hedged, even when a route matches them.
+type HedgeRoute struct {
+	Method     string
+	PathPrefix string
+
+	// Delay is how long the first attempt may take before the second one is sent. When zero, the
+	// p95 latency of the recent requests of the route is used, and requests are not hedged until
+	// enough of them completed to estimate it.
+	Delay time.Duration
+}
+
+// HedgedTransport is a http.RoundTripper sending a second attempt of a slow request to cut tail
+// latency. The response arriving first is returned and the other attempt is canceled. The hedges
+// sent and won are counted in the "http.client.hedges" metric.
+type HedgedTransport struct {
+	Base http.RoundTripper
+
+	logger  *zap.Logger
+	routes  []HedgeRoute
+	stats   []*hedgeLatencies
+	counter metric.Int64Counter
+}
+
+func NewHedgedTransport(base http.RoundTripper, logger *zap.Logger, routes ...HedgeRoute) *HedgedTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	counter, err := otel.Meter("internal/middleware").Int64Counter(
+		"http.client.hedges",
+		metric.WithDescription("Number of hedged requests sent, by route and winning attempt"),
+	)
+	if err != nil {
+		logger.Warn("Failed to create hedge counter", zap.Error(err))
+	}
+
+	stats := make([]*hedgeLatencies, len(routes))
+	for i := range stats {
+		stats[i] = &hedgeLatencies{}
+	}
+
+	return &HedgedTransport{
+		Base:    base,
+		logger:  logger,
+		routes:  routes,
+		stats:   stats,
+		counter: counter,
+	}
+}
+
+type hedgeResult struct {
+	resp   *http.Response
+	err    error
+	hedge  bool
+	cancel context.CancelFunc
+}
+
+func (t *HedgedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	index := t.match(req)
+	if index < 0 || !isIdempotentMethod(req.Method) || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return t.Base.RoundTrip(req)
+	}
+
+	route := t.routes[index]
+	stats := t.stats[index]
+
+	delay := route.Delay
+	if delay <= 0 {
+		var ok bool
+		delay, ok = stats.p95()
+		if !ok {
+			start := time.Now()
+			resp, err := t.Base.RoundTrip(req)
+			if err == nil {
+				stats.add(time.Since(start))
+			}
+			return resp, err
+		}
+	}
+
+	start := time.Now()
+	results := make(chan hedgeResult, 2)
+	cancels := map[bool]context.CancelFunc{}
+	send := func(hedge bool) {
+		attempt, cancel, err := t.attempt(req)
+		cancels[hedge] = cancel
+		go func() {
+			if err != nil {
+				results <- hedgeResult{err: err, hedge: hedge, cancel: cancel}
+				return
+			}
+			resp, err := t.Base.RoundTrip(attempt)
+			results <- hedgeResult{resp: resp, err: err, hedge: hedge, cancel: cancel}
+		}()
+	}
+
+	send(false)
+	pending := 1
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	var last hedgeResult
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			send(true)
+			pending++
+			t.logger.Debug("Sent hedged request", zap.String("method", req.Method), zap.String("path", req.URL.Path), zap.Duration("delay", delay))
+		case result := <-results:
+			pending--
+			if result.err != nil {
+				result.cancel()
+				last = result
+				// Without a hedge in flight, hedge right away instead of failing
+				if pending == 0 && !result.hedge && timer.Stop() {
+					send(true)
+					pending++
+				}
+				continue
+			}
+
+			stats.add(time.Since(start))
+			t.recordWin(req.Context(), route, len(cancels) > 1, result.hedge)
+			if pending > 0 {
+				cancels[!result.hedge]()
+				go discardLoser(results)
+			}
+			result.resp.Body = &cancelOnClose{ReadCloser: result.resp.Body, cancel: result.cancel}
+			return result.resp, nil
+		}
+	}
+
+	return nil, last.err
+}
+
+// match returns the index of the first route matching req, or -1
+func (t *HedgedTransport) match(req *http.Request) int {
+	for i, route := range t.routes {
+		if route.Method != "" && route.Method != req.Method {
+			continue
+		}
+		if strings.HasPrefix(req.URL.Path, route.PathPrefix) {
+			return i
+		}
+	}
+	return -1
+}
+
+// attempt clones req with its own cancelable context and a fresh body
+func (t *HedgedTransport) attempt(req *http.Request) (*http.Request, context.CancelFunc, error) {
+	ctx, cancel := context.WithCancel(req.Context())
+	attempt := req.Clone(ctx)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, cancel, err
+		}
+		attempt.Body = body
+	}
+	return attempt, cancel, nil
+}
+
+func (t *HedgedTransport) recordWin(ctx context.Context, route HedgeRoute, hedged, hedgeWon bool) {
+	if t.counter == nil || !hedged {
+		return
+	}
+
+	winner := "primary"
+	if hedgeWon {
+		winner = "hedge"
+	}
+	t.counter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("route", route.Method+" "+route.PathPrefix),
+		attribute.String("winner", winner),
+	))
+}
+
+// discardLoser waits for the canceled attempt and closes its response, if it still produced one
+func discardLoser(results <-chan hedgeResult) {
+	result := <-results
+	result.cancel()
+	if result.resp != nil {
+		_, _ = io.Copy(io.Discard, result.resp.Body)
+		_ = result.resp.Body.Close()
+	}
+}
+
+// cancelOnClose releases the context of the winning attempt once its body has been read
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// hedgeLatencies keeps the latencies of the last requests of a route
+type hedgeLatencies struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+func (l *hedgeLatencies) add(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.samples) < hedgeLatencySamples {
+		l.samples = append(l.samples, d)
+		return
+	}
+	l.samples[l.next] = d
+	l.next = (l.next + 1) % hedgeLatencySamples
+}
+
+// p95 returns the 95th percentile of the kept latencies, ok is false until there are enough of them
+func (l *hedgeLatencies) p95() (time.Duration, bool) {
+	l.mu.Lock()
+	samples := slices.Clone(l.samples)
+	l.mu.Unlock()
+
+	if len(samples) < hedgeMinLatencySamples {
+		return 0, false
+	}
+	slices.Sort(samples)
+	return samples[len(samples)*95/100], true
+}
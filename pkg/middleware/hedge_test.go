@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newHedgeTestServer answers the first request only once its client gives up on it, and the
+// later ones right away with the body they carried
+func newHedgeTestServer(t *testing.T) (server *httptest.Server, requests *atomic.Int32, bodies func() []string, primaryCanceled <-chan struct{}) {
+	t.Helper()
+
+	canceled := make(chan struct{})
+	var mu sync.Mutex
+	var received []string
+	requests = &atomic.Int32{}
+
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, string(body))
+		mu.Unlock()
+
+		if requests.Add(1) == 1 {
+			select {
+			case <-r.Context().Done():
+				close(canceled)
+			case <-time.After(5 * time.Second):
+			}
+			return
+		}
+		_, _ = w.Write(append([]byte("hedge:"), body...))
+	}))
+	t.Cleanup(server.Close)
+
+	bodies = func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), received...)
+	}
+	return server, requests, bodies, canceled
+}
+
+func TestHedgedTransport(t *testing.T) {
+	t.Run("Should return the first response and cancel the slower attempt", func(t *testing.T) {
+		server, requests, _, primaryCanceled := newHedgeTestServer(t)
+		transport := NewHedgedTransport(server.Client().Transport, zap.NewNop(), HedgeRoute{Method: http.MethodGet, PathPrefix: "/users", Delay: 10 * time.Millisecond})
+
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/users/1", nil)
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip() unexpected error: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+
+		if string(body) != "hedge:" {
+			t.Errorf("RoundTrip() body = %q, want the response of the hedge", body)
+		}
+		if got := requests.Load(); got != 2 {
+			t.Errorf("server received %d requests, want 2", got)
+		}
+		select {
+		case <-primaryCanceled:
+		case <-time.After(time.Second):
+			t.Error("the slower attempt was not canceled")
+		}
+	})
+
+	t.Run("Should replay the request body for the hedge", func(t *testing.T) {
+		server, _, bodies, _ := newHedgeTestServer(t)
+		transport := NewHedgedTransport(server.Client().Transport, zap.NewNop(), HedgeRoute{Method: http.MethodPut, PathPrefix: "/users", Delay: 10 * time.Millisecond})
+
+		req, _ := http.NewRequest(http.MethodPut, server.URL+"/users/1", bytes.NewReader([]byte(`{"name":"Alice"}`)))
+		resp, err := transport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("RoundTrip() unexpected error: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+
+		if string(body) != `hedge:{"name":"Alice"}` {
+			t.Errorf("RoundTrip() body = %q, want the body echoed by the hedge", body)
+		}
+		got := bodies()
+		if len(got) != 2 || got[0] != `{"name":"Alice"}` || got[1] != `{"name":"Alice"}` {
+			t.Errorf("server received bodies %q, want the request body twice", got)
+		}
+	})
+
+	t.Run("Should never hedge a non-idempotent method", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			time.Sleep(50 * time.Millisecond)
+			w.WriteHeader(http.StatusCreated)
+		}))
+		t.Cleanup(server.Close)
+		transport := NewHedgedTransport(server.Client().Transport, zap.NewNop(), HedgeRoute{PathPrefix: "/", Delay: time.Millisecond})
+
+		for _, method := range []string{http.MethodPost, http.MethodPatch} {
+			req, _ := http.NewRequest(method, server.URL+"/orders", bytes.NewReader([]byte(`{}`)))
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip(%s) unexpected error: %v", method, err)
+			}
+			_ = resp.Body.Close()
+		}
+
+		if got := requests.Load(); got != 2 {
+			t.Errorf("server received %d requests, want 1 per call", got)
+		}
+	})
+
+	t.Run("Should not hedge a route without a match", func(t *testing.T) {
+		server, requests, _, _ := newHedgeTestServer(t)
+		transport := NewHedgedTransport(server.Client().Transport, zap.NewNop(), HedgeRoute{Method: http.MethodGet, PathPrefix: "/users", Delay: time.Millisecond})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/orders/1", nil)
+		_, err := transport.RoundTrip(req)
+		if err == nil {
+			t.Fatal("RoundTrip() expected the timeout of the only attempt")
+		}
+		if got := requests.Load(); got != 1 {
+			t.Errorf("server received %d requests, want 1", got)
+		}
+	})
+}
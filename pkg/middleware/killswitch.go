@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/NYCU-SDC/summer/pkg/handler"
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"github.com/NYCU-SDC/summer/pkg/problem"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// KillSwitchFlagPrefix prefixes the route name to form the flag of its kill switch
+const KillSwitchFlagPrefix = "killswitch."
+
+var ErrRouteDisabled = errors.New("route disabled")
+
+// FlagSource reads a string feature flag. It is called on every request, so it should answer from
+// the memory of a flag client refreshed in the background rather than query the flag service.
+type FlagSource func(ctx context.Context, flag string) (value string, ok bool)
+
+// RouteDisabledError is returned for a route turned off by its kill switch, it is written as a 503
+// Problem with the incident reference in the incident extension member
+type RouteDisabledError struct {
+	Route    string
+	Incident string
+	cause    handlerutil.DependencyUnavailableError
+}
+
+func (e RouteDisabledError) Error() string {
+	return "route " + e.Route + " is temporarily disabled, incident " + e.Incident
+}
+
+func (e RouteDisabledError) Is(target error) bool {
+	return target == ErrRouteDisabled
+}
+
+// Unwrap returns the 503 error carrying the Retry-After duration
+func (e RouteDisabledError) Unwrap() error {
+	return e.cause
+}
+
+// KillSwitch lets on-call engineers turn route off at runtime without a deploy. While the flag
+// "killswitch."+route is set to a non-empty value, e.g. the incident reference "INC-1234", requests
+// are rejected with a 503 Problem naming that incident, instead of reaching the misbehaving handler.
+// retryAfter is sent in the Retry-After header when positive.
+//
+//	mux.HandleFunc("POST /api/exports", middleware.KillSwitch(h.CreateExport, logger, "exports.create", flags.String, time.Minute))
+func KillSwitch(next http.HandlerFunc, logger *zap.Logger, route string, flags FlagSource, retryAfter time.Duration) http.HandlerFunc {
+	problemWriter := problem.New()
+	problemWriter.RegisterMapping(ErrRouteDisabled, func(err error) problem.Problem {
+		p := problem.NewServiceUnavailableProblem("Endpoint is temporarily disabled, please retry later")
+
+		var disabledError RouteDisabledError
+		if errors.As(err, &disabledError) {
+			p = p.WithExtension("incident", disabledError.Incident)
+		}
+		return p
+	})
+
+	flag := KillSwitchFlagPrefix + route
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		incident, ok := flags(ctx, flag)
+		if !ok || incident == "" {
+			next(w, r)
+			return
+		}
+
+		trace.SpanFromContext(ctx).SetAttributes(
+			attribute.String("killswitch.route", route),
+			attribute.String("killswitch.incident", incident),
+		)
+		logger := logutil.WithContext(ctx, logger)
+		logger.Debug("Rejected request of a disabled route", zap.String("route", route), zap.String("incident", incident), zap.String("path", r.URL.Path))
+
+		problemWriter.WriteError(ctx, w, RouteDisabledError{
+			Route:    route,
+			Incident: incident,
+			cause:    handlerutil.NewDependencyUnavailableError(nil, retryAfter, "route "+route+" is disabled"),
+		}, logger)
+	}
+}
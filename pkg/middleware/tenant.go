@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type tenantContextKey struct{}
+
+// Tenant identifies the tenant a request belongs to, Source is the name of the resolver that found it
+type Tenant struct {
+	ID     string
+	Source string
+}
+
+// TenantResolver extracts the tenant id from a request, ok is false when the request does not carry one
+type TenantResolver struct {
+	Name    string
+	Resolve func(r *http.Request) (id string, ok bool)
+}
+
+// HostTenantResolver resolves the tenant from the subdomain of baseDomain,
+// e.g. "acme" for "acme.example.com" with baseDomain "example.com"
+func HostTenantResolver(baseDomain string) TenantResolver {
+	suffix := "." + strings.TrimPrefix(strings.ToLower(baseDomain), ".")
+
+	return TenantResolver{
+		Name: "host",
+		Resolve: func(r *http.Request) (string, bool) {
+			host := strings.ToLower(r.Host)
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+
+			subdomain, found := strings.CutSuffix(host, suffix)
+			if !found || subdomain == "" || strings.Contains(subdomain, ".") {
+				return "", false
+			}
+			return subdomain, true
+		},
+	}
+}
+
+// HeaderTenantResolver resolves the tenant from a request header such as X-Tenant-ID
+func HeaderTenantResolver(header string) TenantResolver {
+	return TenantResolver{
+		Name: "header",
+		Resolve: func(r *http.Request) (string, bool) {
+			id := strings.TrimSpace(r.Header.Get(header))
+			return id, id != ""
+		},
+	}
+}
+
+// ClaimTenantResolver resolves the tenant from a claim of the authenticated token. The claims must come
+// from a token that was already verified by the authentication middleware, they are never parsed here.
+func ClaimTenantResolver(claim string, claimsFromContext func(ctx context.Context) (map[string]any, bool)) TenantResolver {
+	return TenantResolver{
+		Name: "claim",
+		Resolve: func(r *http.Request) (string, bool) {
+			claims, ok := claimsFromContext(r.Context())
+			if !ok {
+				return "", false
+			}
+
+			value, ok := claims[claim]
+			if !ok || value == nil {
+				return "", false
+			}
+
+			id := fmt.Sprintf("%v", value)
+			return id, id != ""
+		},
+	}
+}
+
+// TenantMiddleware tries the resolvers in order and stores the first resolved Tenant in the context.
+// The tenant id is also stored as the "tenant_id" context value picked up by logutil.WithContext,
+// and recorded as a span attribute. Requests without a tenant are passed through unchanged.
+func TenantMiddleware(next http.HandlerFunc, logger *zap.Logger, resolvers ...TenantResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, resolver := range resolvers {
+			id, ok := resolver.Resolve(r)
+			if !ok {
+				continue
+			}
+
+			tenant := Tenant{ID: id, Source: resolver.Name}
+			ctx := context.WithValue(r.Context(), tenantContextKey{}, tenant)
+			ctx = context.WithValue(ctx, "tenant_id", tenant.ID)
+
+			trace.SpanFromContext(ctx).SetAttributes(attribute.String("tenant.id", tenant.ID))
+			logutil.WithContext(ctx, logger).Debug("Resolved tenant", zap.String("source", tenant.Source))
+
+			next(w, r.WithContext(ctx))
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// TenantFromContext returns the tenant resolved by TenantMiddleware
+func TenantFromContext(ctx context.Context) (Tenant, bool) {
+	tenant, ok := ctx.Value(tenantContextKey{}).(Tenant)
+	return tenant, ok
+}
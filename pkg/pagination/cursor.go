@@ -0,0 +1,107 @@
+package pagination
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// Cursor points at the last item of a page in keyset pagination
+type Cursor struct {
+	SortValue string `json:"s"`
+	ID        string `json:"i"`
+}
+
+// CursorCodec turns cursors into opaque tokens. With a key, the payload is encrypted and
+// authenticated with AES-GCM so clients can neither read nor forge cursors. The nonce is derived
+// from the payload, so the same cursor always encodes to the same token.
+type CursorCodec struct {
+	aead     cipher.AEAD
+	nonceKey []byte
+}
+
+// NewCursorCodec creates a codec, key must be 16, 24 or 32 bytes long to select AES-128, AES-192
+// or AES-256, a nil key disables encryption and only base64 encodes the payload
+func NewCursorCodec(key []byte) (*CursorCodec, error) {
+	if key == nil {
+		return &CursorCodec{}, nil
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor key: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cursor cipher: %w", err)
+	}
+
+	nonceKey := sha256.Sum256(append([]byte("cursor-nonce:"), key...))
+	return &CursorCodec{aead: aead, nonceKey: nonceKey[:]}, nil
+}
+
+func (c *CursorCodec) Encode(cursor Cursor) (string, error) {
+	return c.EncodeValue(cursor)
+}
+
+// Decode returns ErrInvalidCursor for tokens that are malformed or were not produced with the same key
+func (c *CursorCodec) Decode(token string) (Cursor, error) {
+	var cursor Cursor
+	err := c.DecodeValue(token, &cursor)
+	if err != nil {
+		return Cursor{}, err
+	}
+	return cursor, nil
+}
+
+// EncodeValue turns any JSON encodable v into a token, for cursors other than a keyset Cursor. A
+// nil codec only base64 encodes the payload.
+func (c *CursorCodec) EncodeValue(v any) (string, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal cursor: %w", err)
+	}
+
+	if c == nil || c.aead == nil {
+		return base64.RawURLEncoding.EncodeToString(payload), nil
+	}
+
+	mac := hmac.New(sha256.New, c.nonceKey)
+	mac.Write(payload)
+	nonce := mac.Sum(nil)[:c.aead.NonceSize()]
+
+	sealed := c.aead.Seal(nonce, nonce, payload, nil)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// DecodeValue decodes a token from EncodeValue into dst, see Decode for the errors
+func (c *CursorCodec) DecodeValue(token string, dst any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return fmt.Errorf("%w: malformed encoding", ErrInvalidCursor)
+	}
+
+	payload := raw
+	if c != nil && c.aead != nil {
+		nonceSize := c.aead.NonceSize()
+		if len(raw) < nonceSize+c.aead.Overhead() {
+			return fmt.Errorf("%w: too short", ErrInvalidCursor)
+		}
+
+		payload, err = c.aead.Open(nil, raw[:nonceSize], raw[nonceSize:], nil)
+		if err != nil {
+			return fmt.Errorf("%w: authentication failed", ErrInvalidCursor)
+		}
+	}
+
+	err = json.Unmarshal(payload, dst)
+	if err != nil {
+		return fmt.Errorf("%w: malformed payload", ErrInvalidCursor)
+	}
+	return nil
+}
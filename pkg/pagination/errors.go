@@ -1,8 +1,31 @@
 package pagination
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrInvalidPageOrSize   = errors.New("invalid page number or size")
 	ErrInvalidSortingField = errors.New("invalid sorting field")
+	ErrInvalidCursor       = errors.New("invalid cursor")
+
+	ErrInvalidFilterField    = errors.New("invalid filter field")
+	ErrInvalidFilterOperator = errors.New("invalid filter operator")
 )
+
+// ParameterError reports an invalid pagination query parameter, it wraps ErrInvalidPageOrSize
+// and is written as a validation problem pointing at Parameter
+type ParameterError struct {
+	Parameter string
+	Value     string
+	Message   string
+}
+
+func (e ParameterError) Error() string {
+	return fmt.Sprintf("%s: %s=%q, %s", ErrInvalidPageOrSize, e.Parameter, e.Value, e.Message)
+}
+
+func (e ParameterError) Unwrap() error {
+	return ErrInvalidPageOrSize
+}
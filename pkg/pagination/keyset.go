@@ -0,0 +1,117 @@
+package pagination
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Keyset is the tail of a keyset page query built by Factory.Keyset
+type Keyset struct {
+	// Predicate selects the rows after the cursor, e.g. "(created_at, id) > ($1, $2)", it is empty
+	// for the first page
+	Predicate string
+	// OrderBy is the ORDER BY list, e.g. "created_at ASC, id ASC"
+	OrderBy string
+	// Limit is one more than the page size, the extra row tells whether there is a next page
+	Limit int
+	// Args are the values of the placeholders of Predicate
+	Args []any
+}
+
+// Clause returns the WHERE, ORDER BY and LIMIT clauses, to append to a query without a WHERE clause.
+// Queries with their own conditions should combine them with Predicate instead.
+func (k Keyset) Clause() string {
+	var clause strings.Builder
+	if k.Predicate != "" {
+		clause.WriteString("WHERE ")
+		clause.WriteString(k.Predicate)
+		clause.WriteString(" ")
+	}
+	clause.WriteString("ORDER BY ")
+	clause.WriteString(k.OrderBy)
+	clause.WriteString(" LIMIT ")
+	clause.WriteString(strconv.Itoa(k.Limit))
+	return clause.String()
+}
+
+// CursorResponse is a page of keyset pagination
+type CursorResponse[T any] struct {
+	Items       []T    `json:"items"`
+	NextCursor  string `json:"nextCursor,omitempty"`
+	PageSize    int    `json:"pageSize"`
+	HasNextPage bool   `json:"hasNextPage"`
+}
+
+// Keyset builds the clauses selecting the page of req after its cursor, ordered by req.SortBy then
+// by idColumn to break ties. columns maps the sortable columns of f to their SQL expression, it is
+// the whitelist of what ends up in the query, so SortBy is never written into SQL itself. The
+// placeholders of the predicate start after argOffset, the number of arguments the query already has:
+//
+//	keyset, err := factory.Keyset(pageRequest, map[string]string{"createdAt": "created_at"}, "id", 1)
+//	rows, err := db.Query(ctx, "SELECT id, name, created_at FROM users WHERE team_id = $1 AND "+
+//		keyset.Predicate+" ORDER BY "+keyset.OrderBy+" LIMIT "+strconv.Itoa(keyset.Limit),
+//		append([]any{teamID}, keyset.Args...)...)
+//
+// The sort column must not be NULL, and the cursor values are sent as text for Postgres to cast
+// to the column type. An unknown SortBy returns ErrInvalidSortingField.
+func (f Factory[T]) Keyset(req Request, columns map[string]string, idColumn string, argOffset int) (Keyset, error) {
+	descending := strings.EqualFold(req.Sort, "desc")
+	direction, operator := "ASC", ">"
+	if descending {
+		direction, operator = "DESC", "<"
+	}
+
+	keyset := Keyset{Limit: req.Size + 1}
+
+	if req.SortBy == "" {
+		keyset.OrderBy = idColumn + " " + direction
+		if req.Cursor != nil {
+			keyset.Predicate = fmt.Sprintf("%s %s $%d", idColumn, operator, argOffset+1)
+			keyset.Args = []any{req.Cursor.ID}
+		}
+		return keyset, nil
+	}
+
+	column, ok := columns[req.SortBy]
+	if !ok || !slices.Contains(f.SortableColumns, req.SortBy) {
+		return Keyset{}, fmt.Errorf("%w: %s, valid: %v", ErrInvalidSortingField, req.SortBy, f.SortableColumns)
+	}
+
+	keyset.OrderBy = fmt.Sprintf("%s %s, %s %s", column, direction, idColumn, direction)
+	if req.Cursor != nil {
+		keyset.Predicate = fmt.Sprintf("(%s, %s) %s ($%d, $%d)", column, idColumn, operator, argOffset+1, argOffset+2)
+		keyset.Args = []any{req.Cursor.SortValue, req.Cursor.ID}
+	}
+	return keyset, nil
+}
+
+// NewCursorResponse builds the response of a page queried with the Limit of Keyset, the extra row
+// is dropped and the next cursor is taken from the last item with cursorOf
+func (f Factory[T]) NewCursorResponse(items []T, req Request, cursorOf func(item T) Cursor) (CursorResponse[T], error) {
+	response := CursorResponse[T]{
+		Items:    items,
+		PageSize: req.Size,
+	}
+	if req.Size < 1 || len(items) <= req.Size {
+		return response, nil
+	}
+
+	response.Items = items[:req.Size]
+	response.HasNextPage = true
+
+	token, err := f.cursorCodec().Encode(cursorOf(response.Items[req.Size-1]))
+	if err != nil {
+		return CursorResponse[T]{}, err
+	}
+	response.NextCursor = token
+	return response, nil
+}
+
+func (f Factory[T]) cursorCodec() *CursorCodec {
+	if f.CursorCodec == nil {
+		return &CursorCodec{}
+	}
+	return f.CursorCodec
+}
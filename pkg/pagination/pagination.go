@@ -12,6 +12,12 @@ type Request struct {
 	Size   int
 	Sort   string
 	SortBy string
+
+	// Cursor is the decoded cursor query parameter, nil for the first page, see Factory.Keyset
+	Cursor *Cursor
+
+	// Query is the search, filters and sort of the request, set by handlerutil.GetListRequest
+	Query ListQuery
 }
 
 type Response[T any] struct {
@@ -23,9 +29,32 @@ type Response[T any] struct {
 	HasNextPage bool `json:"hasNextPage"`
 }
 
+// PageNumbering is the number clients use for the first page
+type PageNumbering int
+
+const (
+	// OneBased numbers the first page 1, it is the default
+	OneBased PageNumbering = iota
+	// ZeroBased numbers the first page 0
+	ZeroBased
+)
+
+// FirstPage returns the number of the first page
+func (n PageNumbering) FirstPage() int {
+	if n == ZeroBased {
+		return 0
+	}
+	return 1
+}
+
 type Factory[T any] struct {
 	MaxPageSize     int
 	SortableColumns []string
+	Numbering       PageNumbering
+
+	// CursorCodec decodes the cursor query parameter and encodes the next one, tokens are only
+	// base64 encoded when nil
+	CursorCodec *CursorCodec
 }
 
 func NewFactory[T any](maxPageSize int, sortableColumns []string) Factory[T] {
@@ -35,19 +64,48 @@ func NewFactory[T any](maxPageSize int, sortableColumns []string) Factory[T] {
 	}
 }
 
+// WithNumbering returns a copy of f numbering pages with n
+func (f Factory[T]) WithNumbering(n PageNumbering) Factory[T] {
+	f.Numbering = n
+	return f
+}
+
+// WithCursorCodec returns a copy of f encoding cursors with codec
+func (f Factory[T]) WithCursorCodec(codec *CursorCodec) Factory[T] {
+	f.CursorCodec = codec
+	return f
+}
+
+// GetRequest parses the page, size, sorting and cursor query parameters. A missing page selects the first
+// page and a missing size defaults to 10. A page that is not a number or is before the first page, and a
+// size that is not a positive number, are rejected with a ParameterError.
 func (f Factory[T]) GetRequest(r *http.Request) (Request, error) {
 	pageParam := r.URL.Query().Get("page")
 	sizeParam := r.URL.Query().Get("size")
 	sort := r.URL.Query().Get("sort")
 	sortBy := r.URL.Query().Get("sortBy")
+	cursorParam := r.URL.Query().Get("cursor")
 
-	page, err := strconv.Atoi(pageParam)
-	if err != nil || page < 1 {
-		page = 0
+	firstPage := f.Numbering.FirstPage()
+	page := firstPage
+	if pageParam != "" {
+		var err error
+		page, err = strconv.Atoi(pageParam)
+		if err != nil {
+			return Request{}, ParameterError{Parameter: "page", Value: pageParam, Message: "must be an integer"}
+		}
+		if page < firstPage {
+			return Request{}, ParameterError{Parameter: "page", Value: pageParam, Message: fmt.Sprintf("must be at least %d", firstPage)}
+		}
 	}
-	size, err := strconv.Atoi(sizeParam)
-	if err != nil || size < 1 {
-		size = 10
+
+	size := 10
+	if sizeParam != "" {
+		var err error
+		size, err = strconv.Atoi(sizeParam)
+		if err != nil || size < 1 {
+			return Request{}, ParameterError{Parameter: "size", Value: sizeParam, Message: "must be a positive integer"}
+		}
 	}
 
 	if size > f.MaxPageSize {
@@ -57,14 +115,30 @@ func (f Factory[T]) GetRequest(r *http.Request) (Request, error) {
 		return Request{}, fmt.Errorf("%w: %s, valid: %v", ErrInvalidSortingField, sortBy, f.SortableColumns)
 	}
 
+	var cursor *Cursor
+	if cursorParam != "" {
+		decoded, err := f.cursorCodec().Decode(cursorParam)
+		if err != nil {
+			return Request{}, err
+		}
+		cursor = &decoded
+	}
+
 	return Request{
 		Page:   page,
 		Size:   size,
 		Sort:   sort,
 		SortBy: sortBy,
+		Cursor: cursor,
 	}, nil
 }
 
+// Offset returns the number of items before page, for the OFFSET clause of the page query
+func (f Factory[T]) Offset(page, size int) int {
+	return (page - f.Numbering.FirstPage()) * size
+}
+
+// NewResponse builds the response of page, numbered like the requests of f
 func (f Factory[T]) NewResponse(items []T, totalItems int, page, size int) Response[T] {
 	totalPages := totalItems / size
 	if totalItems%size != 0 {
@@ -77,6 +151,6 @@ func (f Factory[T]) NewResponse(items []T, totalItems int, page, size int) Respo
 		TotalItems:  totalItems,
 		CurrentPage: page,
 		PageSize:    size,
-		HasNextPage: page+1 < totalPages,
+		HasNextPage: page-f.Numbering.FirstPage()+1 < totalPages,
 	}
 }
@@ -0,0 +1,125 @@
+package pagination
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type paginationTestItem struct {
+	ID int
+}
+
+func TestFactory_GetRequest(t *testing.T) {
+	tests := []struct {
+		name          string
+		numbering     PageNumbering
+		query         string
+		wantPage      int
+		wantSize      int
+		wantParameter string
+		wantErr       error
+	}{
+		{name: "Should default to page 1 and size 10", query: "", wantPage: 1, wantSize: 10},
+		{name: "Should default to page 0 when zero-based", numbering: ZeroBased, query: "", wantPage: 0, wantSize: 10},
+		{name: "Should accept page 1", query: "page=1&size=20", wantPage: 1, wantSize: 20},
+		{name: "Should accept page 0 when zero-based", numbering: ZeroBased, query: "page=0", wantPage: 0, wantSize: 10},
+		{name: "Should reject page 0 when one-based", query: "page=0", wantParameter: "page", wantErr: ErrInvalidPageOrSize},
+		{name: "Should reject a negative page when zero-based", numbering: ZeroBased, query: "page=-1", wantParameter: "page", wantErr: ErrInvalidPageOrSize},
+		{name: "Should reject a page that is not a number", query: "page=first", wantParameter: "page", wantErr: ErrInvalidPageOrSize},
+		{name: "Should reject size 0", query: "size=0", wantParameter: "size", wantErr: ErrInvalidPageOrSize},
+		{name: "Should reject a negative size", query: "size=-5", wantParameter: "size", wantErr: ErrInvalidPageOrSize},
+		{name: "Should reject a size that is not a number", query: "size=ten", wantParameter: "size", wantErr: ErrInvalidPageOrSize},
+		{name: "Should reject a size above the maximum", query: "size=101", wantErr: ErrInvalidPageOrSize},
+		{name: "Should reject an unknown sort column", query: "sort=asc&sortBy=password", wantErr: ErrInvalidSortingField},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factory := NewFactory[paginationTestItem](100, []string{"name"}).WithNumbering(tt.numbering)
+			r := httptest.NewRequest(http.MethodGet, "/items?"+tt.query, nil)
+
+			got, err := factory.GetRequest(r)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GetRequest() error = %v, want %v", err, tt.wantErr)
+				}
+				if tt.wantParameter != "" {
+					var parameterErr ParameterError
+					if !errors.As(err, &parameterErr) || parameterErr.Parameter != tt.wantParameter {
+						t.Errorf("GetRequest() error = %#v, want ParameterError on %q", err, tt.wantParameter)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetRequest() unexpected error: %v", err)
+			}
+			if got.Page != tt.wantPage || got.Size != tt.wantSize {
+				t.Errorf("GetRequest() = page %d size %d, want page %d size %d", got.Page, got.Size, tt.wantPage, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestFactory_Offset(t *testing.T) {
+	tests := []struct {
+		name      string
+		numbering PageNumbering
+		page      int
+		size      int
+		want      int
+	}{
+		{name: "Should start page 1 at offset 0 when one-based", page: 1, size: 10, want: 0},
+		{name: "Should start page 3 at offset 20 when one-based", page: 3, size: 10, want: 20},
+		{name: "Should start page 0 at offset 0 when zero-based", numbering: ZeroBased, page: 0, size: 10, want: 0},
+		{name: "Should start page 3 at offset 30 when zero-based", numbering: ZeroBased, page: 3, size: 10, want: 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factory := NewFactory[paginationTestItem](100, nil).WithNumbering(tt.numbering)
+
+			got := factory.Offset(tt.page, tt.size)
+			if got != tt.want {
+				t.Errorf("Offset(%d, %d) = %d, want %d", tt.page, tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFactory_NewResponse(t *testing.T) {
+	tests := []struct {
+		name           string
+		numbering      PageNumbering
+		totalItems     int
+		page           int
+		size           int
+		wantTotalPages int
+		wantNextPage   bool
+	}{
+		{name: "Should have a next page after page 1 when one-based", totalItems: 42, page: 1, size: 10, wantTotalPages: 5, wantNextPage: true},
+		{name: "Should have no next page on the last page when one-based", totalItems: 42, page: 5, size: 10, wantTotalPages: 5, wantNextPage: false},
+		{name: "Should have a next page before the last page when one-based", totalItems: 40, page: 3, size: 10, wantTotalPages: 4, wantNextPage: true},
+		{name: "Should have no next page on an exactly full last page", totalItems: 40, page: 4, size: 10, wantTotalPages: 4, wantNextPage: false},
+		{name: "Should have a next page after page 0 when zero-based", numbering: ZeroBased, totalItems: 42, page: 0, size: 10, wantTotalPages: 5, wantNextPage: true},
+		{name: "Should have no next page on the last page when zero-based", numbering: ZeroBased, totalItems: 42, page: 4, size: 10, wantTotalPages: 5, wantNextPage: false},
+		{name: "Should have no pages without items", totalItems: 0, page: 1, size: 10, wantTotalPages: 0, wantNextPage: false},
+		{name: "Should have no next page past the last page", totalItems: 42, page: 9, size: 10, wantTotalPages: 5, wantNextPage: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			factory := NewFactory[paginationTestItem](100, nil).WithNumbering(tt.numbering)
+
+			got := factory.NewResponse(nil, tt.totalItems, tt.page, tt.size)
+			if got.TotalPages != tt.wantTotalPages || got.HasNextPage != tt.wantNextPage {
+				t.Errorf("NewResponse() = totalPages %d hasNextPage %t, want totalPages %d hasNextPage %t", got.TotalPages, got.HasNextPage, tt.wantTotalPages, tt.wantNextPage)
+			}
+			if got.CurrentPage != tt.page || got.PageSize != tt.size {
+				t.Errorf("NewResponse() = currentPage %d pageSize %d, want %d and %d", got.CurrentPage, got.PageSize, tt.page, tt.size)
+			}
+		})
+	}
+}
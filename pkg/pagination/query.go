@@ -0,0 +1,154 @@
+package pagination
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// FilterOp is the operator of a filter, written before the value: filter[age]=gte:18
+type FilterOp string
+
+const (
+	OpEq  FilterOp = "eq"
+	OpNe  FilterOp = "ne"
+	OpLt  FilterOp = "lt"
+	OpLte FilterOp = "lte"
+	OpGt  FilterOp = "gt"
+	OpGte FilterOp = "gte"
+	// OpIn matches one of the comma separated values: filter[role]=in:admin,editor
+	OpIn FilterOp = "in"
+	// OpContains matches text columns containing the value, case-insensitively
+	OpContains FilterOp = "contains"
+)
+
+// FilterOps are all the operators, in the order they are documented
+var FilterOps = []FilterOp{OpEq, OpNe, OpLt, OpLte, OpGt, OpGte, OpIn, OpContains}
+
+// Filter is one filter[field]=op:value query parameter
+type Filter struct {
+	Field string
+	Op    FilterOp
+	// Values holds the value of the filter, or every value of an OpIn filter
+	Values []string
+}
+
+// SortField is one field of sort=field:dir,...
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// ListQuery is the search, filters and sort of a list request, parsed by handlerutil.ParseListQuery.
+// Field names are the API names, Where and OrderBy map them to SQL through a whitelist.
+type ListQuery struct {
+	Search  string
+	Filters []Filter
+	Sort    []SortField
+}
+
+// Condition is a SQL predicate and the values of its placeholders
+type Condition struct {
+	// Predicate is empty when there is nothing to filter on
+	Predicate string
+	Args      []any
+}
+
+// Where builds the predicate of the filters and search of q. columns maps the filterable fields to
+// their SQL expression and is the whitelist of what ends up in the query, field names are never
+// written into SQL themselves. The search matches any of searchColumns case-insensitively. The
+// placeholders start after argOffset, the number of arguments the query already has:
+//
+//	where, err := q.Where(map[string]string{"status": "status", "createdAt": "created_at"}, []string{"name", "email"}, 1)
+//	// status = $2 AND created_at >= $3 AND (name ILIKE $4 OR email ILIKE $4)
+//
+// Values are sent as text for Postgres to cast to the column type. A field missing from columns
+// returns ErrInvalidFilterField.
+func (q ListQuery) Where(columns map[string]string, searchColumns []string, argOffset int) (Condition, error) {
+	var predicates []string
+	var args []any
+	placeholder := func(value any) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(argOffset+len(args))
+	}
+
+	for _, filter := range q.Filters {
+		column, ok := columns[filter.Field]
+		if !ok {
+			return Condition{}, fmt.Errorf("%w: %s", ErrInvalidFilterField, filter.Field)
+		}
+		if len(filter.Values) == 0 {
+			return Condition{}, fmt.Errorf("%w: %s has no value", ErrInvalidFilterField, filter.Field)
+		}
+
+		switch filter.Op {
+		case OpIn:
+			placeholders := make([]string, 0, len(filter.Values))
+			for _, value := range filter.Values {
+				placeholders = append(placeholders, placeholder(value))
+			}
+			predicates = append(predicates, column+" IN ("+strings.Join(placeholders, ", ")+")")
+		case OpContains:
+			predicates = append(predicates, column+" ILIKE "+placeholder("%"+escapeLike(filter.Values[0])+"%"))
+		default:
+			operator, ok := comparisonOperators[filter.Op]
+			if !ok {
+				return Condition{}, fmt.Errorf("%w: %s on %s", ErrInvalidFilterOperator, filter.Op, filter.Field)
+			}
+			predicates = append(predicates, column+" "+operator+" "+placeholder(filter.Values[0]))
+		}
+	}
+
+	if q.Search != "" && len(searchColumns) > 0 {
+		pattern := placeholder("%" + escapeLike(q.Search) + "%")
+		matches := make([]string, 0, len(searchColumns))
+		for _, column := range searchColumns {
+			matches = append(matches, column+" ILIKE "+pattern)
+		}
+		predicates = append(predicates, "("+strings.Join(matches, " OR ")+")")
+	}
+
+	return Condition{Predicate: strings.Join(predicates, " AND "), Args: args}, nil
+}
+
+// OrderBy builds the ORDER BY list of the sort of q, followed by idColumn so pages are stable.
+// columns maps the sortable fields to their SQL expression like in Where, a field missing from it
+// returns ErrInvalidSortingField. Without sort fields the rows are ordered by idColumn only.
+func (q ListQuery) OrderBy(columns map[string]string, idColumn string) (string, error) {
+	terms := make([]string, 0, len(q.Sort)+1)
+	for _, field := range q.Sort {
+		column, ok := columns[field.Field]
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrInvalidSortingField, field.Field)
+		}
+		terms = append(terms, column+" "+sortDirection(field.Descending))
+	}
+
+	descending := len(q.Sort) > 0 && q.Sort[len(q.Sort)-1].Descending
+	if !slices.ContainsFunc(q.Sort, func(field SortField) bool { return columns[field.Field] == idColumn }) {
+		terms = append(terms, idColumn+" "+sortDirection(descending))
+	}
+	return strings.Join(terms, ", "), nil
+}
+
+var comparisonOperators = map[FilterOp]string{
+	OpEq:  "=",
+	OpNe:  "<>",
+	OpLt:  "<",
+	OpLte: "<=",
+	OpGt:  ">",
+	OpGte: ">=",
+}
+
+func sortDirection(descending bool) string {
+	if descending {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, so user input is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,86 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/NYCU-SDC/summer/pkg/handler"
+)
+
+var ErrNotProblemResponse = errors.New("response is not application/problem+json")
+
+// FromResponse decodes an application/problem+json body returned by another service.
+// The response body is consumed but not closed, the caller still owns it.
+func FromResponse(resp *http.Response) (Problem, error) {
+	if resp == nil || resp.Body == nil {
+		return Problem{}, fmt.Errorf("%w: empty response", ErrNotProblemResponse)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/problem+json" {
+		return Problem{}, fmt.Errorf("%w: got %q", ErrNotProblemResponse, resp.Header.Get("Content-Type"))
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Problem{}, fmt.Errorf("failed to read problem response body: %w", err)
+	}
+
+	var problem Problem
+	err = json.Unmarshal(bodyBytes, &problem)
+	if err != nil {
+		return Problem{}, fmt.Errorf("failed to decode problem response: %w", err)
+	}
+
+	// Some services omit the status member, fall back to the HTTP status code
+	if problem.Status == 0 {
+		problem.Status = resp.StatusCode
+	}
+
+	return problem, nil
+}
+
+// AsError maps the problem back to the matching handlerutil error, so that a problem
+// received from a downstream service is written out again with the same status
+func (p Problem) AsError() error {
+	switch p.Status {
+	case http.StatusNotFound:
+		return handlerutil.NewNotFoundError("", "", "", p.Detail)
+	case http.StatusBadRequest:
+		validationErr := handlerutil.NewValidationError("", nil, p.Detail)
+		if len(p.Errors) > 0 {
+			validationErr = handlerutil.NewValidationErrorWithErrors(p.Detail, p.Errors)
+		}
+		for _, v := range p.Violations {
+			validationErr.Violations = append(validationErr.Violations, handlerutil.FieldViolation{
+				Pointer:   v.Source.Pointer,
+				Parameter: v.Source.Parameter,
+				Header:    v.Source.Header,
+				Message:   v.Detail,
+			})
+		}
+		return validationErr
+	case http.StatusUnauthorized:
+		return fmt.Errorf("%w: %s", handlerutil.ErrUnauthorized, p.Detail)
+	case http.StatusForbidden:
+		return fmt.Errorf("%w: %s", handlerutil.ErrForbidden, p.Detail)
+	case http.StatusConflict:
+		return handlerutil.NewConflictError("", "", p.Detail)
+	case http.StatusPreconditionFailed:
+		return handlerutil.NewPreconditionFailedError("", "", p.Detail)
+	case http.StatusUnprocessableEntity:
+		return fmt.Errorf("%w: %s", handlerutil.ErrUnprocessableEntity, p.Detail)
+	case http.StatusRequestEntityTooLarge:
+		return handlerutil.NewPayloadTooLargeError(0, 0, p.Detail)
+	case http.StatusTooManyRequests:
+		return handlerutil.NewTooManyRequestsError(0, p.Detail)
+	case http.StatusServiceUnavailable:
+		return handlerutil.NewDependencyUnavailableError(nil, 0, p.Detail)
+	default:
+		return fmt.Errorf("%w: %s (status %d)", handlerutil.ErrInternalServer, p.Detail, p.Status)
+	}
+}
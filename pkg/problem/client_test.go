@@ -0,0 +1,163 @@
+package problem
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	handlerutil "github.com/NYCU-SDC/summer/pkg/handler"
+)
+
+func newProblemResponse(status int, contentType, body string) *http.Response {
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	return &http.Response{
+		StatusCode: status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestFromResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		resp        *http.Response
+		wantErr     error
+		wantStatus  int
+		wantDetail  string
+		wantErrsLen int
+	}{
+		{
+			name:       "Should decode problem response",
+			resp:       newProblemResponse(http.StatusNotFound, "application/problem+json", `{"title":"Not Found","status":404,"type":"https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/404","detail":"user not found"}`),
+			wantStatus: http.StatusNotFound,
+			wantDetail: "user not found",
+		},
+		{
+			name:        "Should decode problem response with errors and charset",
+			resp:        newProblemResponse(http.StatusBadRequest, "application/problem+json; charset=utf-8", `{"title":"Validation Problem","status":400,"detail":"invalid","errors":["a","b"]}`),
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "invalid",
+			wantErrsLen: 2,
+		},
+		{
+			name:       "Should fall back to HTTP status when status member is missing",
+			resp:       newProblemResponse(http.StatusForbidden, "application/problem+json", `{"title":"Forbidden"}`),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:    "Should reject non problem content type",
+			resp:    newProblemResponse(http.StatusOK, "application/json", `{}`),
+			wantErr: ErrNotProblemResponse,
+		},
+		{
+			name:    "Should reject nil response",
+			resp:    nil,
+			wantErr: ErrNotProblemResponse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem, err := FromResponse(tt.resp)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("FromResponse() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FromResponse() unexpected error: %v", err)
+			}
+
+			if problem.Status != tt.wantStatus {
+				t.Errorf("FromResponse().Status = %v, want %v", problem.Status, tt.wantStatus)
+			}
+
+			if problem.Detail != tt.wantDetail {
+				t.Errorf("FromResponse().Detail = %v, want %v", problem.Detail, tt.wantDetail)
+			}
+
+			if len(problem.Errors) != tt.wantErrsLen {
+				t.Errorf("FromResponse().Errors length = %v, want %v", len(problem.Errors), tt.wantErrsLen)
+			}
+		})
+	}
+}
+
+func TestProblem_AsError(t *testing.T) {
+	tests := []struct {
+		name    string
+		problem Problem
+		target  error
+	}{
+		{
+			name:    "Should map 404 to ErrNotFound",
+			problem: NewNotFoundProblem("user not found"),
+			target:  handlerutil.ErrNotFound,
+		},
+		{
+			name:    "Should map 400 to ErrValidation",
+			problem: NewValidateProblemWithErrors("invalid", []string{"email"}),
+			target:  handlerutil.ErrValidation,
+		},
+		{
+			name:    "Should map 401 to ErrUnauthorized",
+			problem: NewUnauthorizedProblem("login required"),
+			target:  handlerutil.ErrUnauthorized,
+		},
+		{
+			name:    "Should map 403 to ErrForbidden",
+			problem: NewForbiddenProblem("no permission"),
+			target:  handlerutil.ErrForbidden,
+		},
+		{
+			name:    "Should map 409 to ErrConflict",
+			problem: NewConflictProblem("email already taken"),
+			target:  handlerutil.ErrConflict,
+		},
+		{
+			name:    "Should map 412 to ErrPreconditionFailed",
+			problem: NewPreconditionFailedProblem("order was modified"),
+			target:  handlerutil.ErrPreconditionFailed,
+		},
+		{
+			name:    "Should map 422 to ErrUnprocessableEntity",
+			problem: NewUnprocessableEntityProblem("referenced user does not exist"),
+			target:  handlerutil.ErrUnprocessableEntity,
+		},
+		{
+			name:    "Should map 429 to ErrTooManyRequests",
+			problem: NewTooManyRequestsProblem("slow down"),
+			target:  handlerutil.ErrTooManyRequests,
+		},
+		{
+			name:    "Should map 503 to ErrServiceUnavailable",
+			problem: NewServiceUnavailableProblem("dependencies unavailable: postgres"),
+			target:  handlerutil.ErrServiceUnavailable,
+		},
+		{
+			name:    "Should map unknown status to ErrInternalServer",
+			problem: Problem{Status: http.StatusBadGateway, Detail: "upstream failed"},
+			target:  handlerutil.ErrInternalServer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.problem.AsError()
+			if !errors.Is(err, tt.target) {
+				t.Errorf("AsError() = %v, want errors.Is %v", err, tt.target)
+			}
+
+			// The mapped error should produce the same status when written again
+			rebuilt := New().buildProblem(context.Background(), err)
+			if rebuilt.Status != tt.problem.Status && tt.problem.Status < 500 {
+				t.Errorf("buildProblem(AsError()).Status = %v, want %v", rebuilt.Status, tt.problem.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package problem
+
+import (
+	"errors"
+)
+
+// expectedError marks an error the handler anticipates, see Expected
+type expectedError struct {
+	err error
+}
+
+func (e expectedError) Error() string {
+	return e.err.Error()
+}
+
+func (e expectedError) Unwrap() error {
+	return e.err
+}
+
+// Expected marks err as anticipated, e.g. the 404 of a polling endpoint until the resource is
+// ready, so that WriteError logs it at Debug instead of Warn. The problem written to the client
+// is the same as for err, as long as the ProblemMapping inspects it with errors.Is and errors.As.
+func Expected(err error) error {
+	if err == nil {
+		return nil
+	}
+	return expectedError{err: err}
+}
+
+// IsExpected reports whether err, or any error it wraps, was marked with Expected
+func IsExpected(err error) bool {
+	var expected expectedError
+	return errors.As(err, &expected)
+}
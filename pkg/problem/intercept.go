@@ -0,0 +1,112 @@
+package problem
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// maxInterceptedBody is the number of bytes of an intercepted body kept to build the problem detail
+const maxInterceptedBody = 4096
+
+// interceptWriter holds back error responses that are not problem+json until the handler returns
+type interceptWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	intercepted bool
+	status      int
+	body        bytes.Buffer
+}
+
+func (w *interceptWriter) WriteHeader(code int) {
+	if w.wroteHeader || w.intercepted {
+		return
+	}
+
+	if code >= 400 && !isProblemContentType(w.Header().Get("Content-Type")) {
+		w.intercepted = true
+		w.status = code
+		return
+	}
+
+	if code >= 200 {
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *interceptWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader && !w.intercepted {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.intercepted {
+		remaining := maxInterceptedBody - w.body.Len()
+		if remaining > 0 {
+			w.body.Write(b[:min(len(b), remaining)])
+		}
+		return len(b), nil
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *interceptWriter) HeaderWritten() bool {
+	return w.wroteHeader || w.intercepted
+}
+
+func (w *interceptWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// InterceptMiddleware rewrites error responses written with http.Error or a bare WriteHeader(>=400)
+// into problem+json bodies, so legacy handlers and third-party libraries answer in the same format as
+// the rest of the API. Responses that are already problem+json are passed through untouched.
+func InterceptMiddleware(next http.HandlerFunc, logger *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		iw := &interceptWriter{ResponseWriter: w}
+		next(iw, r)
+
+		if !iw.intercepted {
+			return
+		}
+
+		detail := http.StatusText(iw.status)
+		if mediaType, _, _ := mime.ParseMediaType(w.Header().Get("Content-Type")); mediaType == "text/plain" {
+			if text := strings.TrimSpace(iw.body.String()); text != "" {
+				detail = text
+			}
+		}
+
+		problem := DefaultSanitizer(nil, NewProblem(iw.status, detail))
+		if r.URL != nil {
+			problem.Instance = r.URL.Path
+		}
+
+		logger.Debug("Rewrote plain error response into problem", zap.Int("status", iw.status), zap.String("path", problem.Instance))
+
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(problem.Status)
+
+		jsonBytes, err := json.Marshal(problem)
+		if err != nil {
+			logger.Error("Failed to marshal problem response", zap.Error(err))
+			return
+		}
+
+		_, err = w.Write(jsonBytes)
+		if err != nil {
+			logger.Error("Failed to write problem response", zap.Error(err))
+		}
+	}
+}
+
+func isProblemContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/problem+json"
+}
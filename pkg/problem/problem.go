@@ -4,10 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/NYCU-SDC/summer/pkg/database"
 	"github.com/NYCU-SDC/summer/pkg/handler"
+	"github.com/NYCU-SDC/summer/pkg/log"
 	"github.com/NYCU-SDC/summer/pkg/pagination"
 	"github.com/go-playground/validator/v10"
 	"go.opentelemetry.io/otel"
@@ -28,14 +32,61 @@ type Problem struct {
 	Instance string `json:"instance,omitempty"`
 
 	Errors []string `json:"errors,omitempty"`
+
+	// Violations point at the request fields a validation problem is about
+	Violations []Violation `json:"violations,omitempty"`
+
+	// Extensions are written as additional top-level members, see IsValidExtensionName
+	Extensions map[string]any `json:"-"`
+}
+
+// Violation is a single failed field, in the shape of a JSON:API error object
+type Violation struct {
+	Detail string          `json:"detail"`
+	Source ViolationSource `json:"source"`
+}
+
+// ViolationSource locates a violation, only one of its fields is set
+type ViolationSource struct {
+	// Pointer is a JSON Pointer (RFC 6901) into the request body, e.g. "/items/2/email"
+	Pointer string `json:"pointer,omitempty"`
+
+	// Parameter names a path or query parameter, e.g. "page"
+	Parameter string `json:"parameter,omitempty"`
+
+	// Header names a request header, e.g. "X-Tenant-ID"
+	Header string `json:"header,omitempty"`
 }
 
 func (p Problem) IsEmpty() bool {
-	return p.Title == "" && p.Status == 0 && p.Type == "" && p.Detail == "" && p.Instance == "" && len(p.Errors) == 0
+	return p.Title == "" && p.Status == 0 && p.Type == "" && p.Detail == "" && p.Instance == "" && len(p.Errors) == 0 && len(p.Violations) == 0 && len(p.Extensions) == 0
+}
+
+// StatusCoder can be implemented by domain errors to choose the HTTP status of their problem
+// without registering a custom ProblemMapping
+type StatusCoder interface {
+	HTTPStatus() int
+}
+
+// ProblemTitler can be implemented together with StatusCoder to override the problem title,
+// which defaults to the status text
+type ProblemTitler interface {
+	ProblemTitle() string
 }
 
 type HttpWriter struct {
 	ProblemMapping func(error) Problem
+
+	// Sanitizer is applied to every problem right before it is written,
+	// it should remove anything from the problem that must not reach the client
+	Sanitizer func(error, Problem) Problem
+
+	// LegacyFormat writes problems as before RFC 9457 support: an empty type is not replaced by
+	// about:blank, an empty detail is still written, and extension members are left out
+	LegacyFormat bool
+
+	// registry is created with the writer, so RegisterMapping may run concurrently with WriteError
+	registry *registry
 }
 
 func New() *HttpWriter {
@@ -43,37 +94,84 @@ func New() *HttpWriter {
 		ProblemMapping: func(err error) Problem {
 			return Problem{}
 		},
+		Sanitizer: DefaultSanitizer,
+		registry:  &registry{},
 	}
 }
 
 func NewWithMapping(ProblemMapping func(error) Problem) *HttpWriter {
 	return &HttpWriter{
 		ProblemMapping: ProblemMapping,
+		Sanitizer:      DefaultSanitizer,
+		registry:       &registry{},
 	}
 }
 
-// buildProblem converts an error into a Problem struct
-func (h *HttpWriter) buildProblem(err error) Problem {
+// buildProblem converts an error into a Problem struct, requests marked with logutil.WithDebug
+// get the raw error appended to Errors instead of a sanitized problem
+func (h *HttpWriter) buildProblem(ctx context.Context, err error) Problem {
 	// Check if the error matches the custom error type
 	problem := h.ProblemMapping(err)
 
+	// If the problem is still empty, check the sentinel errors registered on the writer, then on the package
+	if problem.IsEmpty() && h.registry != nil {
+		problem = h.registry.lookup(err)
+	}
+	if problem.IsEmpty() {
+		problem = defaultRegistry.lookup(err)
+	}
+
+	// If the problem is still empty, check whether the error chooses its own status
+	var statusCoder StatusCoder
+	if problem.IsEmpty() && errors.As(err, &statusCoder) {
+		problem = newStatusCoderProblem(err, statusCoder)
+	}
+
 	// If the problem is still empty, check for standard error types
 	if problem.IsEmpty() {
 		var notFoundError handlerutil.NotFoundError
 		var validationError handlerutil.ValidationError
 		var validationErrors validator.ValidationErrors
+		var conflictError handlerutil.ConflictError
+		var payloadTooLargeError handlerutil.PayloadTooLargeError
+		var tooManyRequestsError handlerutil.TooManyRequestsError
+		var preconditionFailedError handlerutil.PreconditionFailedError
+		var dependencyUnavailableError handlerutil.DependencyUnavailableError
 		var internalDbError databaseutil.InternalServerError
+		var uniqueViolationError databaseutil.UniqueViolationError
+		var paginationParameterError pagination.ParameterError
 		switch {
 		case errors.As(err, &notFoundError):
 			problem = NewNotFoundProblem(err.Error())
+		case errors.As(err, &conflictError):
+			// Resource and Constraint name the schema, only an explicit Message reaches the client
+			problem = NewConflictProblem("Resource conflicts with its current state")
+			if conflictError.Message != "" {
+				problem.Detail = conflictError.Message
+			}
+		case errors.As(err, &payloadTooLargeError):
+			problem = NewPayloadTooLargeProblem(payloadTooLargeError.Error())
+		case errors.As(err, &tooManyRequestsError):
+			problem = NewTooManyRequestsProblem(tooManyRequestsError.Error())
+		case errors.As(err, &preconditionFailedError):
+			problem = NewPreconditionFailedProblem("Resource was modified, reload it and try again")
+			if preconditionFailedError.Message != "" {
+				problem.Detail = preconditionFailedError.Message
+			}
+		case errors.As(err, &dependencyUnavailableError):
+			problem = NewServiceUnavailableProblem(dependencyUnavailableError.Error())
 		case errors.As(err, &validationError):
 			if len(validationError.Errors) > 0 {
 				problem = NewValidateProblemWithErrors(validationError.Error(), validationError.Errors)
 			} else {
 				problem = NewValidateProblem(validationError.Error())
 			}
+			problem.Violations = newViolations(validationError.Violations)
 		case errors.As(err, &validationErrors):
 			problem = NewValidateProblem(validationErrors.Error())
+			if errors.As(handlerutil.TranslateValidationErrors(validationErrors), &validationError) {
+				problem.Violations = newViolations(validationError.Violations)
+			}
 		case errors.Is(err, handlerutil.ErrUserAlreadyExists):
 			problem = NewValidateProblem("User already exists")
 		case errors.Is(err, handlerutil.ErrCredentialInvalid):
@@ -88,17 +186,82 @@ func (h *HttpWriter) buildProblem(err error) Problem {
 			problem = NewValidateProblem("Validation error")
 		case errors.Is(err, handlerutil.ErrNotFound):
 			problem = NewNotFoundProblem("Resource not found")
+		case errors.Is(err, handlerutil.ErrConflict):
+			problem = NewConflictProblem("Resource conflicts with its current state")
+		case errors.Is(err, handlerutil.ErrPayloadTooLarge):
+			problem = NewPayloadTooLargeProblem("Request body is too large")
+		case errors.Is(err, handlerutil.ErrTooManyRequests):
+			problem = NewTooManyRequestsProblem("Too many requests, please retry later")
+		case errors.Is(err, handlerutil.ErrPreconditionFailed):
+			problem = NewPreconditionFailedProblem("Resource was modified, reload it and try again")
+		case errors.Is(err, handlerutil.ErrUnprocessableEntity):
+			problem = NewUnprocessableEntityProblem("Request is well-formed but cannot be processed")
+		case errors.Is(err, handlerutil.ErrServiceUnavailable):
+			problem = NewServiceUnavailableProblem("Service is temporarily unavailable, please retry later")
+		case errors.As(err, &uniqueViolationError) && len(uniqueViolationError.Columns) > 0:
+			problem = NewConflictProblem("Resource already exists")
+			problem.Violations = newUniqueViolations(uniqueViolationError)
+		case errors.Is(err, databaseutil.ErrUniqueViolation):
+			problem = NewConflictProblem("Resource already exists")
+		case errors.Is(err, databaseutil.ErrForeignKeyViolation):
+			problem = NewUnprocessableEntityProblem("Referenced resource does not exist or is still in use")
+		case errors.Is(err, databaseutil.ErrCheckViolation):
+			problem = NewUnprocessableEntityProblem("Resource does not satisfy a data rule")
+		case errors.Is(err, databaseutil.ErrNotNullViolation):
+			problem = NewValidateProblem("Required field is missing")
+		case errors.Is(err, databaseutil.ErrInvalidEnumValue):
+			problem = NewValidateProblem("Value is not one of the allowed values")
+		case errors.Is(err, databaseutil.ErrDatabaseUnavailable):
+			problem = NewServiceUnavailableProblem("Database is temporarily unavailable, please retry later")
+		case errors.Is(err, databaseutil.ErrIdempotencyKeyReused):
+			problem = NewUnprocessableEntityProblem("Idempotency key was already used for a different request")
 		case errors.As(err, &internalDbError):
 			problem = NewInternalServerProblem("Internal server error")
+		case errors.As(err, &paginationParameterError):
+			problem = NewValidateProblem("Invalid " + paginationParameterError.Parameter)
+			problem.Violations = []Violation{{
+				Detail: paginationParameterError.Parameter + " " + paginationParameterError.Message,
+				Source: ViolationSource{Parameter: paginationParameterError.Parameter},
+			}}
 		case errors.Is(err, pagination.ErrInvalidPageOrSize):
 			problem = NewValidateProblem("Invalid page or size")
 		case errors.Is(err, pagination.ErrInvalidSortingField):
 			problem = NewValidateProblem("Invalid sorting field")
+		case errors.Is(err, pagination.ErrInvalidCursor):
+			problem = NewValidateProblem("Invalid cursor")
+		case errors.Is(err, pagination.ErrInvalidFilterField):
+			problem = NewValidateProblem("Invalid filter field")
+		case errors.Is(err, pagination.ErrInvalidFilterOperator):
+			problem = NewValidateProblem("Invalid filter operator")
 		default:
 			problem = NewInternalServerProblem("Internal server error")
 		}
 	}
 
+	if logutil.DebugEnabled(ctx) {
+		problem.Errors = append(problem.Errors, err.Error())
+	} else if h.Sanitizer != nil {
+		problem = h.Sanitizer(err, problem)
+	}
+
+	return problem
+}
+
+// newStatusCoderProblem builds a Problem from an error implementing StatusCoder,
+// an empty Problem is returned when the status is not a 4xx or 5xx code
+func newStatusCoderProblem(err error, statusCoder StatusCoder) Problem {
+	status := statusCoder.HTTPStatus()
+	if status < 400 || status > 599 {
+		return Problem{}
+	}
+
+	problem := NewProblem(status, err.Error())
+
+	var titler ProblemTitler
+	if errors.As(err, &titler) && titler.ProblemTitle() != "" {
+		problem.Title = titler.ProblemTitle()
+	}
+
 	return problem
 }
 
@@ -106,11 +269,34 @@ func (h *HttpWriter) buildProblem(err error) Problem {
 func (h *HttpWriter) writeProblemResponse(w http.ResponseWriter, problem Problem, err error, logger *zap.Logger) {
 	logger = logger.WithOptions(zap.AddCallerSkip(2))
 
-	logger.Warn("Handling "+problem.Title, zap.String("problem", problem.Title), zap.Error(err), zap.Int("status", problem.Status), zap.String("type", problem.Type), zap.String("detail", problem.Detail))
+	logProblem := logger.Warn
+	if IsExpected(err) {
+		logProblem = logger.Debug
+	}
+	logProblem("Handling "+problem.Title, zap.String("problem", problem.Title), zap.Error(err), zap.Int("status", problem.Status), zap.String("type", problem.Type), zap.String("detail", problem.Detail))
+
+	if headerWritten(w) {
+		logger.Error("Response already started, skipping problem response", zap.String("problem", problem.Title), zap.Int("status", problem.Status))
+		return
+	}
+
+	var tooManyRequestsError handlerutil.TooManyRequestsError
+	var dependencyUnavailableError handlerutil.DependencyUnavailableError
+	if errors.As(err, &tooManyRequestsError) && tooManyRequestsError.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(tooManyRequestsError.RetryAfter.Seconds()))))
+	} else if errors.As(err, &dependencyUnavailableError) && dependencyUnavailableError.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(dependencyUnavailableError.RetryAfter.Seconds()))))
+	}
 
 	w.Header().Set("Content-Type", "application/problem+json")
 	w.WriteHeader(problem.Status)
-	jsonBytes, marshalErr := json.Marshal(problem)
+	var jsonBytes []byte
+	var marshalErr error
+	if h.LegacyFormat {
+		jsonBytes, marshalErr = json.Marshal(legacyProblem(problem))
+	} else {
+		jsonBytes, marshalErr = json.Marshal(problem)
+	}
 	if marshalErr != nil {
 		logger.Error("Failed to marshal problem response", zap.Error(marshalErr))
 		http.Error(w, marshalErr.Error(), http.StatusInternalServerError)
@@ -133,7 +319,7 @@ func (h *HttpWriter) WriteError(ctx context.Context, w http.ResponseWriter, err
 		return
 	}
 
-	problem := h.buildProblem(err)
+	problem := h.buildProblem(ctx, err)
 	h.writeProblemResponse(w, problem, err, logger)
 }
 
@@ -145,7 +331,7 @@ func (h *HttpWriter) WriteErrorWithRequest(ctx context.Context, r *http.Request,
 		return
 	}
 
-	problem := h.buildProblem(err)
+	problem := h.buildProblem(ctx, err)
 	if r != nil && r.URL != nil {
 		problem.Instance = r.URL.Path
 	}
@@ -189,6 +375,34 @@ func NewValidateProblemWithErrors(detail string, errors []string) Problem {
 	}
 }
 
+func newViolations(fieldViolations []handlerutil.FieldViolation) []Violation {
+	if len(fieldViolations) == 0 {
+		return nil
+	}
+
+	violations := make([]Violation, 0, len(fieldViolations))
+	for _, v := range fieldViolations {
+		violations = append(violations, Violation{
+			Detail: v.Message,
+			Source: ViolationSource{Pointer: v.Pointer, Parameter: v.Parameter, Header: v.Header},
+		})
+	}
+	return violations
+}
+
+// newUniqueViolations points at the body field of every conflicting column, the values are left
+// out since they may belong to another user
+func newUniqueViolations(err databaseutil.UniqueViolationError) []Violation {
+	violations := make([]Violation, 0, len(err.Columns))
+	for _, column := range err.Columns {
+		violations = append(violations, Violation{
+			Detail: column + " is already taken",
+			Source: ViolationSource{Pointer: "/" + column},
+		})
+	}
+	return violations
+}
+
 func NewUnauthorizedProblem(detail string) Problem {
 	return Problem{
 		Title:  "Unauthorized",
@@ -215,3 +429,79 @@ func NewBadRequestProblem(detail string) Problem {
 		Detail: detail,
 	}
 }
+
+func NewConflictProblem(detail string) Problem {
+	return Problem{
+		Title:  "Conflict",
+		Status: http.StatusConflict,
+		Type:   "https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/409",
+		Detail: detail,
+	}
+}
+
+func NewUnprocessableEntityProblem(detail string) Problem {
+	return Problem{
+		Title:  "Unprocessable Entity",
+		Status: http.StatusUnprocessableEntity,
+		Type:   "https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/422",
+		Detail: detail,
+	}
+}
+
+// NewProblem creates a Problem for any status, the title is derived from http.StatusText
+func NewProblem(status int, detail string) Problem {
+	return Problem{
+		Title:  http.StatusText(status),
+		Status: status,
+		Type:   fmt.Sprintf("https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/%d", status),
+		Detail: detail,
+	}
+}
+
+func NewMethodNotAllowedProblem(detail string) Problem {
+	return NewProblem(http.StatusMethodNotAllowed, detail)
+}
+
+func NewNotAcceptableProblem(detail string) Problem {
+	return NewProblem(http.StatusNotAcceptable, detail)
+}
+
+func NewRequestTimeoutProblem(detail string) Problem {
+	return NewProblem(http.StatusRequestTimeout, detail)
+}
+
+func NewGoneProblem(detail string) Problem {
+	return NewProblem(http.StatusGone, detail)
+}
+
+func NewPreconditionFailedProblem(detail string) Problem {
+	return NewProblem(http.StatusPreconditionFailed, detail)
+}
+
+func NewUnsupportedMediaTypeProblem(detail string) Problem {
+	return NewProblem(http.StatusUnsupportedMediaType, detail)
+}
+
+func NewPayloadTooLargeProblem(detail string) Problem {
+	return NewProblem(http.StatusRequestEntityTooLarge, detail)
+}
+
+func NewTooManyRequestsProblem(detail string) Problem {
+	return NewProblem(http.StatusTooManyRequests, detail)
+}
+
+func NewNotImplementedProblem(detail string) Problem {
+	return NewProblem(http.StatusNotImplemented, detail)
+}
+
+func NewBadGatewayProblem(detail string) Problem {
+	return NewProblem(http.StatusBadGateway, detail)
+}
+
+func NewServiceUnavailableProblem(detail string) Problem {
+	return NewProblem(http.StatusServiceUnavailable, detail)
+}
+
+func NewGatewayTimeoutProblem(detail string) Problem {
+	return NewProblem(http.StatusGatewayTimeout, detail)
+}
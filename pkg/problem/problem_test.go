@@ -4,13 +4,23 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"sync"
 	"testing"
+	"time"
 
+	databaseutil "github.com/NYCU-SDC/summer/pkg/database"
 	handlerutil "github.com/NYCU-SDC/summer/pkg/handler"
+	logutil "github.com/NYCU-SDC/summer/pkg/log"
 	"github.com/NYCU-SDC/summer/pkg/pagination"
+	"github.com/jackc/pgx/v5/pgconn"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 func TestWriteError_ValidationError(t *testing.T) {
@@ -528,12 +538,76 @@ func TestHttpWriter_buildProblem(t *testing.T) {
 			wantType:   "https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/404",
 			wantDetail: "Resource not found",
 		},
+		{
+			name:       "Should handle ErrInvalidCursor",
+			err:        fmt.Errorf("%w: authentication failed", pagination.ErrInvalidCursor),
+			wantStatus: http.StatusBadRequest,
+			wantTitle:  "Validation Problem",
+			wantType:   "https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/400",
+			wantDetail: "Invalid cursor",
+		},
+		{
+			name:       "Should handle ErrInvalidFilterField",
+			err:        fmt.Errorf("%w: password", pagination.ErrInvalidFilterField),
+			wantStatus: http.StatusBadRequest,
+			wantTitle:  "Validation Problem",
+			wantType:   "https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/400",
+			wantDetail: "Invalid filter field",
+		},
+		{
+			name:       "Should handle ErrUniqueViolation",
+			err:        fmt.Errorf("%w: duplicate key", databaseutil.ErrUniqueViolation),
+			wantStatus: http.StatusConflict,
+			wantTitle:  "Conflict",
+			wantType:   "https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/409",
+			wantDetail: "Resource already exists",
+		},
+		{
+			name:       "Should handle ErrForeignKeyViolation",
+			err:        fmt.Errorf("%w: missing parent", databaseutil.ErrForeignKeyViolation),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantTitle:  "Unprocessable Entity",
+			wantType:   "https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/422",
+			wantDetail: "Referenced resource does not exist or is still in use",
+		},
+		{
+			name:       "Should handle ErrCheckViolation",
+			err:        fmt.Errorf("%w: price must be positive", databaseutil.ErrCheckViolation),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantTitle:  "Unprocessable Entity",
+			wantType:   "https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/422",
+			wantDetail: "Resource does not satisfy a data rule",
+		},
+		{
+			name:       "Should handle ErrNotNullViolation",
+			err:        fmt.Errorf("%w: name is null", databaseutil.ErrNotNullViolation),
+			wantStatus: http.StatusBadRequest,
+			wantTitle:  "Validation Problem",
+			wantType:   "https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/400",
+			wantDetail: "Required field is missing",
+		},
+		{
+			name:       "Should handle ErrInvalidEnumValue",
+			err:        fmt.Errorf("%w: \"owner\" is not a valid role", databaseutil.ErrInvalidEnumValue),
+			wantStatus: http.StatusBadRequest,
+			wantTitle:  "Validation Problem",
+			wantType:   "https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/400",
+			wantDetail: "Value is not one of the allowed values",
+		},
+		{
+			name:       "Should handle ErrDatabaseUnavailable",
+			err:        databaseutil.ErrDatabaseUnavailable,
+			wantStatus: http.StatusServiceUnavailable,
+			wantTitle:  "Service Unavailable",
+			wantType:   "https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/503",
+			wantDetail: "Database is temporarily unavailable, please retry later",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			hw := New()
-			problem := hw.buildProblem(tt.err)
+			problem := hw.buildProblem(context.Background(), tt.err)
 
 			if problem.Status != tt.wantStatus {
 				t.Errorf("buildProblem().Status = %v, want %v", problem.Status, tt.wantStatus)
@@ -590,7 +664,7 @@ func TestHttpWriter_buildProblem_WithCustomMapping(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			hw := NewWithMapping(tt.problemMapping)
-			problem := hw.buildProblem(tt.err)
+			problem := hw.buildProblem(context.Background(), tt.err)
 
 			if problem.Status != tt.wantStatus {
 				t.Errorf("buildProblem().Status = %v, want %v", problem.Status, tt.wantStatus)
@@ -739,18 +813,66 @@ func TestHttpWriter_buildProblem_AllErrorTypes(t *testing.T) {
 			wantStatus: http.StatusBadRequest,
 			wantTitle:  "Validation Problem",
 		},
+		{
+			name:       "Should handle pagination parameter error",
+			err:        pagination.ParameterError{Parameter: "page", Value: "0", Message: "must be at least 1"},
+			wantStatus: http.StatusBadRequest,
+			wantTitle:  "Validation Problem",
+		},
 		{
 			name:       "Should handle pagination invalid sorting field error",
 			err:        pagination.ErrInvalidSortingField,
 			wantStatus: http.StatusBadRequest,
 			wantTitle:  "Validation Problem",
 		},
+		{
+			name:       "Should handle conflict error",
+			err:        handlerutil.NewConflictError("users", "unique_email", ""),
+			wantStatus: http.StatusConflict,
+			wantTitle:  "Conflict",
+		},
+		{
+			name:       "Should handle payload too large error",
+			err:        handlerutil.NewPayloadTooLargeError(1024, 2048, ""),
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantTitle:  "Request Entity Too Large",
+		},
+		{
+			name:       "Should handle too many requests error",
+			err:        handlerutil.NewTooManyRequestsError(time.Minute, ""),
+			wantStatus: http.StatusTooManyRequests,
+			wantTitle:  "Too Many Requests",
+		},
+		{
+			name:       "Should handle precondition failed error",
+			err:        handlerutil.NewPreconditionFailedError("order", "If-Match", ""),
+			wantStatus: http.StatusPreconditionFailed,
+			wantTitle:  "Precondition Failed",
+		},
+		{
+			name:       "Should handle dependency unavailable error",
+			err:        handlerutil.NewDependencyUnavailableError([]string{"postgres"}, 30*time.Second, ""),
+			wantStatus: http.StatusServiceUnavailable,
+			wantTitle:  "Service Unavailable",
+		},
+		{
+			name:       "Should handle wrapped conflict sentinel",
+			err:        fmt.Errorf("create user: %w", handlerutil.ErrConflict),
+			wantStatus: http.StatusConflict,
+			wantTitle:  "Conflict",
+		},
+		{
+			name:       "Should handle reused idempotency key error",
+			err:        fmt.Errorf("%w: abc", databaseutil.ErrIdempotencyKeyReused),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantTitle:  "Unprocessable Entity",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			hw := New()
-			problem := hw.buildProblem(tt.err)
+			problem := hw.buildProblem(context.Background(), tt.err)
 
 			if problem.Status != tt.wantStatus {
 				t.Errorf("buildProblem().Status = %v, want %v", problem.Status, tt.wantStatus)
@@ -763,6 +885,53 @@ func TestHttpWriter_buildProblem_AllErrorTypes(t *testing.T) {
 	}
 }
 
+func TestHttpWriter_buildProblem_ConflictDetail(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantDetail string
+	}{
+		{
+			name:       "Should not expose the resource and constraint of a conflict",
+			err:        handlerutil.NewConflictError("users", "unique_email", ""),
+			wantStatus: http.StatusConflict,
+			wantDetail: "Resource conflicts with its current state",
+		},
+		{
+			name:       "Should keep the message of a conflict",
+			err:        handlerutil.NewConflictError("users", "unique_email", "Email is already taken"),
+			wantStatus: http.StatusConflict,
+			wantDetail: "Email is already taken",
+		},
+		{
+			name:       "Should not expose the resource and precondition of a failed precondition",
+			err:        handlerutil.NewPreconditionFailedError("order", "If-Match", ""),
+			wantStatus: http.StatusPreconditionFailed,
+			wantDetail: "Resource was modified, reload it and try again",
+		},
+		{
+			name:       "Should keep the message of a failed precondition",
+			err:        handlerutil.NewPreconditionFailedError("order", "If-Match", "Order was updated by someone else"),
+			wantStatus: http.StatusPreconditionFailed,
+			wantDetail: "Order was updated by someone else",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := New().buildProblem(context.Background(), tt.err)
+
+			if problem.Status != tt.wantStatus {
+				t.Errorf("buildProblem().Status = %v, want %v", problem.Status, tt.wantStatus)
+			}
+			if problem.Detail != tt.wantDetail {
+				t.Errorf("buildProblem().Detail = %v, want %v", problem.Detail, tt.wantDetail)
+			}
+		})
+	}
+}
+
 func TestNewInternalServerProblem(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -856,3 +1025,634 @@ func TestNewBadRequestProblem(t *testing.T) {
 		})
 	}
 }
+
+func TestDefaultSanitizer(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		problem    Problem
+		wantDetail string
+		wantErrors []string
+	}{
+		{
+			name:       "Should keep detail without SQL fragments",
+			problem:    NewValidateProblem("Email format is invalid"),
+			wantDetail: "Email format is invalid",
+		},
+		{
+			name:       "Should replace detail containing a query",
+			problem:    NewInternalServerProblem("failed: SELECT id, email FROM users WHERE id = $1"),
+			wantDetail: "Internal Server Error",
+		},
+		{
+			name:       "Should replace detail containing a constraint name",
+			problem:    NewNotFoundProblem(`ERROR: insert or update on table "posts" violates foreign key constraint "posts_user_id_fkey" (SQLSTATE 23503)`),
+			wantDetail: "Resource not found",
+		},
+		{
+			name:       "Should drop errors containing SQL fragments",
+			problem:    NewValidateProblemWithErrors("Validation failed", []string{"email: invalid format", `column "password_hash" does not exist`}),
+			wantDetail: "Validation failed",
+			wantErrors: []string{"email: invalid format"},
+		},
+		{
+			name:       "Should replace detail naming the table of a NotFoundError",
+			err:        handlerutil.NewNotFoundError("users", "id", "42", ""),
+			problem:    NewNotFoundProblem("unable to find users with id '42'"),
+			wantDetail: "Resource not found",
+		},
+		{
+			name:       "Should keep the message of a NotFoundError",
+			err:        handlerutil.NewNotFoundError("users", "id", "42", "User not found"),
+			problem:    NewNotFoundProblem("User not found"),
+			wantDetail: "User not found",
+		},
+		{
+			name:       "Should replace detail containing an insert",
+			problem:    NewInternalServerProblem(`failed: INSERT INTO users (email) VALUES ($1)`),
+			wantDetail: "Internal Server Error",
+		},
+		{
+			name:       "Should replace detail containing an update",
+			problem:    NewInternalServerProblem(`failed: UPDATE users SET email = $1 WHERE id = $2`),
+			wantDetail: "Internal Server Error",
+		},
+		{
+			name:       "Should replace detail containing a delete",
+			problem:    NewInternalServerProblem(`failed: DELETE FROM sessions WHERE expires_at < now()`),
+			wantDetail: "Internal Server Error",
+		},
+		{
+			name:       "Should replace detail containing a driver prefix",
+			problem:    NewInternalServerProblem(`pq: relation "users" does not exist`),
+			wantDetail: "Internal Server Error",
+		},
+		{
+			name:       "Should replace detail containing a syntax error",
+			problem:    NewInternalServerProblem(`ERROR: syntax error at or near "FORM"`),
+			wantDetail: "Internal Server Error",
+		},
+		{
+			name:       "Should keep a sentence with select and from",
+			problem:    NewValidateProblem("Please select a value from the list"),
+			wantDetail: "Please select a value from the list",
+		},
+		{
+			name:       "Should keep a sentence with update and set",
+			problem:    NewValidateProblem("Update your profile and set a new password"),
+			wantDetail: "Update your profile and set a new password",
+		},
+		{
+			name:       "Should keep a sentence with delete from",
+			problem:    NewValidateProblem("Cannot delete from an archived project"),
+			wantDetail: "Cannot delete from an archived project",
+		},
+		{
+			name:       "Should keep a sentence with insert into",
+			problem:    NewValidateProblem("Insert into the form a valid email"),
+			wantDetail: "Insert into the form a valid email",
+		},
+		{
+			name:       "Should keep a sentence with column and constraint",
+			problem:    NewValidateProblemWithErrors("Validation failed", []string{"column width violates the layout constraint", "this violates our policy"}),
+			wantDetail: "Validation failed",
+			wantErrors: []string{"column width violates the layout constraint", "this violates our policy"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := DefaultSanitizer(tt.err, tt.problem)
+
+			if problem.Detail != tt.wantDetail {
+				t.Errorf("DefaultSanitizer().Detail = %v, want %v", problem.Detail, tt.wantDetail)
+			}
+
+			if len(problem.Errors) != len(tt.wantErrors) {
+				t.Fatalf("DefaultSanitizer().Errors = %v, want %v", problem.Errors, tt.wantErrors)
+			}
+			for i := range tt.wantErrors {
+				if problem.Errors[i] != tt.wantErrors[i] {
+					t.Errorf("DefaultSanitizer().Errors[%d] = %v, want %v", i, problem.Errors[i], tt.wantErrors[i])
+				}
+			}
+		})
+	}
+}
+
+func TestHttpWriter_Sanitizer(t *testing.T) {
+	hw := New()
+	hw.Sanitizer = func(err error, p Problem) Problem {
+		p.Detail = "sanitized"
+		return p
+	}
+
+	w := httptest.NewRecorder()
+	logger, _ := zap.NewDevelopment()
+	hw.WriteError(context.Background(), w, handlerutil.NewNotFoundError("users", "id", "123", ""), logger)
+
+	var problem Problem
+	if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if problem.Detail != "sanitized" {
+		t.Errorf("WriteError() detail = %v, want sanitized", problem.Detail)
+	}
+}
+
+func TestHttpWriter_WriteError_Debug(t *testing.T) {
+	ctx := logutil.WithDebug(context.Background())
+
+	cause := fmt.Errorf("failed to query users: %w", errors.New(`pq: relation "users" does not exist`))
+
+	w := httptest.NewRecorder()
+	logger, _ := zap.NewDevelopment()
+	New().WriteError(ctx, w, cause, logger)
+
+	var problem Problem
+	if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if len(problem.Errors) != 1 || problem.Errors[0] != cause.Error() {
+		t.Errorf("WriteError() errors = %v, want [%v]", problem.Errors, cause.Error())
+	}
+}
+
+type statusCoderError struct {
+	status int
+	title  string
+}
+
+func (e statusCoderError) Error() string {
+	return "quota exceeded for project"
+}
+
+func (e statusCoderError) HTTPStatus() int {
+	return e.status
+}
+
+func (e statusCoderError) ProblemTitle() string {
+	return e.title
+}
+
+func TestHttpWriter_buildProblem_StatusCoder(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantTitle  string
+		wantType   string
+		wantDetail string
+	}{
+		{
+			name:       "Should use status and title from the error",
+			err:        statusCoderError{status: http.StatusTooManyRequests, title: "Quota Exceeded"},
+			wantStatus: http.StatusTooManyRequests,
+			wantTitle:  "Quota Exceeded",
+			wantType:   "https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/429",
+			wantDetail: "quota exceeded for project",
+		},
+		{
+			name:       "Should default title to status text",
+			err:        fmt.Errorf("wrapped: %w", statusCoderError{status: http.StatusConflict}),
+			wantStatus: http.StatusConflict,
+			wantTitle:  "Conflict",
+			wantType:   "https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/409",
+			wantDetail: "wrapped: quota exceeded for project",
+		},
+		{
+			name:       "Should ignore non error status",
+			err:        statusCoderError{status: http.StatusOK},
+			wantStatus: http.StatusInternalServerError,
+			wantTitle:  "Internal Server Error",
+			wantType:   "https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/500",
+			wantDetail: "Internal server error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := New().buildProblem(context.Background(), tt.err)
+
+			if problem.Status != tt.wantStatus {
+				t.Errorf("buildProblem().Status = %v, want %v", problem.Status, tt.wantStatus)
+			}
+
+			if problem.Title != tt.wantTitle {
+				t.Errorf("buildProblem().Title = %v, want %v", problem.Title, tt.wantTitle)
+			}
+
+			if problem.Type != tt.wantType {
+				t.Errorf("buildProblem().Type = %v, want %v", problem.Type, tt.wantType)
+			}
+
+			if problem.Detail != tt.wantDetail {
+				t.Errorf("buildProblem().Detail = %v, want %v", problem.Detail, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestNewProblem(t *testing.T) {
+	tests := []struct {
+		name      string
+		problem   Problem
+		wantCode  int
+		wantTitle string
+	}{
+		{name: "Should create generic problem", problem: NewProblem(http.StatusTeapot, "short and stout"), wantCode: http.StatusTeapot, wantTitle: "I'm a teapot"},
+		{name: "Should create method not allowed problem", problem: NewMethodNotAllowedProblem(""), wantCode: http.StatusMethodNotAllowed, wantTitle: "Method Not Allowed"},
+		{name: "Should create not acceptable problem", problem: NewNotAcceptableProblem(""), wantCode: http.StatusNotAcceptable, wantTitle: "Not Acceptable"},
+		{name: "Should create request timeout problem", problem: NewRequestTimeoutProblem(""), wantCode: http.StatusRequestTimeout, wantTitle: "Request Timeout"},
+		{name: "Should create gone problem", problem: NewGoneProblem(""), wantCode: http.StatusGone, wantTitle: "Gone"},
+		{name: "Should create precondition failed problem", problem: NewPreconditionFailedProblem(""), wantCode: http.StatusPreconditionFailed, wantTitle: "Precondition Failed"},
+		{name: "Should create unsupported media type problem", problem: NewUnsupportedMediaTypeProblem(""), wantCode: http.StatusUnsupportedMediaType, wantTitle: "Unsupported Media Type"},
+		{name: "Should create too many requests problem", problem: NewTooManyRequestsProblem(""), wantCode: http.StatusTooManyRequests, wantTitle: "Too Many Requests"},
+		{name: "Should create not implemented problem", problem: NewNotImplementedProblem(""), wantCode: http.StatusNotImplemented, wantTitle: "Not Implemented"},
+		{name: "Should create bad gateway problem", problem: NewBadGatewayProblem(""), wantCode: http.StatusBadGateway, wantTitle: "Bad Gateway"},
+		{name: "Should create service unavailable problem", problem: NewServiceUnavailableProblem(""), wantCode: http.StatusServiceUnavailable, wantTitle: "Service Unavailable"},
+		{name: "Should create gateway timeout problem", problem: NewGatewayTimeoutProblem(""), wantCode: http.StatusGatewayTimeout, wantTitle: "Gateway Timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.problem.Status != tt.wantCode {
+				t.Errorf("Status = %v, want %v", tt.problem.Status, tt.wantCode)
+			}
+
+			if tt.problem.Title != tt.wantTitle {
+				t.Errorf("Title = %v, want %v", tt.problem.Title, tt.wantTitle)
+			}
+
+			wantType := fmt.Sprintf("https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/%d", tt.wantCode)
+			if tt.problem.Type != wantType {
+				t.Errorf("Type = %v, want %v", tt.problem.Type, wantType)
+			}
+		})
+	}
+}
+
+func TestHttpWriter_RegisterMapping(t *testing.T) {
+	errQuotaExceeded := errors.New("quota exceeded")
+	errPaymentMissing := errors.New("payment missing")
+
+	RegisterMapping(errPaymentMissing, func(err error) Problem {
+		return NewProblem(http.StatusPaymentRequired, "Payment required")
+	})
+
+	hw := New()
+	hw.RegisterMapping(errQuotaExceeded, func(err error) Problem {
+		return NewTooManyRequestsProblem("Quota exceeded")
+	})
+	hw.RegisterMapping(errPaymentMissing, func(err error) Problem {
+		return NewForbiddenProblem("Writer level mapping")
+	})
+
+	tests := []struct {
+		name       string
+		writer     *HttpWriter
+		err        error
+		wantStatus int
+		wantDetail string
+	}{
+		{
+			name:       "Should use writer level mapping",
+			writer:     hw,
+			err:        fmt.Errorf("project 42: %w", errQuotaExceeded),
+			wantStatus: http.StatusTooManyRequests,
+			wantDetail: "Quota exceeded",
+		},
+		{
+			name:       "Should prefer writer level mapping over package level",
+			writer:     hw,
+			err:        errPaymentMissing,
+			wantStatus: http.StatusForbidden,
+			wantDetail: "Writer level mapping",
+		},
+		{
+			name:       "Should use package level mapping",
+			writer:     New(),
+			err:        errPaymentMissing,
+			wantStatus: http.StatusPaymentRequired,
+			wantDetail: "Payment required",
+		},
+		{
+			name:       "Should keep defaults for unregistered errors",
+			writer:     hw,
+			err:        handlerutil.ErrForbidden,
+			wantStatus: http.StatusForbidden,
+			wantDetail: "Make sure you have the right permissions",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := tt.writer.buildProblem(context.Background(), tt.err)
+
+			if problem.Status != tt.wantStatus {
+				t.Errorf("buildProblem().Status = %v, want %v", problem.Status, tt.wantStatus)
+			}
+
+			if problem.Detail != tt.wantDetail {
+				t.Errorf("buildProblem().Detail = %v, want %v", problem.Detail, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestHttpWriter_RegisterMapping_Concurrent(t *testing.T) {
+	errQuotaExceeded := errors.New("quota exceeded")
+	hw := New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			hw.RegisterMapping(errQuotaExceeded, func(err error) Problem {
+				return NewTooManyRequestsProblem("Quota exceeded")
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			hw.WriteError(context.Background(), httptest.NewRecorder(), errQuotaExceeded, zap.NewNop())
+		}()
+	}
+	wg.Wait()
+
+	problem := hw.buildProblem(context.Background(), errQuotaExceeded)
+	if problem.Status != http.StatusTooManyRequests {
+		t.Errorf("buildProblem().Status = %v, want %v", problem.Status, http.StatusTooManyRequests)
+	}
+}
+
+func TestHttpWriter_WriteError_HeaderAlreadyWritten(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := NewResponseWriter(recorder)
+	logger, _ := zap.NewDevelopment()
+
+	w.WriteHeader(http.StatusAccepted)
+	_, _ = w.Write([]byte("partial"))
+
+	New().WriteError(context.Background(), w, handlerutil.ErrForbidden, logger)
+
+	if recorder.Code != http.StatusAccepted {
+		t.Errorf("WriteError() status = %v, want %v", recorder.Code, http.StatusAccepted)
+	}
+
+	if recorder.Body.String() != "partial" {
+		t.Errorf("WriteError() body = %q, want %q", recorder.Body.String(), "partial")
+	}
+
+	if got := recorder.Header().Get("Content-Type"); got == "application/problem+json" {
+		t.Errorf("WriteError() should not set Content-Type after the response started")
+	}
+}
+
+func TestHttpWriter_WriteError_HeaderNotWritten(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := NewResponseWriter(recorder)
+	logger, _ := zap.NewDevelopment()
+
+	New().WriteError(context.Background(), w, handlerutil.ErrForbidden, logger)
+
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("WriteError() status = %v, want %v", recorder.Code, http.StatusForbidden)
+	}
+
+	if !w.HeaderWritten() {
+		t.Errorf("HeaderWritten() = false, want true")
+	}
+}
+
+func TestInterceptMiddleware(t *testing.T) {
+	tests := []struct {
+		name            string
+		handler         http.HandlerFunc
+		wantStatus      int
+		wantContentType string
+		wantDetail      string
+		wantBody        string
+	}{
+		{
+			name: "Should rewrite http.Error response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "missing token", http.StatusUnauthorized)
+			},
+			wantStatus:      http.StatusUnauthorized,
+			wantContentType: "application/problem+json",
+			wantDetail:      "missing token",
+		},
+		{
+			name: "Should rewrite bare WriteHeader",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			wantStatus:      http.StatusServiceUnavailable,
+			wantContentType: "application/problem+json",
+			wantDetail:      "Service Unavailable",
+		},
+		{
+			name: "Should pass through problem responses",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				logger, _ := zap.NewDevelopment()
+				New().WriteError(r.Context(), w, handlerutil.ErrForbidden, logger)
+			},
+			wantStatus:      http.StatusForbidden,
+			wantContentType: "application/problem+json",
+			wantDetail:      "Make sure you have the right permissions",
+		},
+		{
+			name: "Should pass through successful responses",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("ok"))
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "ok",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, _ := zap.NewDevelopment()
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+
+			InterceptMiddleware(tt.handler, logger)(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", w.Code, tt.wantStatus)
+			}
+
+			if tt.wantContentType == "" {
+				if w.Body.String() != tt.wantBody {
+					t.Errorf("body = %q, want %q", w.Body.String(), tt.wantBody)
+				}
+				return
+			}
+
+			if got := w.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("Content-Type = %v, want %v", got, tt.wantContentType)
+			}
+
+			var problem Problem
+			if err := json.NewDecoder(w.Body).Decode(&problem); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			if problem.Detail != tt.wantDetail {
+				t.Errorf("detail = %v, want %v", problem.Detail, tt.wantDetail)
+			}
+		})
+	}
+}
+
+func TestHttpWriter_WriteError_Violations(t *testing.T) {
+	err := handlerutil.NewValidationErrorWithErrors("validation failed", []string{"email must be a valid email address"})
+	err.Violations = []handlerutil.FieldViolation{
+		{Pointer: "/items/2/email", Message: "email must be a valid email address"},
+	}
+
+	w := httptest.NewRecorder()
+	New().WriteError(context.Background(), w, err, zap.NewNop())
+
+	var body struct {
+		Violations []struct {
+			Detail string `json:"detail"`
+			Source struct {
+				Pointer string `json:"pointer"`
+			} `json:"source"`
+		} `json:"violations"`
+	}
+	if decodeErr := json.NewDecoder(w.Body).Decode(&body); decodeErr != nil {
+		t.Fatalf("failed to decode response: %v", decodeErr)
+	}
+
+	if len(body.Violations) != 1 {
+		t.Fatalf("expected 1 violation, got %d", len(body.Violations))
+	}
+	if body.Violations[0].Source.Pointer != "/items/2/email" {
+		t.Errorf("expected pointer /items/2/email, got %q", body.Violations[0].Source.Pointer)
+	}
+	if body.Violations[0].Detail != "email must be a valid email address" {
+		t.Errorf("unexpected violation detail %q", body.Violations[0].Detail)
+	}
+}
+
+func TestHttpWriter_buildProblem_UniqueViolation(t *testing.T) {
+	pgErr := &pgconn.PgError{
+		Code:           databaseutil.PGErrUniqueViolation,
+		ConstraintName: "organizations_org_id_slug_key",
+		TableName:      "organizations",
+		Detail:         "Key (org_id, slug)=(1, acme) already exists.",
+	}
+	err := databaseutil.WrapDBError(pgErr, zap.NewNop(), "create organization")
+
+	var uniqueErr databaseutil.UniqueViolationError
+	if !errors.As(err, &uniqueErr) {
+		t.Fatalf("WrapDBError() = %T, want UniqueViolationError", err)
+	}
+	if !reflect.DeepEqual(uniqueErr.Columns, []string{"org_id", "slug"}) || !reflect.DeepEqual(uniqueErr.Values, []string{"1", "acme"}) {
+		t.Errorf("UniqueViolationError = %+v, want columns org_id, slug and values 1, acme", uniqueErr)
+	}
+	if !errors.Is(err, databaseutil.ErrUniqueViolation) {
+		t.Errorf("UniqueViolationError should match ErrUniqueViolation")
+	}
+
+	problem := New().buildProblem(context.Background(), err)
+	if problem.Status != http.StatusConflict {
+		t.Errorf("buildProblem().Status = %d, want %d", problem.Status, http.StatusConflict)
+	}
+	if len(problem.Violations) != 2 || problem.Violations[0].Source.Pointer != "/org_id" || problem.Violations[1].Source.Pointer != "/slug" {
+		t.Errorf("buildProblem().Violations = %+v, want pointers /org_id and /slug", problem.Violations)
+	}
+}
+
+func TestHttpWriter_WriteError_ViolationSources(t *testing.T) {
+	err := handlerutil.NewValidationErrorWithErrors("validation failed", []string{"'limit' must be an integer", "X-Tenant-ID is required"})
+	err.Violations = []handlerutil.FieldViolation{
+		{Parameter: "limit", Message: "'limit' must be an integer"},
+		{Header: "X-Tenant-ID", Message: "X-Tenant-ID is required"},
+	}
+
+	w := httptest.NewRecorder()
+	New().WriteError(context.Background(), w, err, zap.NewNop())
+
+	var body struct {
+		Violations []struct {
+			Source map[string]string `json:"source"`
+		} `json:"violations"`
+	}
+	if decodeErr := json.NewDecoder(w.Body).Decode(&body); decodeErr != nil {
+		t.Fatalf("failed to decode response: %v", decodeErr)
+	}
+
+	want := []map[string]string{{"parameter": "limit"}, {"header": "X-Tenant-ID"}}
+	if len(body.Violations) != len(want) {
+		t.Fatalf("expected %d violations, got %d", len(want), len(body.Violations))
+	}
+	for i, v := range body.Violations {
+		if !reflect.DeepEqual(v.Source, want[i]) {
+			t.Errorf("violation %d source = %v, want %v", i, v.Source, want[i])
+		}
+	}
+}
+
+func TestHttpWriter_WriteError_RetryAfter(t *testing.T) {
+	w := httptest.NewRecorder()
+	New().WriteError(context.Background(), w, handlerutil.NewTooManyRequestsError(1500*time.Millisecond, ""), zap.NewNop())
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("expected status %d, got %d", http.StatusTooManyRequests, w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("expected Retry-After 2, got %q", got)
+	}
+}
+
+func TestHttpWriter_WriteError_Expected(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantLevel zapcore.Level
+	}{
+		{
+			name:      "Should log unmarked errors at warn",
+			err:       handlerutil.ErrNotFound,
+			wantLevel: zapcore.WarnLevel,
+		},
+		{
+			name:      "Should log expected errors at debug",
+			err:       Expected(handlerutil.ErrNotFound),
+			wantLevel: zapcore.DebugLevel,
+		},
+		{
+			name:      "Should log wrapped expected errors at debug",
+			err:       fmt.Errorf("report not ready: %w", Expected(handlerutil.ErrNotFound)),
+			wantLevel: zapcore.DebugLevel,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			core, logs := observer.New(zapcore.DebugLevel)
+			w := httptest.NewRecorder()
+
+			New().WriteError(context.Background(), w, tt.err, zap.New(core))
+
+			if w.Code != http.StatusNotFound {
+				t.Errorf("WriteError() status = %v, want %v", w.Code, http.StatusNotFound)
+			}
+
+			entries := logs.All()
+			if len(entries) != 1 {
+				t.Fatalf("WriteError() logged %d entries, want 1", len(entries))
+			}
+			if entries[0].Level != tt.wantLevel {
+				t.Errorf("WriteError() log level = %v, want %v", entries[0].Level, tt.wantLevel)
+			}
+		})
+	}
+}
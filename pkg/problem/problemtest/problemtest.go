@@ -0,0 +1,156 @@
+package problemtest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/NYCU-SDC/summer/pkg/problem"
+)
+
+// Option adds an expectation to AssertProblem
+type Option func(*expectation)
+
+type expectation struct {
+	typ        *string
+	detail     *string
+	violations []problem.Violation
+	extensions map[string]any
+}
+
+// WithType expects the type member to be uri
+func WithType(uri string) Option {
+	return func(e *expectation) {
+		e.typ = &uri
+	}
+}
+
+// WithDetail expects the detail member to be detail
+func WithDetail(detail string) Option {
+	return func(e *expectation) {
+		e.detail = &detail
+	}
+}
+
+// WithViolation expects violation to be one of the violations, others are allowed
+func WithViolation(violation problem.Violation) Option {
+	return func(e *expectation) {
+		e.violations = append(e.violations, violation)
+	}
+}
+
+// WithExtension expects the extension member name to be value, compared after a JSON round trip
+// so e.g. an int matches the float64 it is decoded as
+func WithExtension(name string, value any) Option {
+	return func(e *expectation) {
+		if e.extensions == nil {
+			e.extensions = map[string]any{}
+		}
+		e.extensions[name] = value
+	}
+}
+
+// AssertProblem checks that resp is a problem response with wantStatus and wantTitle, plus the
+// expectations of opts, and returns the decoded problem for further checks. Members not covered by
+// an expectation, such as extensions, are ignored. With an httptest.ResponseRecorder, pass its Result.
+//
+//	p := problemtest.AssertProblem(t, w.Result(), http.StatusNotFound, "Not Found",
+//		problemtest.WithDetail("user 42 does not exist"))
+func AssertProblem(t testing.TB, resp *http.Response, wantStatus int, wantTitle string, opts ...Option) problem.Problem {
+	t.Helper()
+
+	var want expectation
+	for _, opt := range opts {
+		opt(&want)
+	}
+
+	if resp.StatusCode != wantStatus {
+		t.Errorf("status code = %d, want %d", resp.StatusCode, wantStatus)
+	}
+	if contentType := resp.Header.Get("Content-Type"); !strings.HasPrefix(contentType, "application/problem+json") {
+		t.Errorf("Content-Type = %q, want application/problem+json", contentType)
+	}
+
+	got, ok := decode(t, resp.Body)
+	if !ok {
+		return got
+	}
+
+	if got.Status != resp.StatusCode {
+		t.Errorf("status member = %d, want the response status %d", got.Status, resp.StatusCode)
+	}
+	if got.Title != wantTitle {
+		t.Errorf("title = %q, want %q", got.Title, wantTitle)
+	}
+	if want.typ != nil && got.Type != *want.typ {
+		t.Errorf("type = %q, want %q", got.Type, *want.typ)
+	}
+	if want.detail != nil && got.Detail != *want.detail {
+		t.Errorf("detail = %q, want %q", got.Detail, *want.detail)
+	}
+	for _, violation := range want.violations {
+		if !slices.Contains(got.Violations, violation) {
+			t.Errorf("violations = %+v, want them to contain %+v", got.Violations, violation)
+		}
+	}
+	for name, value := range want.extensions {
+		gotValue, ok := got.Extensions[name]
+		if !ok {
+			t.Errorf("extension %q is missing, got %v", name, got.Extensions)
+			continue
+		}
+		if wantValue := roundTrip(t, value); !reflect.DeepEqual(gotValue, wantValue) {
+			t.Errorf("extension %q = %v, want %v", name, gotValue, wantValue)
+		}
+	}
+
+	return got
+}
+
+// Decode reads a problem from body. Extension members are kept in Extensions and the members of
+// the legacy format are accepted, so it decodes whatever HttpWriter writes.
+func Decode(t testing.TB, body io.Reader) problem.Problem {
+	t.Helper()
+
+	p, _ := decode(t, body)
+	return p
+}
+
+func decode(t testing.TB, body io.Reader) (problem.Problem, bool) {
+	t.Helper()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Errorf("failed to read problem body: %v", err)
+		return problem.Problem{}, false
+	}
+
+	var p problem.Problem
+	err = json.NewDecoder(bytes.NewReader(data)).Decode(&p)
+	if err != nil {
+		t.Errorf("failed to decode problem %q: %v", data, err)
+		return problem.Problem{}, false
+	}
+	return p, true
+}
+
+func roundTrip(t testing.TB, value any) any {
+	t.Helper()
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("failed to marshal expected extension value %v: %v", value, err)
+	}
+
+	var decoded any
+	err = json.Unmarshal(data, &decoded)
+	if err != nil {
+		t.Fatalf("failed to unmarshal expected extension value %s: %v", data, err)
+	}
+	return decoded
+}
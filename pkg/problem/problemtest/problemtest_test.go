@@ -0,0 +1,99 @@
+package problemtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NYCU-SDC/summer/pkg/handler"
+	"github.com/NYCU-SDC/summer/pkg/problem"
+	"go.uber.org/zap"
+)
+
+// recordingTB records the failures instead of failing the test
+type recordingTB struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recordingTB) Fatalf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func writeProblem(err error) *http.Response {
+	hw := problem.NewWithMapping(func(err error) problem.Problem {
+		if errors.Is(err, handlerutil.ErrForbidden) {
+			return problem.NewForbiddenProblem("Not enough credit").WithExtension("balance", 30)
+		}
+		return problem.Problem{}
+	})
+
+	w := httptest.NewRecorder()
+	hw.WriteError(context.Background(), w, err, zap.NewNop())
+	return w.Result()
+}
+
+func TestAssertProblem(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		opts         []Option
+		wantFailures int
+	}{
+		{
+			name: "Should pass on a matching problem",
+			err:  handlerutil.ErrForbidden,
+			opts: []Option{
+				WithDetail("Not enough credit"),
+				WithExtension("balance", 30),
+			},
+		},
+		{
+			name: "Should match violations",
+			err: handlerutil.ValidationError{
+				Message:    "validation failed",
+				Errors:     []string{"email is required"},
+				Violations: []handlerutil.FieldViolation{{Pointer: "/email", Message: "email is required"}},
+			},
+			opts: []Option{
+				WithViolation(problem.Violation{Detail: "email is required", Source: problem.ViolationSource{Pointer: "/email"}}),
+			},
+		},
+		{
+			name: "Should report every mismatch",
+			err:  handlerutil.ErrForbidden,
+			opts: []Option{
+				WithDetail("Out of credit"),
+				WithExtension("balance", 0),
+				WithExtension("accounts", []string{"/account/12345"}),
+			},
+			wantFailures: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := writeProblem(tt.err)
+			recorder := &recordingTB{TB: t}
+
+			wantStatus, wantTitle := resp.StatusCode, http.StatusText(resp.StatusCode)
+			if wantStatus == http.StatusBadRequest {
+				wantTitle = "Validation Problem"
+			}
+			AssertProblem(recorder, resp, wantStatus, wantTitle, tt.opts...)
+
+			if len(recorder.failures) != tt.wantFailures {
+				t.Errorf("AssertProblem() failures = %q, want %d", recorder.failures, tt.wantFailures)
+			}
+		})
+	}
+}
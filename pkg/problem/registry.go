@@ -0,0 +1,53 @@
+package problem
+
+import (
+	"errors"
+	"sync"
+)
+
+// mapping pairs a sentinel error with the factory building its problem
+type mapping struct {
+	target  error
+	factory func(error) Problem
+}
+
+// registry is an ordered list of mappings, the first registered match wins
+type registry struct {
+	mu       sync.RWMutex
+	mappings []mapping
+}
+
+func (r *registry) register(target error, factory func(error) Problem) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.mappings = append(r.mappings, mapping{target: target, factory: factory})
+}
+
+func (r *registry) lookup(err error) Problem {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, m := range r.mappings {
+		if errors.Is(err, m.target) {
+			return m.factory(err)
+		}
+	}
+	return Problem{}
+}
+
+var defaultRegistry = &registry{}
+
+// RegisterMapping declares the problem for a sentinel error on every HttpWriter, it is meant
+// to be called once at startup. Errors matching target with errors.Is are converted with factory
+// before the built-in defaults are checked.
+func RegisterMapping(target error, factory func(error) Problem) {
+	defaultRegistry.register(target, factory)
+}
+
+// RegisterMapping declares the problem for a sentinel error on this writer only,
+// writer mappings take precedence over the ones registered at package level. The writer
+// must be created with New or NewWithMapping.
+func (h *HttpWriter) RegisterMapping(target error, factory func(error) Problem) {
+	h.registry.register(target, factory)
+}
@@ -0,0 +1,152 @@
+package problem
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"regexp"
+)
+
+// AboutBlank is the problem type meaning that the problem has no semantics beyond the HTTP
+// status code, it is assumed when the type member is absent (RFC 9457 section 4.2.1)
+const AboutBlank = "about:blank"
+
+// extensionNamePattern is the naming convention of RFC 9457 section 3.2 for extension members,
+// which keeps them usable as identifiers in clients decoding them into structures
+var extensionNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{2,}$`)
+
+var standardMembers = map[string]bool{
+	"type":       true,
+	"status":     true,
+	"title":      true,
+	"detail":     true,
+	"instance":   true,
+	"errors":     true,
+	"violations": true,
+}
+
+// rfc9457Problem is the wire format of Problem, members without a value are left out
+type rfc9457Problem struct {
+	Type       string      `json:"type"`
+	Status     int         `json:"status,omitempty"`
+	Title      string      `json:"title,omitempty"`
+	Detail     string      `json:"detail,omitempty"`
+	Instance   string      `json:"instance,omitempty"`
+	Errors     []string    `json:"errors,omitempty"`
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// legacyProblem is Problem without its JSON methods, it keeps the members that HttpWriter wrote
+// before RFC 9457 support, such as an empty detail, for HttpWriter.LegacyFormat
+type legacyProblem Problem
+
+// normalized applies the about:blank rules: an empty type is about:blank, and the title of an
+// about:blank problem is the status text
+func (p Problem) normalized() Problem {
+	if p.Type == "" {
+		p.Type = AboutBlank
+	}
+	if p.Type == AboutBlank {
+		if text := http.StatusText(p.Status); text != "" {
+			p.Title = text
+		}
+	}
+	return p
+}
+
+// MarshalJSON writes p as RFC 9457 problem details, with Extensions as top-level members. Extension
+// names that do not follow the RFC naming convention or clash with a standard member are dropped.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	p = p.normalized()
+
+	data, err := json.Marshal(rfc9457Problem{
+		Type:       p.Type,
+		Status:     p.Status,
+		Title:      p.Title,
+		Detail:     p.Detail,
+		Instance:   p.Instance,
+		Errors:     p.Errors,
+		Violations: p.Violations,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	extensions := make(map[string]any, len(p.Extensions))
+	for name, value := range p.Extensions {
+		if IsValidExtensionName(name) {
+			extensions[name] = value
+		}
+	}
+	if len(extensions) == 0 {
+		return data, nil
+	}
+
+	extensionData, err := json.Marshal(extensions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Splice the extension members into the object: {"type":...} + {"ext":...} => {"type":...,"ext":...}
+	var buf bytes.Buffer
+	buf.Grow(len(data) + len(extensionData))
+	buf.Write(data[:len(data)-1])
+	buf.WriteByte(',')
+	buf.Write(extensionData[1:])
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON reads RFC 9457 problem details, the members it does not know are kept in
+// Extensions and an absent type is read as about:blank
+func (p *Problem) UnmarshalJSON(data []byte) error {
+	var standard legacyProblem
+	err := json.Unmarshal(data, &standard)
+	if err != nil {
+		return err
+	}
+
+	var members map[string]json.RawMessage
+	err = json.Unmarshal(data, &members)
+	if err != nil {
+		return err
+	}
+
+	*p = Problem(standard)
+	p.Extensions = nil
+	for name, value := range members {
+		if standardMembers[name] {
+			continue
+		}
+		var decoded any
+		err = json.Unmarshal(value, &decoded)
+		if err != nil {
+			return err
+		}
+		if p.Extensions == nil {
+			p.Extensions = make(map[string]any)
+		}
+		p.Extensions[name] = decoded
+	}
+
+	if p.Type == "" {
+		p.Type = AboutBlank
+	}
+	return nil
+}
+
+// IsValidExtensionName reports whether name can be used as an extension member: it follows the
+// naming convention of RFC 9457 and is not one of the members defined by Problem
+func IsValidExtensionName(name string) bool {
+	return !standardMembers[name] && extensionNamePattern.MatchString(name)
+}
+
+// WithExtension returns a copy of p with the extension member name set to value
+func (p Problem) WithExtension(name string, value any) Problem {
+	extensions := make(map[string]any, len(p.Extensions)+1)
+	for k, v := range p.Extensions {
+		extensions[k] = v
+	}
+	extensions[name] = value
+	p.Extensions = extensions
+	return p
+}
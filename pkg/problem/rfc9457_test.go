@@ -0,0 +1,182 @@
+package problem
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestProblem_MarshalJSON_RFC9457(t *testing.T) {
+	tests := []struct {
+		name    string
+		problem Problem
+		want    map[string]any
+	}{
+		{
+			name:    "Should default an empty type to about:blank with the status text as title",
+			problem: Problem{Status: http.StatusNotFound, Detail: "user 42 does not exist"},
+			want: map[string]any{
+				"type":   "about:blank",
+				"status": float64(404),
+				"title":  "Not Found",
+				"detail": "user 42 does not exist",
+			},
+		},
+		{
+			name:    "Should use the status text as title of about:blank problems",
+			problem: Problem{Type: AboutBlank, Title: "Missing user", Status: http.StatusNotFound},
+			want: map[string]any{
+				"type":   "about:blank",
+				"status": float64(404),
+				"title":  "Not Found",
+			},
+		},
+		{
+			name:    "Should keep the title of typed problems",
+			problem: Problem{Type: "https://example.com/problems/out-of-credit", Title: "Out of credit", Status: http.StatusForbidden},
+			want: map[string]any{
+				"type":   "https://example.com/problems/out-of-credit",
+				"status": float64(403),
+				"title":  "Out of credit",
+			},
+		},
+		{
+			name: "Should write extensions as top-level members",
+			problem: NewProblem(http.StatusForbidden, "Not enough credit").
+				WithExtension("balance", 30).
+				WithExtension("accounts", []string{"/account/12345"}),
+			want: map[string]any{
+				"type":     "https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/403",
+				"status":   float64(403),
+				"title":    "Forbidden",
+				"detail":   "Not enough credit",
+				"balance":  float64(30),
+				"accounts": []any{"/account/12345"},
+			},
+		},
+		{
+			name: "Should drop extensions breaking the naming convention or shadowing a member",
+			problem: Problem{Status: http.StatusBadRequest, Extensions: map[string]any{
+				"ok":         "too short",
+				"1st":        "starts with a digit",
+				"trace-id":   "contains a dash",
+				"status":     500,
+				"request_id": "kept",
+			}},
+			want: map[string]any{
+				"type":       "about:blank",
+				"status":     float64(400),
+				"title":      "Bad Request",
+				"request_id": "kept",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.problem)
+			if err != nil {
+				t.Fatalf("json.Marshal() unexpected error: %v", err)
+			}
+
+			var got map[string]any
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("json.Marshal() wrote invalid JSON %s: %v", data, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("json.Marshal() = %s, want %v", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestProblem_UnmarshalJSON_RFC9457(t *testing.T) {
+	var problem Problem
+	err := json.Unmarshal([]byte(`{"status":403,"title":"Forbidden","balance":30,"accounts":["/account/12345"]}`), &problem)
+	if err != nil {
+		t.Fatalf("json.Unmarshal() unexpected error: %v", err)
+	}
+
+	if problem.Type != AboutBlank {
+		t.Errorf("Type = %q, want %q when the member is absent", problem.Type, AboutBlank)
+	}
+	if problem.Status != http.StatusForbidden || problem.Title != "Forbidden" {
+		t.Errorf("Status, Title = %d, %q, want 403, Forbidden", problem.Status, problem.Title)
+	}
+	wantExtensions := map[string]any{"balance": float64(30), "accounts": []any{"/account/12345"}}
+	if !reflect.DeepEqual(problem.Extensions, wantExtensions) {
+		t.Errorf("Extensions = %v, want %v", problem.Extensions, wantExtensions)
+	}
+
+	data, err := json.Marshal(problem)
+	if err != nil {
+		t.Fatalf("json.Marshal() unexpected error: %v", err)
+	}
+	var roundTrip Problem
+	if err := json.Unmarshal(data, &roundTrip); err != nil {
+		t.Fatalf("json.Unmarshal() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(roundTrip, problem) {
+		t.Errorf("round trip = %+v, want %+v", roundTrip, problem)
+	}
+}
+
+func TestHttpWriter_WriteError_RFC9457(t *testing.T) {
+	errOutOfCredit := errors.New("out of credit")
+
+	tests := []struct {
+		name        string
+		legacy      bool
+		wantType    any
+		wantDetail  bool
+		wantBalance bool
+	}{
+		{name: "Should write RFC 9457 problems by default", wantType: "about:blank", wantBalance: true},
+		{name: "Should keep the previous members in legacy format", legacy: true, wantType: "", wantDetail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hw := NewWithMapping(func(err error) Problem {
+				if errors.Is(err, errOutOfCredit) {
+					return Problem{Status: http.StatusForbidden}.WithExtension("balance", 30)
+				}
+				return Problem{}
+			})
+			hw.LegacyFormat = tt.legacy
+
+			w := httptest.NewRecorder()
+			hw.WriteError(context.Background(), w, errOutOfCredit, zap.NewNop())
+
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/problem+json" {
+				t.Errorf("Content-Type = %q, want application/problem+json", contentType)
+			}
+			if w.Code != http.StatusForbidden {
+				t.Errorf("status code = %d, want %d", w.Code, http.StatusForbidden)
+			}
+
+			var body map[string]any
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if body["status"] != float64(w.Code) {
+				t.Errorf("status member = %v, want the HTTP status %d", body["status"], w.Code)
+			}
+			if body["type"] != tt.wantType {
+				t.Errorf("type member = %v, want %v", body["type"], tt.wantType)
+			}
+			if _, ok := body["detail"]; ok != tt.wantDetail {
+				t.Errorf("detail member present = %v, want %v", ok, tt.wantDetail)
+			}
+			if _, ok := body["balance"]; ok != tt.wantBalance {
+				t.Errorf("balance member present = %v, want %v", ok, tt.wantBalance)
+			}
+		})
+	}
+}
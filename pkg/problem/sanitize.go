@@ -0,0 +1,74 @@
+package problem
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/NYCU-SDC/summer/pkg/handler"
+)
+
+// sqlFragmentPattern matches the parts of database error messages that reveal the schema or the
+// query: statements with their table, driver prefixes and the wording of PostgreSQL and SQL Server
+// errors. Each alternative needs SQL syntax around the keywords, so that ordinary sentences such
+// as "select a value from the list" are kept.
+var sqlFragmentPattern = regexp.MustCompile(`(?i)(` +
+	`\bselect\s+(?:\*|distinct\s+)?[\w."$]+(?:\s*,\s*[\w."$*]+)*\s+from\s+[\w."]+` +
+	`|\binsert\s+into\s+[\w."]+\s*(?:\(|values\b|select\b)` +
+	`|\bupdate\s+[\w."]+\s+set\s+[\w."]+\s*=` +
+	`|\bdelete\s+from\s+[\w."]+\s*(?:where\b|returning\b|;|$)` +
+	`|\bsqlstate\b` +
+	`|\bviolates\s+(?:foreign\s+key|unique|check|not-null|exclusion)\s+constraint\b` +
+	`|\b(?:relation|column|table|constraint|index)\s+"[^"]+"` +
+	`|\bsyntax\s+error\s+at\s+or\s+near\b` +
+	`|\b(?:pq|pgx|pgconn|mssql):\s` +
+	`)`)
+
+// DefaultSanitizer replaces the detail and drops the error entries that contain SQL fragments,
+// so that database internals leaking through err.Error() are not written to the client. The
+// detail of a NotFoundError without a Message names the table and key, it is replaced as well.
+func DefaultSanitizer(err error, problem Problem) Problem {
+	var notFoundError handlerutil.NotFoundError
+	if errors.As(err, &notFoundError) && notFoundError.Message == "" {
+		problem.Detail = sanitizedDetail(http.StatusNotFound)
+	}
+
+	if sqlFragmentPattern.MatchString(problem.Detail) {
+		problem.Detail = sanitizedDetail(problem.Status)
+	}
+
+	if len(problem.Errors) > 0 {
+		errs := make([]string, 0, len(problem.Errors))
+		for _, e := range problem.Errors {
+			if !sqlFragmentPattern.MatchString(e) {
+				errs = append(errs, e)
+			}
+		}
+		problem.Errors = errs
+	}
+
+	if len(problem.Violations) > 0 {
+		violations := make([]Violation, 0, len(problem.Violations))
+		for _, v := range problem.Violations {
+			if !sqlFragmentPattern.MatchString(v.Detail) {
+				violations = append(violations, v)
+			}
+		}
+		problem.Violations = violations
+	}
+
+	return problem
+}
+
+func sanitizedDetail(status int) string {
+	switch status {
+	case http.StatusNotFound:
+		return "Resource not found"
+	case http.StatusBadRequest:
+		return "Validation error"
+	case 0:
+		return "Internal server error"
+	default:
+		return http.StatusText(status)
+	}
+}
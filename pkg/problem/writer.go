@@ -0,0 +1,65 @@
+package problem
+
+import (
+	"net/http"
+)
+
+// ResponseWriter wraps a http.ResponseWriter and tracks whether the response has been started,
+// so that WriteError does not try to write a second status line after the handler already did
+type ResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+// NewResponseWriter wraps w, it returns w itself when it is already a *ResponseWriter
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	if rw, ok := w.(*ResponseWriter); ok {
+		return rw
+	}
+	return &ResponseWriter{ResponseWriter: w}
+}
+
+func (w *ResponseWriter) WriteHeader(code int) {
+	// Informational responses do not start the final response
+	if code >= 200 {
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *ResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// HeaderWritten reports whether the status line and headers have been sent
+func (w *ResponseWriter) HeaderWritten() bool {
+	return w.wroteHeader
+}
+
+func (w *ResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.wroteHeader = true
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *ResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// headerWritten reports whether w, or any writer it wraps, has already sent the headers
+func headerWritten(w http.ResponseWriter) bool {
+	for w != nil {
+		if reporter, ok := w.(interface{ HeaderWritten() bool }); ok {
+			return reporter.HeaderWritten()
+		}
+		unwrapper, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		w = unwrapper.Unwrap()
+	}
+	return false
+}
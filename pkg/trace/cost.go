@@ -0,0 +1,83 @@
+package traceutil
+
+import (
+	"context"
+	"sync"
+
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Cost keys recorded by the summer packages, services can add their own
+const (
+	CostDBQueries     = "db.queries"
+	CostDBRowsRead    = "db.rows_read"
+	CostDBRowsWritten = "db.rows_written"
+	CostCacheHits     = "cache.hits"
+	CostCacheMisses   = "cache.misses"
+	CostBytesSent     = "bytes.sent"
+)
+
+// CostCounter accumulates the resources used by a request, it is safe for concurrent use
+type CostCounter struct {
+	mu     sync.Mutex
+	values map[string]int64
+}
+
+// WithCostCounter returns a context carrying a new counter, TraceMiddleware does this for every request
+func WithCostCounter(ctx context.Context) (context.Context, *CostCounter) {
+	counter := &CostCounter{}
+	return logutil.WithCostCounter(ctx, counter), counter
+}
+
+// CostCounterFromContext returns the counter of ctx, or nil when there is none
+func CostCounterFromContext(ctx context.Context) *CostCounter {
+	counter, _ := logutil.CostCounterFromContext(ctx).(*CostCounter)
+	return counter
+}
+
+// AddCost adds n to key on the counter of ctx, it does nothing without a counter
+func AddCost(ctx context.Context, key string, n int64) {
+	logutil.AddCost(ctx, key, n)
+}
+
+func (c *CostCounter) Add(key string, n int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.values == nil {
+		c.values = map[string]int64{}
+	}
+	c.values[key] += n
+}
+
+// Values returns a copy of the accumulated costs
+func (c *CostCounter) Values() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	values := make(map[string]int64, len(c.values))
+	for k, v := range c.values {
+		values[k] = v
+	}
+	return values
+}
+
+// RecordOnSpan sets every accumulated cost as an attribute of span
+func (c *CostCounter) RecordOnSpan(span trace.Span) {
+	if !span.IsRecording() {
+		return
+	}
+
+	attrs := getAttributes()
+	defer putAttributes(attrs)
+
+	c.mu.Lock()
+	for k, v := range c.values {
+		*attrs = append(*attrs, attribute.Int64(k, v))
+	}
+	c.mu.Unlock()
+
+	span.SetAttributes(*attrs...)
+}
@@ -0,0 +1,37 @@
+package traceutil
+
+import (
+	"os"
+	"strconv"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var disabled atomic.Bool
+
+func init() {
+	// OTEL_SDK_DISABLED is the standard OpenTelemetry switch, see the SDK environment variables spec
+	if off, _ := strconv.ParseBool(os.Getenv("OTEL_SDK_DISABLED")); off {
+		Disable()
+	}
+}
+
+// Disable turns tracing off for the whole process, for small tools and tests that should neither
+// set OpenTelemetry up nor pay for it. It installs no-op global tracer provider and propagator, so
+// the spans started with otel.Tracer anywhere, e.g. by logutil.WithSpan or handlerutil.Bind, cost
+// next to nothing, and TraceMiddleware stops extracting upstream context, starting spans and
+// counting costs, which also makes databaseutil.CostTracer return right away. It is also applied
+// at startup when the OTEL_SDK_DISABLED environment variable is true, and cannot be undone.
+func Disable() {
+	disabled.Store(true)
+	otel.SetTracerProvider(noop.NewTracerProvider())
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+}
+
+// Disabled reports whether Disable was called
+func Disabled() bool {
+	return disabled.Load()
+}
@@ -0,0 +1,183 @@
+package traceutil
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+)
+
+// ExporterStats is a snapshot of the counters kept by MonitoredExporter
+type ExporterStats struct {
+	Exported     int64
+	Failed       int64
+	FellBack     int64
+	LastError    error
+	LastFailedAt time.Time
+}
+
+// MonitoredExporter wraps a span exporter, usually the OTLP one, and keeps track of failed exports.
+// When a Fallback exporter is set, spans that could not be exported are written to it instead,
+// so traces are still available locally while the collector is down.
+type MonitoredExporter struct {
+	sdktrace.SpanExporter
+	Fallback sdktrace.SpanExporter
+
+	logger *zap.Logger
+
+	exported atomic.Int64
+	failed   atomic.Int64
+	fellBack atomic.Int64
+
+	mu           sync.Mutex
+	lastError    error
+	lastFailedAt time.Time
+
+	failedCounter metric.Int64Counter
+}
+
+func NewMonitoredExporter(exporter sdktrace.SpanExporter, fallback sdktrace.SpanExporter, logger *zap.Logger) *MonitoredExporter {
+	failedCounter, err := otel.Meter("internal/trace").Int64Counter(
+		"trace.exporter.failed_spans",
+		metric.WithDescription("Number of spans the trace exporter failed to export"),
+	)
+	if err != nil {
+		logger.Warn("Failed to create exporter failure counter", zap.Error(err))
+	}
+
+	return &MonitoredExporter{
+		SpanExporter:  exporter,
+		Fallback:      fallback,
+		logger:        logger,
+		failedCounter: failedCounter,
+	}
+}
+
+func (e *MonitoredExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	if err == nil {
+		e.exported.Add(int64(len(spans)))
+		return nil
+	}
+
+	e.failed.Add(int64(len(spans)))
+	e.mu.Lock()
+	e.lastError = err
+	e.lastFailedAt = time.Now()
+	e.mu.Unlock()
+
+	if e.failedCounter != nil {
+		e.failedCounter.Add(ctx, int64(len(spans)), metric.WithAttributes(attribute.Bool("fallback", e.Fallback != nil)))
+	}
+
+	if e.Fallback == nil {
+		return err
+	}
+
+	fallbackErr := e.Fallback.ExportSpans(ctx, spans)
+	if fallbackErr != nil {
+		e.logger.Error("Failed to export spans to fallback exporter", zap.Error(fallbackErr), zap.NamedError("export_error", err))
+		return err
+	}
+
+	e.fellBack.Add(int64(len(spans)))
+	return nil
+}
+
+func (e *MonitoredExporter) Shutdown(ctx context.Context) error {
+	err := e.SpanExporter.Shutdown(ctx)
+	if e.Fallback != nil {
+		fallbackErr := e.Fallback.Shutdown(ctx)
+		if err == nil {
+			err = fallbackErr
+		}
+	}
+	return err
+}
+
+// Stats returns the current counters
+func (e *MonitoredExporter) Stats() ExporterStats {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return ExporterStats{
+		Exported:     e.exported.Load(),
+		Failed:       e.failed.Load(),
+		FellBack:     e.fellBack.Load(),
+		LastError:    e.lastError,
+		LastFailedAt: e.lastFailedAt,
+	}
+}
+
+// Monitor logs a warning every interval in which exports failed, until ctx is canceled
+func (e *MonitoredExporter) Monitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastFailed int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats := e.Stats()
+			if stats.Failed == lastFailed {
+				continue
+			}
+
+			e.logger.Warn("Trace exporter is failing to export spans",
+				zap.Int64("failed_since_last_check", stats.Failed-lastFailed),
+				zap.Int64("failed_total", stats.Failed),
+				zap.Int64("fell_back_total", stats.FellBack),
+				zap.Int64("exported_total", stats.Exported),
+				zap.Time("last_failed_at", stats.LastFailedAt),
+				zap.Error(stats.LastError),
+			)
+			lastFailed = stats.Failed
+		}
+	}
+}
+
+// LogSpanExporter writes finished spans as log entries, it is meant to be used as the
+// fallback of MonitoredExporter with a logger writing to a local file
+type LogSpanExporter struct {
+	logger *zap.Logger
+}
+
+func NewLogSpanExporter(logger *zap.Logger) *LogSpanExporter {
+	return &LogSpanExporter{logger: logger}
+}
+
+func (e *LogSpanExporter) ExportSpans(_ context.Context, spans []sdktrace.ReadOnlySpan) error {
+	for _, span := range spans {
+		fields := []zap.Field{
+			zap.String("trace_id", span.SpanContext().TraceID().String()),
+			zap.String("span_id", span.SpanContext().SpanID().String()),
+			zap.String("name", span.Name()),
+			zap.Time("start", span.StartTime()),
+			zap.Duration("duration", span.EndTime().Sub(span.StartTime())),
+			zap.String("status", span.Status().Code.String()),
+		}
+		if span.Parent().HasSpanID() {
+			fields = append(fields, zap.String("parent_span_id", span.Parent().SpanID().String()))
+		}
+		for _, attr := range span.Attributes() {
+			fields = append(fields, zap.String("attr."+string(attr.Key), attr.Value.Emit()))
+		}
+
+		e.logger.Info("Span", fields...)
+	}
+	return nil
+}
+
+func (e *LogSpanExporter) Shutdown(_ context.Context) error {
+	// Sync fails on stdout and stderr for most platforms, there is nothing to act on here
+	_ = e.logger.Sync()
+	return nil
+}
@@ -0,0 +1,40 @@
+package traceutil
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InjectTraceContext returns the trace context of ctx in a form that can be stored with an
+// outbox record or a queued event, to be passed to StartConsumerSpan when it is processed
+func InjectTraceContext(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// StartConsumerSpan starts the span processing an asynchronous event. The span starts a new trace,
+// since the producing request has usually finished long before, and links to the producer span
+// found in traceContext so both sides are connected in the tracing UI.
+func StartConsumerSpan(ctx context.Context, tracerName, spanName string, traceContext map[string]string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{
+		trace.WithNewRoot(),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+	}
+
+	producerCtx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier(traceContext))
+	producer := trace.SpanContextFromContext(producerCtx)
+	if producer.IsValid() {
+		opts = append(opts, trace.WithLinks(trace.Link{
+			SpanContext: producer,
+			Attributes:  []attribute.KeyValue{attribute.String("link.type", "producer")},
+		}))
+	}
+
+	return otel.Tracer(tracerName).Start(ctx, spanName, opts...)
+}
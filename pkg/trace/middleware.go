@@ -14,6 +14,7 @@ import (
 	"github.com/NYCU-SDC/summer/pkg/problem"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
@@ -21,8 +22,9 @@ import (
 
 type CustomResponseWriter struct {
 	http.ResponseWriter
-	StatusCode int
-	Body       *bytes.Buffer
+	StatusCode   int
+	Body         *bytes.Buffer
+	BytesWritten int64
 }
 
 func (w *CustomResponseWriter) WriteHeader(code int) {
@@ -34,7 +36,20 @@ func (w *CustomResponseWriter) Write(b []byte) (int, error) {
 	if w.Body != nil {
 		w.Body.Write(b)
 	}
-	return w.ResponseWriter.Write(b)
+	n, err := w.ResponseWriter.Write(b)
+	w.BytesWritten += int64(n)
+	return n, err
+}
+
+func (w *CustomResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *CustomResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
 
 func writeBodyHandlingError(w http.ResponseWriter, err error, logger *zap.Logger) {
@@ -73,30 +88,77 @@ func writeBodyHandlingError(w http.ResponseWriter, err error, logger *zap.Logger
 // Note: Enabling debug mode causes the entire request body to be read into memory. This
 // can lead to high memory consumption for large payloads, such as file uploads, and is a
 // known limitation. Use with caution in environments that handle large requests.
-func TraceMiddleware(next http.HandlerFunc, logger *zap.Logger, debug bool) http.HandlerFunc {
+//
+// The request logger, with the trace fields, is stored in the context, see logutil.FromContext.
+// Each request gets a CostCounter, the costs reported while handling it are recorded as span
+// attributes when it completes. Responses with a 5xx status set the span status to error, see
+// WithErrorSnapshot to also record what led to them.
+//
+// Performance budget: without debug, the middleware must add less than 2µs and 10 allocations
+// per request when tracing is disabled, see BenchmarkTraceMiddleware. The span attributes and log
+// fields are pooled, the log fields alone halve the bytes allocated with tracing disabled. The
+// response writer is not: handlers may keep it past their return, e.g. http.TimeoutHandler. After
+// Disable, no span is started and no upstream context is extracted at all.
+func TraceMiddleware(next http.HandlerFunc, logger *zap.Logger, debug bool, opts ...TraceOption) http.HandlerFunc {
 	name := "internal/middleware"
 	tracer := otel.Tracer(name)
 	propagator := otel.GetTextMapPropagator()
 
+	var options traceOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
-		upstream := trace.SpanFromContext(ctx).SpanContext()
+		ctx := r.Context()
+		span := trace.SpanFromContext(ctx)
+		var upstream trace.SpanContext
+		var costs *CostCounter
 
-		ctx, span := tracer.Start(ctx, r.Method+" "+r.URL.Path)
-		defer span.End()
+		tracing := !disabled.Load()
+		if tracing {
+			// Debug mode is only trusted from DebugMiddleware, never from client baggage
+			ctx = logutil.WithoutDebugBaggage(propagator.Extract(ctx, propagation.HeaderCarrier(r.Header)))
+			upstream = trace.SpanFromContext(ctx).SpanContext()
 
-		span.SetAttributes(
-			attribute.String("method", r.Method),
-			attribute.String("path", r.URL.Path),
-			attribute.String("query", r.URL.RawQuery),
-		)
-		span.AddEvent("HTTPRequestStarted")
+			ctx, span = tracer.Start(ctx, r.Method+" "+r.URL.Path)
+			defer span.End()
+
+			ctx, costs = WithCostCounter(ctx)
+
+			if options.traceResponse {
+				propagator.Inject(ctx, propagation.HeaderCarrier(w.Header()))
+			}
+		}
+
+		var logBuffer *logutil.EntryBuffer
+		if options.snapshot != nil {
+			logBuffer = logutil.NewEntryBuffer(options.snapshot.MaxLogEntries, options.snapshot.LogLevel)
+			ctx = logutil.WithEntryBuffer(ctx, logBuffer)
+		}
+
+		if span.IsRecording() {
+			attrs := getAttributes()
+			*attrs = append(*attrs,
+				attribute.String("method", r.Method),
+				attribute.String("path", r.URL.Path),
+				attribute.String("query", r.URL.RawQuery),
+			)
+			span.SetAttributes(*attrs...)
+			putAttributes(attrs)
+			span.AddEvent("HTTPRequestStarted")
+		}
 
 		reqLogger := logutil.WithContext(ctx, logger)
-		if upstream.HasTraceID() {
-			reqLogger.Debug("Upstream trace available", zap.String("trace_id", upstream.TraceID().String()))
-		} else {
-			reqLogger.Debug("No upstream trace available, creating a new one", zap.String("trace_id", span.SpanContext().TraceID().String()))
+		ctx = logutil.IntoContext(ctx, reqLogger)
+		if tracing {
+			if upstream.HasTraceID() {
+				if entry := reqLogger.Check(zap.DebugLevel, "Upstream trace available"); entry != nil {
+					entry.Write(zap.String("trace_id", upstream.TraceID().String()))
+				}
+			} else if entry := reqLogger.Check(zap.DebugLevel, "No upstream trace available, creating a new one"); entry != nil {
+				entry.Write(zap.String("trace_id", span.SpanContext().TraceID().String()))
+			}
 		}
 
 		var bodyBytes []byte
@@ -124,14 +186,27 @@ func TraceMiddleware(next http.HandlerFunc, logger *zap.Logger, debug bool) http
 		}
 		next(crw, r.WithContext(ctx))
 
+		if tracing {
+			costs.Add(CostBytesSent, crw.BytesWritten)
+			costs.RecordOnSpan(span)
+		}
+
 		status := crw.StatusCode
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+			if options.snapshot != nil {
+				RecordSnapshot(span, r, logBuffer, *options.snapshot)
+			}
+		}
 
-		fields := []zap.Field{
+		pooledFields := getFields()
+		defer putFields(pooledFields)
+		fields := append(*pooledFields,
 			zap.String("method", r.Method),
 			zap.String("path", r.URL.Path),
 			zap.String("query", r.URL.RawQuery),
 			zap.Int("status", status),
-		}
+		)
 
 		if status >= 100 && status < 400 {
 			reqLogger.Info("Request completed", fields...)
@@ -158,12 +233,13 @@ func RecoverMiddleware(next http.HandlerFunc, logger *zap.Logger, debug bool) ht
 	return func(w http.ResponseWriter, r *http.Request) {
 		traceCtx, span := tracer.Start(r.Context(), "RecoverMiddleware")
 		reqLogger := logutil.WithContext(traceCtx, logger)
+		w = problem.NewResponseWriter(w)
 
 		defer func() {
 			needRecovery, errString, caller := PanicRecoveryError(recover())
 			if needRecovery {
 				span.AddEvent("PanicRecovered", trace.WithAttributes(attribute.String("panic", fmt.Sprintf("%v", errString))))
-				reqLogger.Error("Recovered from panic", zap.Any("error", errString), zap.String("trace", fmt.Sprintf("%s", caller)))
+				reqLogger.Error("Recovered from panic", zap.Any("error", errString), zap.String("trace", fmt.Sprintf("%s", caller)), zap.Bool("panic", true))
 				if debug {
 					for _, line := range caller {
 						fmt.Printf("\t%s\n", line)
@@ -0,0 +1,129 @@
+package traceutil
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NYCU-SDC/summer/pkg/handler"
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"github.com/NYCU-SDC/summer/pkg/problem"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// benchmarkLogger encodes entries like production does, without paying for the I/O
+func benchmarkLogger() *zap.Logger {
+	encoder := zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	return zap.New(zapcore.NewCore(encoder, zapcore.AddSync(io.Discard), zapcore.InfoLevel))
+}
+
+func benchmarkTraceMiddleware(b *testing.B, provider trace.TracerProvider, header http.Header) {
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	b.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	handler := TraceMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}, benchmarkLogger(), false)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/users?page=1", nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		handler(w, r)
+	}
+}
+
+func BenchmarkTraceMiddleware(b *testing.B) {
+	upstream := http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}
+
+	b.Run("Noop", func(b *testing.B) {
+		benchmarkTraceMiddleware(b, noop.NewTracerProvider(), nil)
+	})
+	b.Run("Disabled", func(b *testing.B) {
+		disabled.Store(true)
+		b.Cleanup(func() { disabled.Store(false) })
+		benchmarkTraceMiddleware(b, sdktrace.NewTracerProvider(), upstream)
+	})
+	b.Run("Sampled", func(b *testing.B) {
+		benchmarkTraceMiddleware(b, sdktrace.NewTracerProvider(), nil)
+	})
+	b.Run("SampledWithUpstream", func(b *testing.B) {
+		benchmarkTraceMiddleware(b, sdktrace.NewTracerProvider(), upstream)
+	})
+}
+
+func TestTraceMiddleware_ForgedDebugBaggage(t *testing.T) {
+	previous := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	t.Cleanup(func() { otel.SetTextMapPropagator(previous) })
+
+	var debugEnabled bool
+	var member baggage.Member
+	handler := TraceMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		debugEnabled = logutil.DebugEnabled(r.Context())
+		member = baggage.FromContext(r.Context()).Member(logutil.DebugBaggageKey)
+		problem.New().WriteError(r.Context(), w, errors.New(`pq: relation "users" does not exist`), zap.NewNop())
+	}, zap.NewNop(), false)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	r.Header.Set("Baggage", "debug=true")
+	w := httptest.NewRecorder()
+	handler(w, r)
+
+	if debugEnabled {
+		t.Error("DebugEnabled() = true for a forged baggage header")
+	}
+	if member.Key() != "" {
+		t.Errorf("baggage member %q was not dropped", member.String())
+	}
+
+	var p problem.Problem
+	if err := json.NewDecoder(w.Body).Decode(&p); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(p.Errors) != 0 {
+		t.Errorf("WriteError() errors = %v, want none", p.Errors)
+	}
+}
+
+func TestTraceMiddleware_Streaming(t *testing.T) {
+	events := make(chan handlerutil.SSEEvent, 1)
+	events <- handlerutil.SSEEvent{ID: "1", Data: "x"}
+	close(events)
+
+	var streamErr error
+	handler := TraceMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		streamErr = handlerutil.StreamSSE(w, r, events, time.Minute)
+	}, zap.NewNop(), false)
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	if streamErr != nil {
+		t.Fatalf("StreamSSE() error = %v", streamErr)
+	}
+	if !w.Flushed {
+		t.Error("response was not flushed through TraceMiddleware")
+	}
+	if want := "id: 1\ndata: x\n\n"; w.Body.String() != want {
+		t.Errorf("body = %q, want %q", w.Body.String(), want)
+	}
+}
@@ -0,0 +1,45 @@
+package traceutil
+
+import (
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap"
+)
+
+// The pools below keep TraceMiddleware within its allocation budget, the slices they hold are
+// only passed to APIs copying what they keep: span attributes are copied by the SDK, and zap
+// cores encode the fields before Write returns.
+
+var attributePool = sync.Pool{
+	New: func() any {
+		attrs := make([]attribute.KeyValue, 0, 8)
+		return &attrs
+	},
+}
+
+func getAttributes() *[]attribute.KeyValue {
+	return attributePool.Get().(*[]attribute.KeyValue)
+}
+
+func putAttributes(attrs *[]attribute.KeyValue) {
+	*attrs = (*attrs)[:0]
+	attributePool.Put(attrs)
+}
+
+var fieldPool = sync.Pool{
+	New: func() any {
+		fields := make([]zap.Field, 0, 8)
+		return &fields
+	},
+}
+
+func getFields() *[]zap.Field {
+	return fieldPool.Get().(*[]zap.Field)
+}
+
+func putFields(fields *[]zap.Field) {
+	clear(*fields)
+	*fields = (*fields)[:0]
+	fieldPool.Put(fields)
+}
@@ -0,0 +1,31 @@
+package traceutil
+
+import (
+	"github.com/NYCU-SDC/summer/pkg/log"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// debugSampler samples every span started within a request marked with logutil.WithDebug
+type debugSampler struct {
+	base sdktrace.Sampler
+}
+
+// DebugSampler wraps base so that requests marked for debugging are always sampled, use it as the
+// sampler of the TracerProvider and put middleware.DebugMiddleware in front of TraceMiddleware
+func DebugSampler(base sdktrace.Sampler) sdktrace.Sampler {
+	return debugSampler{base: base}
+}
+
+func (s debugSampler) ShouldSample(parameters sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	if !logutil.DebugEnabled(parameters.ParentContext) {
+		return s.base.ShouldSample(parameters)
+	}
+
+	result := s.base.ShouldSample(parameters)
+	result.Decision = sdktrace.RecordAndSample
+	return result
+}
+
+func (s debugSampler) Description() string {
+	return "DebugSampler{" + s.base.Description() + "}"
+}
@@ -0,0 +1,122 @@
+package traceutil
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+
+	"github.com/NYCU-SDC/summer/pkg/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap/zapcore"
+)
+
+// SnapshotConfig bounds the debug snapshot recorded on the span of a failed request. The log
+// entries written through logutil.WithContext during the request are kept in a buffer and added
+// as span events when the request fails.
+type SnapshotConfig struct {
+	// MaxLogEntries is the number of most recent log entries recorded, 20 when zero
+	MaxLogEntries int
+
+	// LogLevel is the lowest level of the recorded entries, info when zero
+	LogLevel zapcore.Level
+
+	// MaxValueLength truncates every recorded string value, 512 when zero
+	MaxValueLength int
+}
+
+func DefaultSnapshotConfig() SnapshotConfig {
+	return SnapshotConfig{
+		MaxLogEntries:  20,
+		LogLevel:       zapcore.InfoLevel,
+		MaxValueLength: 512,
+	}
+}
+
+func (c SnapshotConfig) withDefaults() SnapshotConfig {
+	defaults := DefaultSnapshotConfig()
+	if c.MaxLogEntries <= 0 {
+		c.MaxLogEntries = defaults.MaxLogEntries
+	}
+	if c.MaxValueLength <= 0 {
+		c.MaxValueLength = defaults.MaxValueLength
+	}
+	return c
+}
+
+// TraceOption configures TraceMiddleware
+type TraceOption func(*traceOptions)
+
+type traceOptions struct {
+	snapshot      *SnapshotConfig
+	traceResponse bool
+}
+
+// WithTraceResponse makes TraceMiddleware write the context of the request span into the response
+// headers with the global propagator, e.g. traceparent, so clients can quote it when reporting errors
+func WithTraceResponse() TraceOption {
+	return func(o *traceOptions) {
+		o.traceResponse = true
+	}
+}
+
+// WithErrorSnapshot makes TraceMiddleware record a snapshot on the span of requests ending with a
+// 5xx status: the request metadata and goroutine count as attributes, and the last log entries as
+// "log" events, so a single trace tells the full story of a failure
+func WithErrorSnapshot(config SnapshotConfig) TraceOption {
+	return func(o *traceOptions) {
+		config = config.withDefaults()
+		o.snapshot = &config
+	}
+}
+
+// RecordSnapshot adds the snapshot of r to span, buffer may be nil when no log entries were kept
+func RecordSnapshot(span trace.Span, r *http.Request, buffer *logutil.EntryBuffer, config SnapshotConfig) {
+	config = config.withDefaults()
+
+	span.SetAttributes(
+		attribute.String("snapshot.request.method", r.Method),
+		attribute.String("snapshot.request.path", truncate(r.URL.Path, config.MaxValueLength)),
+		attribute.String("snapshot.request.query", truncate(r.URL.RawQuery, config.MaxValueLength)),
+		attribute.String("snapshot.request.remote_addr", r.RemoteAddr),
+		attribute.String("snapshot.request.user_agent", truncate(r.UserAgent(), config.MaxValueLength)),
+		attribute.Int64("snapshot.request.content_length", r.ContentLength),
+		attribute.Int("snapshot.goroutines", runtime.NumGoroutine()),
+	)
+
+	if buffer == nil {
+		return
+	}
+
+	entries := buffer.Entries()
+	if len(entries) > config.MaxLogEntries {
+		entries = entries[len(entries)-config.MaxLogEntries:]
+	}
+
+	for _, entry := range entries {
+		keys := make([]string, 0, len(entry.Fields))
+		for key := range entry.Fields {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		attrs := make([]attribute.KeyValue, 0, len(keys)+2)
+		attrs = append(attrs,
+			attribute.String("log.level", entry.Level.String()),
+			attribute.String("log.message", truncate(entry.Message, config.MaxValueLength)),
+		)
+		for _, key := range keys {
+			attrs = append(attrs, attribute.String("log.field."+key, truncate(fmt.Sprintf("%v", entry.Fields[key]), config.MaxValueLength)))
+		}
+
+		span.AddEvent("log", trace.WithTimestamp(entry.Time), trace.WithAttributes(attrs...))
+	}
+}
+
+func truncate(s string, maxLength int) string {
+	if len(s) <= maxLength {
+		return s
+	}
+	return s[:maxLength] + "...(truncated)"
+}